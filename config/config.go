@@ -1,12 +1,28 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// DefaultJWTSecret is the development fallback for JWT_SECRET. It is
+// rejected in production.
+const DefaultJWTSecret = "change-me-in-production"
+
+// LocalUploadsPath is the route serving files of the local storage driver
+const LocalUploadsPath = "/uploads"
+
+// minProductionSecretLength is the shortest HS256 secret accepted in production
+const minProductionSecretLength = 32
+
+// knownDefaultSecrets are placeholder secrets shipped in docs and examples
+var knownDefaultSecrets = []string{DefaultJWTSecret, "your-jwt-secret-here", "secret", "changeme"}
+
 // Config holds all application configuration
 type Config struct {
 	Port      string `mapstructure:"PORT"`
@@ -14,6 +30,97 @@ type Config struct {
 	AppEnv    string `mapstructure:"APP_ENV"`
 	AppURL    string `mapstructure:"APP_URL"`
 	JWTSecret string `mapstructure:"JWT_SECRET"`
+
+	// Asymmetric JWT signing: a PEM private key (RSA for RS256, Ed25519 for
+	// EdDSA) and comma-separated PEM keys still accepted for verification
+	// after a rotation. When unset, tokens are signed with HS256 and JWT_SECRET.
+	JWTPrivateKeyFile string   `mapstructure:"JWT_PRIVATE_KEY_FILE"`
+	JWTVerifyKeyFiles []string `mapstructure:"JWT_VERIFY_KEY_FILES"`
+
+	// HTTP server timeouts (Go duration strings, e.g. "15s")
+	ReadTimeout       time.Duration `mapstructure:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `mapstructure:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `mapstructure:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `mapstructure:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout   time.Duration `mapstructure:"SERVER_SHUTDOWN_TIMEOUT"`
+
+	// Logging: level is debug|info|warn|error, format is json|text
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+
+	// Readiness probe dependency check timeout
+	HealthCheckTimeout time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+
+	// Manager approval policy for sensitive cashier actions
+	ApprovalDiscountThresholdPercent int           `mapstructure:"APPROVAL_DISCOUNT_THRESHOLD_PERCENT"`
+	ApprovalRequiredForVoid          bool          `mapstructure:"APPROVAL_REQUIRED_FOR_VOID"`
+	ApprovalTokenTTL                 time.Duration `mapstructure:"APPROVAL_TOKEN_TTL"`
+
+	// Login brute-force protection
+	LoginMaxFailedAttempts  int           `mapstructure:"LOGIN_MAX_FAILED_ATTEMPTS"`
+	LoginLockoutDuration    time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginMaxLockoutDuration time.Duration `mapstructure:"LOGIN_MAX_LOCKOUT_DURATION"`
+	LoginIPMaxAttempts      int           `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginIPWindow           time.Duration `mapstructure:"LOGIN_IP_WINDOW"`
+
+	// Password policy for new and changed passwords
+	PasswordMinLength        int  `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordRequireMixedCase bool `mapstructure:"PASSWORD_REQUIRE_MIXED_CASE"`
+	PasswordRequireDigit     bool `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol    bool `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+
+	// Owner account seeded on first start. Outside production an empty
+	// password is generated and printed once to stderr; production requires one.
+	InitialOwnerName     string `mapstructure:"INITIAL_OWNER_NAME"`
+	InitialOwnerEmail    string `mapstructure:"INITIAL_OWNER_EMAIL"`
+	InitialOwnerPassword string `mapstructure:"INITIAL_OWNER_PASSWORD"`
+
+	// Outgoing mail: driver is smtp|log. The log driver writes messages to
+	// MAIL_LOG_FILE, or only their recipient and subject to the application
+	// log when it is empty. It is refused in production.
+	MailDriver   string `mapstructure:"MAIL_DRIVER"`
+	MailFrom     string `mapstructure:"MAIL_FROM"`
+	MailLogFile  string `mapstructure:"MAIL_LOG_FILE"`
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+
+	// Password reset links
+	PasswordResetTokenTTL time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_TTL"`
+	PasswordResetURL      string        `mapstructure:"PASSWORD_RESET_URL"`
+
+	// Reset link requests allowed per email and per client IP within
+	// PASSWORD_RESET_RATE_WINDOW. 0 disables a limit.
+	PasswordResetEmailMaxRequests int           `mapstructure:"PASSWORD_RESET_EMAIL_MAX_REQUESTS"`
+	PasswordResetIPMaxRequests    int           `mapstructure:"PASSWORD_RESET_IP_MAX_REQUESTS"`
+	PasswordResetRateWindow       time.Duration `mapstructure:"PASSWORD_RESET_RATE_WINDOW"`
+
+	// Two-factor authentication: issuer shown in authenticator apps and
+	// whether owners must enroll
+	TwoFactorIssuer       string `mapstructure:"TWO_FACTOR_ISSUER"`
+	TwoFactorRequireOwner bool   `mapstructure:"TWO_FACTOR_REQUIRE_OWNER"`
+
+	// Largest product import file accepted, in bytes
+	ImportMaxFileSize int64 `mapstructure:"IMPORT_MAX_FILE_SIZE"`
+
+	// Uploaded files: driver is local|s3. STORAGE_PUBLIC_URL is the base URL
+	// clients load files from; for the local driver it defaults to the
+	// /uploads route that serves STORAGE_LOCAL_DIR.
+	StorageDriver    string `mapstructure:"STORAGE_DRIVER"`
+	StorageLocalDir  string `mapstructure:"STORAGE_LOCAL_DIR"`
+	StoragePublicURL string `mapstructure:"STORAGE_PUBLIC_URL"`
+	S3Endpoint       string `mapstructure:"S3_ENDPOINT"`
+	S3Region         string `mapstructure:"S3_REGION"`
+	S3Bucket         string `mapstructure:"S3_BUCKET"`
+	S3AccessKey      string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey      string `mapstructure:"S3_SECRET_KEY"`
+	S3UseSSL         bool   `mapstructure:"S3_USE_SSL"`
+
+	// Product images: largest upload in bytes and the longest side of the
+	// generated thumbnails in pixels
+	ImageMaxFileSize   int64 `mapstructure:"IMAGE_MAX_FILE_SIZE"`
+	ImageThumbnailSize int   `mapstructure:"IMAGE_THUMBNAIL_SIZE"`
 }
 
 // LoadConfig reads configuration from environment variables and optional .env file
@@ -32,6 +139,72 @@ func LoadConfig() (*Config, error) {
 		AppEnv:    viper.GetString("APP_ENV"),
 		AppURL:    viper.GetString("APP_URL"),
 		JWTSecret: viper.GetString("JWT_SECRET"),
+
+		JWTPrivateKeyFile: viper.GetString("JWT_PRIVATE_KEY_FILE"),
+		JWTVerifyKeyFiles: splitList(viper.GetString("JWT_VERIFY_KEY_FILES")),
+
+		ReadTimeout:       viper.GetDuration("SERVER_READ_TIMEOUT"),
+		ReadHeaderTimeout: viper.GetDuration("SERVER_READ_HEADER_TIMEOUT"),
+		WriteTimeout:      viper.GetDuration("SERVER_WRITE_TIMEOUT"),
+		IdleTimeout:       viper.GetDuration("SERVER_IDLE_TIMEOUT"),
+		ShutdownTimeout:   viper.GetDuration("SERVER_SHUTDOWN_TIMEOUT"),
+
+		LogLevel:  viper.GetString("LOG_LEVEL"),
+		LogFormat: viper.GetString("LOG_FORMAT"),
+
+		HealthCheckTimeout: viper.GetDuration("HEALTH_CHECK_TIMEOUT"),
+
+		ApprovalDiscountThresholdPercent: viper.GetInt("APPROVAL_DISCOUNT_THRESHOLD_PERCENT"),
+		ApprovalRequiredForVoid:          viper.GetBool("APPROVAL_REQUIRED_FOR_VOID"),
+		ApprovalTokenTTL:                 viper.GetDuration("APPROVAL_TOKEN_TTL"),
+
+		LoginMaxFailedAttempts:  viper.GetInt("LOGIN_MAX_FAILED_ATTEMPTS"),
+		LoginLockoutDuration:    viper.GetDuration("LOGIN_LOCKOUT_DURATION"),
+		LoginMaxLockoutDuration: viper.GetDuration("LOGIN_MAX_LOCKOUT_DURATION"),
+		LoginIPMaxAttempts:      viper.GetInt("LOGIN_IP_MAX_ATTEMPTS"),
+		LoginIPWindow:           viper.GetDuration("LOGIN_IP_WINDOW"),
+
+		PasswordMinLength:        viper.GetInt("PASSWORD_MIN_LENGTH"),
+		PasswordRequireMixedCase: viper.GetBool("PASSWORD_REQUIRE_MIXED_CASE"),
+		PasswordRequireDigit:     viper.GetBool("PASSWORD_REQUIRE_DIGIT"),
+		PasswordRequireSymbol:    viper.GetBool("PASSWORD_REQUIRE_SYMBOL"),
+
+		InitialOwnerName:     viper.GetString("INITIAL_OWNER_NAME"),
+		InitialOwnerEmail:    viper.GetString("INITIAL_OWNER_EMAIL"),
+		InitialOwnerPassword: viper.GetString("INITIAL_OWNER_PASSWORD"),
+
+		MailDriver:   viper.GetString("MAIL_DRIVER"),
+		MailFrom:     viper.GetString("MAIL_FROM"),
+		MailLogFile:  viper.GetString("MAIL_LOG_FILE"),
+		SMTPHost:     viper.GetString("SMTP_HOST"),
+		SMTPPort:     viper.GetInt("SMTP_PORT"),
+		SMTPUsername: viper.GetString("SMTP_USERNAME"),
+		SMTPPassword: viper.GetString("SMTP_PASSWORD"),
+
+		PasswordResetTokenTTL: viper.GetDuration("PASSWORD_RESET_TOKEN_TTL"),
+		PasswordResetURL:      viper.GetString("PASSWORD_RESET_URL"),
+
+		PasswordResetEmailMaxRequests: viper.GetInt("PASSWORD_RESET_EMAIL_MAX_REQUESTS"),
+		PasswordResetIPMaxRequests:    viper.GetInt("PASSWORD_RESET_IP_MAX_REQUESTS"),
+		PasswordResetRateWindow:       viper.GetDuration("PASSWORD_RESET_RATE_WINDOW"),
+
+		TwoFactorIssuer:       viper.GetString("TWO_FACTOR_ISSUER"),
+		TwoFactorRequireOwner: viper.GetBool("TWO_FACTOR_REQUIRE_OWNER"),
+
+		ImportMaxFileSize: viper.GetInt64("IMPORT_MAX_FILE_SIZE"),
+
+		StorageDriver:    viper.GetString("STORAGE_DRIVER"),
+		StorageLocalDir:  viper.GetString("STORAGE_LOCAL_DIR"),
+		StoragePublicURL: viper.GetString("STORAGE_PUBLIC_URL"),
+		S3Endpoint:       viper.GetString("S3_ENDPOINT"),
+		S3Region:         viper.GetString("S3_REGION"),
+		S3Bucket:         viper.GetString("S3_BUCKET"),
+		S3AccessKey:      viper.GetString("S3_ACCESS_KEY"),
+		S3SecretKey:      viper.GetString("S3_SECRET_KEY"),
+		S3UseSSL:         viper.GetBool("S3_USE_SSL"),
+
+		ImageMaxFileSize:   viper.GetInt64("IMAGE_MAX_FILE_SIZE"),
+		ImageThumbnailSize: viper.GetInt("IMAGE_THUMBNAIL_SIZE"),
 	}
 
 	// Defaults
@@ -39,12 +212,179 @@ func LoadConfig() (*Config, error) {
 		cfg.Port = "8080"
 	}
 	if cfg.JWTSecret == "" {
-		cfg.JWTSecret = "change-me-in-production"
+		cfg.JWTSecret = DefaultJWTSecret
+	}
+	if cfg.ReadTimeout <= 0 {
+		cfg.ReadTimeout = 15 * time.Second
+	}
+	if cfg.ReadHeaderTimeout <= 0 {
+		cfg.ReadHeaderTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 30 * time.Second
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 60 * time.Second
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 30 * time.Second
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "info"
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "json"
+	}
+	if cfg.HealthCheckTimeout <= 0 {
+		cfg.HealthCheckTimeout = 2 * time.Second
+	}
+	if !viper.IsSet("APPROVAL_DISCOUNT_THRESHOLD_PERCENT") {
+		cfg.ApprovalDiscountThresholdPercent = 10
+	}
+	if !viper.IsSet("APPROVAL_REQUIRED_FOR_VOID") {
+		cfg.ApprovalRequiredForVoid = true
+	}
+	if cfg.ApprovalTokenTTL <= 0 {
+		cfg.ApprovalTokenTTL = 5 * time.Minute
+	}
+	if !viper.IsSet("LOGIN_MAX_FAILED_ATTEMPTS") {
+		cfg.LoginMaxFailedAttempts = 5
+	}
+	if cfg.LoginLockoutDuration <= 0 {
+		cfg.LoginLockoutDuration = time.Minute
+	}
+	if cfg.LoginMaxLockoutDuration <= 0 {
+		cfg.LoginMaxLockoutDuration = time.Hour
+	}
+	if !viper.IsSet("LOGIN_IP_MAX_ATTEMPTS") {
+		cfg.LoginIPMaxAttempts = 20
+	}
+	if cfg.LoginIPWindow <= 0 {
+		cfg.LoginIPWindow = 15 * time.Minute
+	}
+	if cfg.PasswordMinLength <= 0 {
+		cfg.PasswordMinLength = 8
+	}
+	if !viper.IsSet("PASSWORD_REQUIRE_MIXED_CASE") {
+		cfg.PasswordRequireMixedCase = true
+	}
+	if !viper.IsSet("PASSWORD_REQUIRE_DIGIT") {
+		cfg.PasswordRequireDigit = true
+	}
+	if cfg.InitialOwnerName == "" {
+		cfg.InitialOwnerName = "Admin"
+	}
+	if cfg.InitialOwnerEmail == "" {
+		cfg.InitialOwnerEmail = "admin@retail.com"
+	}
+	if cfg.MailDriver == "" {
+		cfg.MailDriver = "log"
+	}
+	if cfg.MailFrom == "" {
+		cfg.MailFrom = "no-reply@retail.local"
+	}
+	if cfg.SMTPPort <= 0 {
+		cfg.SMTPPort = 587
+	}
+	if cfg.PasswordResetTokenTTL <= 0 {
+		cfg.PasswordResetTokenTTL = time.Hour
+	}
+	if !viper.IsSet("PASSWORD_RESET_EMAIL_MAX_REQUESTS") {
+		cfg.PasswordResetEmailMaxRequests = 3
+	}
+	if !viper.IsSet("PASSWORD_RESET_IP_MAX_REQUESTS") {
+		cfg.PasswordResetIPMaxRequests = 10
+	}
+	if cfg.PasswordResetRateWindow <= 0 {
+		cfg.PasswordResetRateWindow = time.Hour
+	}
+	if cfg.TwoFactorIssuer == "" {
+		cfg.TwoFactorIssuer = "Retail Core"
+	}
+	if cfg.ImportMaxFileSize <= 0 {
+		cfg.ImportMaxFileSize = 10 << 20
+	}
+	if cfg.StorageDriver == "" {
+		cfg.StorageDriver = "local"
+	}
+	if cfg.StorageLocalDir == "" {
+		cfg.StorageLocalDir = "uploads"
+	}
+	if cfg.StoragePublicURL == "" && cfg.StorageDriver == "local" {
+		cfg.StoragePublicURL = LocalUploadsPath
+	}
+	if !viper.IsSet("S3_USE_SSL") {
+		cfg.S3UseSSL = true
+	}
+	if cfg.ImageMaxFileSize <= 0 {
+		cfg.ImageMaxFileSize = 5 << 20
+	}
+	if cfg.ImageThumbnailSize <= 0 {
+		cfg.ImageThumbnailSize = 320
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
+// validate rejects incomplete settings and ones that are unsafe in production
+func (c *Config) validate() error {
+	if c.IsProduction() && c.InitialOwnerPassword == "" {
+		return errors.New("INITIAL_OWNER_PASSWORD is required in production; it seeds the first owner, who must change it at first login")
+	}
+
+	switch c.MailDriver {
+	case "log":
+		if c.IsProduction() {
+			return errors.New("MAIL_DRIVER=log is for development only; use smtp in production")
+		}
+	case "smtp":
+		if c.SMTPHost == "" {
+			return errors.New("MAIL_DRIVER=smtp needs SMTP_HOST")
+		}
+	default:
+		return fmt.Errorf("unknown MAIL_DRIVER %q; use log or smtp", c.MailDriver)
+	}
+
+	switch c.StorageDriver {
+	case "local":
+	case "s3":
+		if c.S3Endpoint == "" || c.S3Bucket == "" {
+			return errors.New("STORAGE_DRIVER=s3 needs S3_ENDPOINT and S3_BUCKET")
+		}
+	default:
+		return fmt.Errorf("unknown STORAGE_DRIVER %q; use local or s3", c.StorageDriver)
+	}
+
+	if !c.IsProduction() || c.JWTPrivateKeyFile != "" {
+		return nil
+	}
+
+	for _, known := range knownDefaultSecrets {
+		if c.JWTSecret == known {
+			return errors.New("JWT_SECRET is set to a default value; configure a random secret or JWT_PRIVATE_KEY_FILE in production")
+		}
+	}
+	if len(c.JWTSecret) < minProductionSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d characters in production", minProductionSecretLength)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // IsProduction returns true if APP_ENV is "production"
 func (c *Config) IsProduction() bool {
 	return c.AppEnv == "production"
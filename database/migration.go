@@ -1,14 +1,41 @@
 package database
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
-	"log"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"os"
+	"retail-core-api/models"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// SchemaVersion is the version of the schema created by RunMigrations.
+// Bump it whenever a migration step is added so running instances report
+// which schema they expect in their readiness checks.
+const SchemaVersion = 19
+
+// Credentials seeded by earlier releases. Accounts still using them are
+// forced to change their password.
+const (
+	legacyAdminEmail    = "admin@retail.com"
+	legacyAdminPassword = "password123"
+)
+
+// OwnerSeed holds the owner account created when the users table is empty.
+// An empty Password is replaced by a random one that is printed once to
+// stderr.
+type OwnerSeed struct {
+	Name     string
+	Email    string
+	Password string
+}
+
 // RunMigrations creates necessary database tables if they don't exist
-func RunMigrations(db *sql.DB) error {
+func RunMigrations(db *sql.DB, owner OwnerSeed) error {
 	// Create users table
 	createUsersTable := `
 	CREATE TABLE IF NOT EXISTS users (
@@ -26,23 +53,82 @@ func RunMigrations(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	log.Println("Users table ready")
 
-	// Seed default owner account if no users exist
-	var userCount int
-	_ = db.QueryRow("SELECT COUNT(*) FROM users").Scan(&userCount)
-	if userCount == 0 {
-		hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	// Approval PIN for managers (bcrypt hash, NULL when not set)
+	_, _ = db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS pin_hash VARCHAR(255)")
+
+	// Consecutive failed logins and the lockout they triggered
+	_, _ = db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_count INT NOT NULL DEFAULT 0")
+	_, _ = db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP")
+
+	// Forced password change for seeded and admin-reset accounts
+	_, _ = db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT false")
+
+	// TOTP two-factor authentication; totp_last_step blocks code replay
+	_, _ = db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64)")
+	_, _ = db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT false")
+	_, _ = db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0")
+	slog.Info("Users table ready")
+
+	// Create roles and role_permissions tables
+	createRolesTables := `
+	CREATE TABLE IF NOT EXISTS roles (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(50) UNIQUE NOT NULL,
+		description TEXT DEFAULT '',
+		is_system BOOLEAN NOT NULL DEFAULT false,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS role_permissions (
+		role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+		permission VARCHAR(100) NOT NULL,
+		PRIMARY KEY (role_id, permission)
+	);
+	`
+
+	_, err = db.Exec(createRolesTables)
+	if err != nil {
+		return err
+	}
+
+	// Seed system roles and keep their permissions in sync with the code
+	for _, role := range models.DefaultRoles {
+		var roleID int
+		err = db.QueryRow(
+			`INSERT INTO roles (name, description, is_system) VALUES ($1, $2, true)
+			 ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description, is_system = true
+			 RETURNING id`,
+			role.Name, role.Description,
+		).Scan(&roleID)
+		if err != nil {
+			return err
+		}
+
 		_, err = db.Exec(
-			"INSERT INTO users (name, email, password, role) VALUES ($1, $2, $3, $4)",
-			"Admin", "admin@retail.com", string(hash), "owner",
+			"DELETE FROM role_permissions WHERE role_id = $1 AND NOT (permission = ANY($2))",
+			roleID, role.Permissions,
 		)
 		if err != nil {
-			log.Println("Warning: failed to seed admin user:", err)
-		} else {
-			log.Println("Default admin user seeded (admin@retail.com / password123)")
+			return err
+		}
+		for _, p := range role.Permissions {
+			_, err = db.Exec(
+				"INSERT INTO role_permissions (role_id, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				roleID, p,
+			)
+			if err != nil {
+				return err
+			}
 		}
 	}
+	slog.Info("Roles table ready")
+
+	// Seed the initial owner account if no users exist
+	if err := seedOwner(db, owner); err != nil {
+		return err
+	}
 
 	// Create categories table
 	createCategoriesTable := `
@@ -59,7 +145,23 @@ func RunMigrations(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	log.Println("Categories table ready")
+
+	// Categories form a tree; deleting a parent turns its children into roots
+	_, err = db.Exec("ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id INT REFERENCES categories(id) ON DELETE SET NULL")
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id)")
+	if err != nil {
+		return err
+	}
+
+	// Archived categories are hidden from listings but keep their products
+	_, err = db.Exec("ALTER TABLE categories ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP")
+	if err != nil {
+		return err
+	}
+	slog.Info("Categories table ready")
 
 	// Create products table with foreign key to categories
 	createProductsTable := `
@@ -82,7 +184,7 @@ func RunMigrations(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	log.Println("Products table ready")
+	slog.Info("Products table ready")
 
 	// Add new columns if they don't exist (for existing databases)
 	alterProducts := []string{
@@ -95,6 +197,12 @@ func RunMigrations(db *sql.DB) error {
 		_, _ = db.Exec(q)
 	}
 
+	// Deleted products are archived so past transactions keep their rows
+	_, err = db.Exec("ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP")
+	if err != nil {
+		return err
+	}
+
 	// Create index on category_id for better JOIN performance
 	createIndexQuery := `
 	CREATE INDEX IF NOT EXISTS idx_products_category_id ON products(category_id);
@@ -104,7 +212,53 @@ func RunMigrations(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	log.Println("Database indexes ready")
+
+	// Product search: full-text over name and SKU, trigrams for typo
+	// tolerance and SKU prefixes. The tsvector expression must match
+	// productSearchDocument in the product repository.
+	searchIndexes := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN (to_tsvector('simple', name || ' ' || COALESCE(sku, '')))",
+		"CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (sku gin_trgm_ops)",
+	}
+	for _, q := range searchIndexes {
+		if _, err = db.Exec(q); err != nil {
+			return err
+		}
+	}
+	slog.Info("Database indexes ready")
+
+	// Create product_images table. At most one image per product is
+	// primary; its URL is mirrored into products.image_url.
+	createProductImagesTable := `
+	CREATE TABLE IF NOT EXISTS product_images (
+		id SERIAL PRIMARY KEY,
+		product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		storage_key TEXT NOT NULL,
+		thumbnail_key TEXT NOT NULL,
+		content_type VARCHAR(50) NOT NULL,
+		size_bytes BIGINT NOT NULL,
+		width INT NOT NULL,
+		height INT NOT NULL,
+		is_primary BOOLEAN NOT NULL DEFAULT false,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createProductImagesTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_product_images_product ON product_images(product_id)")
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_product_images_primary ON product_images(product_id) WHERE is_primary")
+	if err != nil {
+		return err
+	}
+	slog.Info("Product images table ready")
 
 	// Create transactions table
 	createTransactionsTable := `
@@ -123,7 +277,7 @@ func RunMigrations(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	log.Println("Transactions table ready")
+	slog.Info("Transactions table ready")
 
 	// Add new columns to transactions if they don't exist
 	alterTransactions := []string{
@@ -131,6 +285,8 @@ func RunMigrations(db *sql.DB) error {
 		"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS discount INT DEFAULT 0",
 		"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS notes TEXT DEFAULT ''",
 		"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS status VARCHAR(20) DEFAULT 'active'",
+		"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS approved_by INT REFERENCES users(id) ON DELETE SET NULL",
+		"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS void_approved_by INT REFERENCES users(id) ON DELETE SET NULL",
 	}
 	for _, q := range alterTransactions {
 		_, _ = db.Exec(q)
@@ -152,10 +308,377 @@ func RunMigrations(db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	log.Println("Transaction details table ready")
+	slog.Info("Transaction details table ready")
 
 	// Add unit_price column if it doesn't exist
 	_, _ = db.Exec("ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS unit_price INT DEFAULT 0")
 
+	// Snapshot of the product at sale time, so renaming or archiving a
+	// product does not rewrite past receipts. Older rows are backfilled from
+	// the current product.
+	alterTransactionDetails := []string{
+		"ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS product_name VARCHAR(255)",
+		"ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS product_sku VARCHAR(100)",
+	}
+	for _, q := range alterTransactionDetails {
+		if _, err = db.Exec(q); err != nil {
+			return err
+		}
+	}
+	_, err = db.Exec(`
+		UPDATE transaction_details td
+		SET product_name = p.name, product_sku = p.sku
+		FROM products p
+		WHERE td.product_id = p.id AND td.product_name IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Create audit_log table
+	createAuditLogTable := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+		actor_name VARCHAR(255) DEFAULT '',
+		actor_role VARCHAR(50) DEFAULT '',
+		action VARCHAR(50) NOT NULL,
+		entity_type VARCHAR(50) NOT NULL,
+		entity_id INT,
+		before_data JSONB,
+		after_data JSONB,
+		changes JSONB,
+		ip VARCHAR(64) DEFAULT '',
+		request_id VARCHAR(128) DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createAuditLogTable)
+	if err != nil {
+		return err
+	}
+
+	auditIndexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id)",
+		"CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id)",
+		"CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at)",
+	}
+	for _, q := range auditIndexes {
+		if _, err = db.Exec(q); err != nil {
+			return err
+		}
+	}
+	slog.Info("Audit log table ready")
+
+	// Create approval_grants table
+	createApprovalGrantsTable := `
+	CREATE TABLE IF NOT EXISTS approval_grants (
+		id SERIAL PRIMARY KEY,
+		approver_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		action VARCHAR(50) NOT NULL,
+		transaction_id INT,
+		token_hash VARCHAR(64) UNIQUE NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		used_by INT REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createApprovalGrantsTable)
+	if err != nil {
+		return err
+	}
+	// Discount approvals are bound to the discount and gross amount approved
+	for _, stmt := range []string{
+		"ALTER TABLE approval_grants ADD COLUMN IF NOT EXISTS discount INT",
+		"ALTER TABLE approval_grants ADD COLUMN IF NOT EXISTS gross_amount INT",
+	} {
+		if _, err = db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	slog.Info("Approval grants table ready")
+
+	// Create login_attempts table
+	createLoginAttemptsTable := `
+	CREATE TABLE IF NOT EXISTS login_attempts (
+		id SERIAL PRIMARY KEY,
+		email VARCHAR(255) NOT NULL,
+		user_id INT REFERENCES users(id) ON DELETE SET NULL,
+		result VARCHAR(30) NOT NULL,
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		request_id VARCHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createLoginAttemptsTable)
+	if err != nil {
+		return err
+	}
+
+	loginAttemptIndexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created ON login_attempts(ip, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_login_attempts_email_created ON login_attempts(email, created_at DESC)",
+	}
+	for _, q := range loginAttemptIndexes {
+		if _, err = db.Exec(q); err != nil {
+			return err
+		}
+	}
+	slog.Info("Login attempts table ready")
+
+	// Create password_reset_tokens table
+	createPasswordResetTokensTable := `
+	CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash VARCHAR(64) UNIQUE NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createPasswordResetTokensTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id)")
+	if err != nil {
+		return err
+	}
+	slog.Info("Password reset tokens table ready")
+
+	// Create user_recovery_codes table
+	createRecoveryCodesTable := `
+	CREATE TABLE IF NOT EXISTS user_recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, code_hash)
+	);
+	`
+
+	_, err = db.Exec(createRecoveryCodesTable)
+	if err != nil {
+		return err
+	}
+	slog.Info("Recovery codes table ready")
+
+	// Create api_keys table
+	createAPIKeysTable := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		prefix VARCHAR(32) UNIQUE NOT NULL,
+		key_hash VARCHAR(64) NOT NULL,
+		permissions JSONB NOT NULL DEFAULT '[]',
+		created_by INT REFERENCES users(id) ON DELETE SET NULL,
+		expires_at TIMESTAMP,
+		last_used_at TIMESTAMP,
+		last_used_ip VARCHAR(64) NOT NULL DEFAULT '',
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createAPIKeysTable)
+	if err != nil {
+		return err
+	}
+
+	// Attribute audit entries to the API key that made the request
+	_, err = db.Exec("ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS api_key_id INT REFERENCES api_keys(id) ON DELETE SET NULL")
+	if err != nil {
+		return err
+	}
+	slog.Info("API keys table ready")
+
+	// Create user_sessions table
+	createSessionsTable := `
+	CREATE TABLE IF NOT EXISTS user_sessions (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createSessionsTable)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_user_sessions_user_expires ON user_sessions(user_id, expires_at)")
+	if err != nil {
+		return err
+	}
+	// Sessions are revoked when their user is deactivated or has a password reset
+	_, err = db.Exec("ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP")
+	if err != nil {
+		return err
+	}
+	slog.Info("User sessions table ready")
+
+	// Create approval_attempts table. Every PIN check is recorded so repeated
+	// failures lock out both the approver and the calling terminal.
+	createApprovalAttemptsTable := `
+	CREATE TABLE IF NOT EXISTS approval_attempts (
+		id SERIAL PRIMARY KEY,
+		approver_id INT REFERENCES users(id) ON DELETE CASCADE,
+		caller_user_id INT REFERENCES users(id) ON DELETE CASCADE,
+		caller_api_key_id INT REFERENCES api_keys(id) ON DELETE CASCADE,
+		success BOOLEAN NOT NULL,
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createApprovalAttemptsTable)
+	if err != nil {
+		return err
+	}
+
+	approvalAttemptIndexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_approval_attempts_approver ON approval_attempts(approver_id, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_approval_attempts_caller_user ON approval_attempts(caller_user_id, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_approval_attempts_caller_key ON approval_attempts(caller_api_key_id, created_at DESC)",
+	}
+	for _, q := range approvalAttemptIndexes {
+		if _, err = db.Exec(q); err != nil {
+			return err
+		}
+	}
+	slog.Info("Approval attempts table ready")
+
+	// Create password_reset_requests table, used to rate limit reset links
+	// per email and per client IP
+	createPasswordResetRequestsTable := `
+	CREATE TABLE IF NOT EXISTS password_reset_requests (
+		id SERIAL PRIMARY KEY,
+		email VARCHAR(255) NOT NULL,
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createPasswordResetRequestsTable)
+	if err != nil {
+		return err
+	}
+
+	passwordResetRequestIndexes := []string{
+		"CREATE INDEX IF NOT EXISTS idx_password_reset_requests_email ON password_reset_requests(email, created_at DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_password_reset_requests_ip ON password_reset_requests(ip, created_at DESC)",
+	}
+	for _, q := range passwordResetRequestIndexes {
+		if _, err = db.Exec(q); err != nil {
+			return err
+		}
+	}
+	slog.Info("Password reset requests table ready")
+
+	// Record the applied schema version
+	createSchemaMigrationsTable := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+
+	_, err = db.Exec(createSchemaMigrationsTable)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		"INSERT INTO schema_migrations (version) VALUES ($1) ON CONFLICT (version) DO NOTHING",
+		SchemaVersion,
+	)
+	if err != nil {
+		return err
+	}
+	slog.Info("Schema version ready", "version", SchemaVersion)
+
+	return nil
+}
+
+// GetSchemaVersion returns the highest schema version recorded in the database
+func GetSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// seedOwner creates the initial owner when the users table is empty and
+// flags an existing account still using the legacy default credentials.
+// Seeded owners must change their password at first login.
+func seedOwner(db *sql.DB, owner OwnerSeed) error {
+	var userCount int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&userCount); err != nil {
+		return err
+	}
+
+	if userCount == 0 {
+		generated := owner.Password == ""
+		if generated {
+			b := make([]byte, 12)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			owner.Password = base64.RawURLEncoding.EncodeToString(b)
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(owner.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(
+			"INSERT INTO users (name, email, password, role, must_change_password) VALUES ($1, $2, $3, $4, true)",
+			owner.Name, owner.Email, string(hash), models.RoleOwner,
+		)
+		if err != nil {
+			slog.Warn("Failed to seed owner user", "error", err)
+			return nil
+		}
+
+		slog.Info("Initial owner seeded", "email", owner.Email, "generated_password", generated)
+		if generated {
+			// Printed outside structured logging so the password never
+			// reaches log shipping
+			fmt.Fprintf(os.Stderr, "\nInitial owner %s seeded with password: %s\nIt must be changed at first login.\n\n",
+				owner.Email, owner.Password)
+		}
+		return nil
+	}
+
+	var id int
+	var hash string
+	err := db.QueryRow(
+		"SELECT id, password FROM users WHERE email = $1 AND must_change_password = false",
+		legacyAdminEmail,
+	).Scan(&id, &hash)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(legacyAdminPassword)) == nil {
+		if _, err := db.Exec("UPDATE users SET must_change_password = true WHERE id = $1", id); err != nil {
+			return err
+		}
+		slog.Warn("Account uses the legacy default password and must change it at next login", "email", legacyAdminEmail)
+	}
 	return nil
 }
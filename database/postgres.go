@@ -2,7 +2,7 @@ package database
 
 import (
 	"database/sql"
-	"log"
+	"log/slog"
 	"strings"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -13,7 +13,7 @@ var DB *sql.DB
 
 // InitDB establishes connection to PostgreSQL database
 func InitDB(connectionString string) (*sql.DB, error) {
-	log.Println("Connecting to database...")
+	slog.Info("Connecting to database...")
 
 	// Disable prepared statement cache for PgBouncer compatibility (Supabase)
 	if strings.Contains(connectionString, "?") {
@@ -39,7 +39,7 @@ func InitDB(connectionString string) (*sql.DB, error) {
 	db.SetMaxIdleConns(5)
 
 	DB = db
-	log.Println("Database connected successfully")
+	slog.Info("Database connected successfully")
 	return db, nil
 }
 
@@ -47,6 +47,6 @@ func InitDB(connectionString string) (*sql.DB, error) {
 func CloseDB() {
 	if DB != nil {
 		DB.Close()
-		log.Println("Database connection closed")
+		slog.Info("Database connection closed")
 	}
 }
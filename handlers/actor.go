@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"retail-core-api/helpers"
+	"retail-core-api/middleware"
+	"retail-core-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// actorFromContext builds the audit actor from the identity set by
+// middleware.Auth and the request metadata.
+func actorFromContext(c *gin.Context) models.Actor {
+	actor := models.Actor{
+		Name:      c.GetString("user_name"),
+		Role:      c.GetString("user_role"),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.GetString(helpers.RequestIDKey),
+	}
+	if id := c.GetInt("user_id"); id > 0 {
+		actor.UserID = &id
+	}
+	if id := c.GetInt(middleware.APIKeyIDKey); id > 0 {
+		actor.APIKeyID = &id
+	}
+	if permissions, ok := c.Get(middleware.PermissionsKey); ok {
+		actor.Permissions, _ = permissions.([]string)
+	}
+	return actor
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler handles HTTP requests for API key management
+type APIKeyHandler struct {
+	service services.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler instance
+func NewAPIKeyHandler(service services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// respondAPIKeyError maps API key service errors to HTTP responses
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case helpers.IsNotFound(err):
+		helpers.NotFound(c, err.Error())
+	case helpers.IsConflict(err):
+		helpers.Error(c, http.StatusConflict, err.Error())
+	case helpers.IsValidation(err):
+		helpers.BadRequest(c, err.Error())
+	default:
+		helpers.InternalError(c, "Failed to process API key", err.Error())
+	}
+}
+
+// List godoc
+// @Summary List API keys
+// @Description Retrieve every API key, including revoked and expired ones. Secrets are never returned.
+// @Tags API Keys
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} helpers.Response{data=[]models.APIKey}
+// @Router /api/api-keys [get]
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.service.GetAll()
+	if err != nil {
+		helpers.InternalError(c, "Failed to retrieve API keys", err.Error())
+		return
+	}
+	helpers.OK(c, "Successfully retrieved API keys", keys)
+}
+
+// Create godoc
+// @Summary Create an API key
+// @Description Issue a key for a machine client, scoped to permissions the caller holds. Send it in the X-API-Key header. The key is shown only in this response.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key body models.APIKeyInput true "Key name, permissions and optional expiry"
+// @Success 201 {object} helpers.Response{data=models.APIKeyCreated}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body or validation error"
+// @Router /api/api-keys [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var input models.APIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	key, err := h.service.Create(actorFromContext(c), input)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	helpers.Created(c, "API key created, store it now as it will not be shown again", key)
+}
+
+// Revoke godoc
+// @Summary Revoke an API key
+// @Description Permanently disable an API key. It stays listed for auditing.
+// @Tags API Keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} helpers.Response
+// @Failure 404 {object} helpers.ErrorResponse "API key not found"
+// @Failure 409 {object} helpers.ErrorResponse "API key already revoked"
+// @Router /api/api-keys/{id} [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		helpers.BadRequest(c, "Invalid API key ID")
+		return
+	}
+
+	if err := h.service.Revoke(actorFromContext(c), id); err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	helpers.OK(c, "API key revoked", nil)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApprovalHandler handles HTTP requests for manager approvals
+type ApprovalHandler struct {
+	service services.ApprovalService
+}
+
+// NewApprovalHandler creates a new approval handler instance
+func NewApprovalHandler(service services.ApprovalService) *ApprovalHandler {
+	return &ApprovalHandler{service: service}
+}
+
+// Grant godoc
+// @Summary Request a manager approval
+// @Description A manager enters their email and PIN on the cashier's terminal to approve a void or a large discount. Returns a single-use token to send in the X-Approval-Token header of the approved request. Voids must name the transaction_id. Repeated wrong PINs lock out the approver and the calling account.
+// @Tags Approvals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ApprovalRequest true "Manager credentials and action"
+// @Success 201 {object} helpers.Response{data=models.ApprovalResponse}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body or approver credentials"
+// @Failure 429 {object} helpers.ErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
+// @Router /api/approvals [post]
+func (h *ApprovalHandler) Grant(c *gin.Context) {
+	var req models.ApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	approval, err := h.service.Grant(actorFromContext(c), req)
+	if err != nil {
+		if retryAfter, ok := helpers.RetryAfter(err); ok {
+			helpers.TooManyRequests(c, err.Error(), retryAfter)
+			return
+		}
+		if helpers.IsValidation(err) {
+			helpers.BadRequest(c, err.Error())
+			return
+		}
+		helpers.InternalError(c, "Failed to grant approval", err.Error())
+		return
+	}
+	helpers.Created(c, "Approval granted", approval)
+}
+
+// SetPIN godoc
+// @Summary Set approval PIN
+// @Description Set or change the caller's 4-8 digit approval PIN. Requires the current password.
+// @Tags Approvals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.PINInput true "Current password and new PIN"
+// @Success 200 {object} helpers.Response
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body or validation error"
+// @Router /api/approvals/pin [put]
+func (h *ApprovalHandler) SetPIN(c *gin.Context) {
+	var input models.PINInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.service.SetPIN(actorFromContext(c), input.CurrentPassword, input.PIN); err != nil {
+		switch {
+		case helpers.IsValidation(err):
+			helpers.BadRequest(c, err.Error())
+		case helpers.IsNotFound(err):
+			helpers.NotFound(c, err.Error())
+		default:
+			helpers.InternalError(c, "Failed to set PIN", err.Error())
+		}
+		return
+	}
+	helpers.OK(c, "PIN updated successfully", nil)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handles HTTP requests for the audit log
+type AuditHandler struct {
+	service services.AuditService
+}
+
+// NewAuditHandler creates a new audit handler instance
+func NewAuditHandler(service services.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// List godoc
+// @Summary List audit log entries
+// @Description Retrieve a paginated list of mutating actions, newest first (owner only)
+// @Tags Audit
+// @Produce json
+// @Security BearerAuth
+// @Param entity_type query string false "Filter by entity type (category, product, transaction, user)"
+// @Param entity_id query int false "Filter by entity ID"
+// @Param actor_id query int false "Filter by acting user ID"
+// @Param action query string false "Filter by action (create, update, delete, void)"
+// @Param start_date query string false "Start date filter (YYYY-MM-DD)"
+// @Param end_date query string false "End date filter (YYYY-MM-DD)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} helpers.PaginatedResponse{data=[]models.AuditLog}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid filter"
+// @Router /api/audit-log [get]
+func (h *AuditHandler) List(c *gin.Context) {
+	page, limit := helpers.ParsePagination(c)
+	params := models.AuditLogListParams{
+		EntityType: strings.TrimSpace(c.Query("entity_type")),
+		Action:     strings.TrimSpace(c.Query("action")),
+		StartDate:  strings.TrimSpace(c.Query("start_date")),
+		EndDate:    strings.TrimSpace(c.Query("end_date")),
+		Page:       page,
+		Limit:      limit,
+	}
+
+	if v := c.Query("entity_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			helpers.BadRequest(c, "Invalid entity_id")
+			return
+		}
+		params.EntityID = &id
+	}
+	if v := c.Query("actor_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			helpers.BadRequest(c, "Invalid actor_id")
+			return
+		}
+		params.ActorID = &id
+	}
+
+	result, err := h.service.GetAll(params)
+	if err != nil {
+		helpers.InternalError(c, "Failed to retrieve audit log", err.Error())
+		return
+	}
+	helpers.Paginated(c, "Successfully retrieved audit log", result.Data, helpers.PaginationMeta{
+		Page:       result.Page,
+		Limit:      result.Limit,
+		Total:      &result.Total,
+		TotalPages: &result.TotalPages,
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"retail-core-api/helpers"
 	"retail-core-api/models"
 	"retail-core-api/services"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,7 +22,7 @@ func NewAuthHandler(authService services.AuthService) *AuthHandler {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return JWT token. Accounts with two-factor authentication get a challenge_token to complete at /auth/login/2fa instead. Repeated failures lock the account with exponential backoff and throttle the client IP.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -28,6 +30,8 @@ func NewAuthHandler(authService services.AuthService) *AuthHandler {
 // @Success 200 {object} helpers.Response
 // @Failure 400 {object} helpers.Response
 // @Failure 401 {object} helpers.Response
+// @Failure 429 {object} helpers.ErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var input models.LoginInput
@@ -41,8 +45,44 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	result, err := h.authService.Login(input.Email, input.Password)
+	result, err := h.authService.Login(input.Email, input.Password, clientInfo(c))
 	if err != nil {
+		if retryAfter, ok := helpers.RetryAfter(err); ok {
+			helpers.TooManyRequests(c, err.Error(), retryAfter)
+			return
+		}
+		helpers.Unauthorized(c, err.Error())
+		return
+	}
+
+	helpers.OK(c, "Login successful", result)
+}
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token from /auth/login and a TOTP or recovery code for a JWT
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorLoginInput true "Challenge token and code"
+// @Success 200 {object} helpers.Response{data=models.LoginResponse}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body"
+// @Failure 401 {object} helpers.ErrorResponse "Invalid code or expired challenge"
+// @Failure 429 {object} helpers.ErrorResponse "Too many failed attempts; see the Retry-After header"
+// @Router /auth/login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var input models.TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.authService.VerifyTwoFactor(input.ChallengeToken, input.Code, clientInfo(c))
+	if err != nil {
+		if retryAfter, ok := helpers.RetryAfter(err); ok {
+			helpers.TooManyRequests(c, err.Error(), retryAfter)
+			return
+		}
 		helpers.Unauthorized(c, err.Error())
 		return
 	}
@@ -52,13 +92,15 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 // Register godoc
 // @Summary Register new user
-// @Description Create a new user account (owner-only)
+// @Description Create a new user account (requires the user.manage permission)
 // @Tags Auth
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param body body models.UserInput true "User registration data"
 // @Success 201 {object} helpers.Response
 // @Failure 400 {object} helpers.Response
+// @Failure 403 {object} helpers.Response "Role grants permissions the caller does not hold"
 // @Failure 409 {object} helpers.Response
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -78,15 +120,105 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		role = "cashier"
 	}
 
-	user, err := h.authService.Register(input.Name, input.Email, input.Password, role)
+	user, err := h.authService.Register(actorFromContext(c), input.Name, input.Email, input.Password, role)
 	if err != nil {
 		if err.Error() == "email already registered" {
 			helpers.Error(c, 409, err.Error())
 			return
 		}
+		if helpers.IsForbidden(err) {
+			helpers.Forbidden(c, err.Error())
+			return
+		}
 		helpers.BadRequest(c, err.Error())
 		return
 	}
 
 	helpers.Created(c, "User registered successfully", user)
 }
+
+// ChangePassword godoc
+// @Summary Change own password
+// @Description Change the caller's password. This is the only route available while a password change is required; the response carries a fresh token.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.ChangePasswordInput true "Current and new password"
+// @Success 200 {object} helpers.Response{data=models.LoginResponse}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body or password policy violation"
+// @Failure 401 {object} helpers.ErrorResponse "Authorization required"
+// @Router /auth/change-password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var input models.ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.authService.ChangePassword(actorFromContext(c), input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		switch {
+		case helpers.IsValidation(err):
+			helpers.BadRequest(c, err.Error())
+		case helpers.IsNotFound(err):
+			helpers.Unauthorized(c, err.Error())
+		default:
+			helpers.InternalError(c, "Failed to change password", err.Error())
+		}
+		return
+	}
+
+	helpers.OK(c, "Password changed successfully", result)
+}
+
+// ListLoginAttempts godoc
+// @Summary List login attempts
+// @Description Retrieve a paginated list of login attempts, newest first
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param email query string false "Filter by email"
+// @Param user_id query int false "Filter by user ID"
+// @Param ip query string false "Filter by client IP"
+// @Param result query string false "Filter by result (success, invalid_credentials, inactive, locked, throttled)"
+// @Param start_date query string false "Start date filter (YYYY-MM-DD)"
+// @Param end_date query string false "End date filter (YYYY-MM-DD)"
+// @Param page query int false "Page number (default: 1)"
+// @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Success 200 {object} helpers.PaginatedResponse{data=[]models.LoginAttempt}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid filter"
+// @Router /api/login-attempts [get]
+func (h *AuthHandler) ListLoginAttempts(c *gin.Context) {
+	page, limit := helpers.ParsePagination(c)
+	params := models.LoginAttemptListParams{
+		Email:     strings.TrimSpace(c.Query("email")),
+		IP:        strings.TrimSpace(c.Query("ip")),
+		Result:    strings.TrimSpace(c.Query("result")),
+		StartDate: strings.TrimSpace(c.Query("start_date")),
+		EndDate:   strings.TrimSpace(c.Query("end_date")),
+		Page:      page,
+		Limit:     limit,
+	}
+
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			helpers.BadRequest(c, "Invalid user_id")
+			return
+		}
+		params.UserID = &id
+	}
+
+	result, err := h.authService.GetLoginAttempts(params)
+	if err != nil {
+		helpers.InternalError(c, "Failed to retrieve login attempts", err.Error())
+		return
+	}
+	helpers.Paginated(c, "Successfully retrieved login attempts", result.Data, helpers.PaginationMeta{
+		Page:       result.Page,
+		Limit:      result.Limit,
+		Total:      &result.Total,
+		TotalPages: &result.TotalPages,
+	})
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"database/sql"
+	"net/http"
 	"retail-core-api/helpers"
 	"retail-core-api/models"
 	"retail-core-api/services"
@@ -23,13 +23,19 @@ func NewCategoryHandler(service services.CategoryService, productService service
 
 // List godoc
 // @Summary Get all categories
-// @Description Retrieve a list of all categories
+// @Description Retrieve a flat list of all categories, or with tree=true the root categories with their descendants nested under children
 // @Tags Categories
 // @Produce json
+// @Param tree query bool false "Return the category tree"
 // @Success 200 {object} helpers.Response{data=[]models.Category} "Successfully retrieved all categories"
 // @Router /categories [get]
 func (h *CategoryHandler) List(c *gin.Context) {
-	categories, err := h.service.GetAllCategories()
+	getCategories := h.service.GetAllCategories
+	if tree, _ := strconv.ParseBool(c.Query("tree")); tree {
+		getCategories = h.service.GetCategoryTree
+	}
+
+	categories, err := getCategories()
 	if err != nil {
 		helpers.InternalError(c, "Failed to retrieve categories", err.Error())
 		return
@@ -86,9 +92,10 @@ func (h *CategoryHandler) Create(c *gin.Context) {
 	category := models.Category{
 		Name:        input.Name,
 		Description: input.Description,
+		ParentID:    input.ParentID,
 	}
 
-	created, err := h.service.CreateCategory(category)
+	created, err := h.service.CreateCategory(actorFromContext(c), category)
 	if err != nil {
 		helpers.BadRequest(c, err.Error())
 		return
@@ -124,9 +131,10 @@ func (h *CategoryHandler) Update(c *gin.Context) {
 	category := models.Category{
 		Name:        input.Name,
 		Description: input.Description,
+		ParentID:    input.ParentID,
 	}
 
-	updated, err := h.service.UpdateCategory(id, category)
+	updated, err := h.service.UpdateCategory(actorFromContext(c), id, category)
 	if err != nil {
 		if helpers.IsNotFound(err) || err.Error() == "category not found" {
 			helpers.NotFound(c, "Category not found")
@@ -140,13 +148,16 @@ func (h *CategoryHandler) Update(c *gin.Context) {
 
 // Delete godoc
 // @Summary Delete a category
-// @Description Delete a category by its ID
+// @Description Delete a category by its ID. A category with products or subcategories is refused unless reassign_to moves them to another category first, or archive=true archives it with its subcategories and deactivates their products. Both run atomically.
 // @Tags Categories
 // @Produce json
 // @Param id path int true "Category ID"
-// @Success 200 {object} helpers.Response "Category deleted successfully"
-// @Failure 400 {object} helpers.ErrorResponse "Invalid category ID"
+// @Param reassign_to query int false "Move products and subcategories to this category, then delete"
+// @Param archive query bool false "Archive the category and its subtree instead of deleting"
+// @Success 200 {object} helpers.Response{data=models.CategoryDeleteResult} "Category deleted successfully"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid category ID or options"
 // @Failure 404 {object} helpers.ErrorResponse "Category not found"
+// @Failure 409 {object} helpers.ErrorResponse "Category still has products or subcategories"
 // @Router /categories/{id} [delete]
 func (h *CategoryHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -155,24 +166,51 @@ func (h *CategoryHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	err = h.service.DeleteCategory(id)
+	var opts models.CategoryDeleteOptions
+	if v := c.Query("reassign_to"); v != "" {
+		target, err := strconv.Atoi(v)
+		if err != nil || target <= 0 {
+			helpers.BadRequest(c, "Invalid reassign_to")
+			return
+		}
+		opts.ReassignTo = &target
+	}
+	if v := c.Query("archive"); v != "" {
+		if opts.Archive, err = strconv.ParseBool(v); err != nil {
+			helpers.BadRequest(c, "Invalid archive")
+			return
+		}
+	}
+
+	result, err := h.service.DeleteCategory(actorFromContext(c), id, opts)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		switch {
+		case helpers.IsNotFound(err):
 			helpers.NotFound(c, "Category not found")
-			return
+		case helpers.IsConflict(err):
+			helpers.Error(c, http.StatusConflict, err.Error())
+		case helpers.IsValidation(err):
+			helpers.BadRequest(c, err.Error())
+		default:
+			helpers.InternalError(c, "Failed to delete category", err.Error())
 		}
-		helpers.InternalError(c, "Failed to delete category", err.Error())
 		return
 	}
-	helpers.OK(c, "Category deleted successfully", nil)
+
+	message := "Category deleted successfully"
+	if result.Action == models.CategoryArchived {
+		message = "Category archived successfully"
+	}
+	helpers.OK(c, message, result)
 }
 
 // GetProducts godoc
 // @Summary Get products by category
-// @Description Retrieve all products belonging to a specific category
+// @Description Retrieve all active products belonging to a category or any of its descendant categories
 // @Tags Categories
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param include_inactive query bool false "Include inactive products (requires product.write)"
 // @Success 200 {object} helpers.Response{data=[]models.Product} "Products retrieved successfully"
 // @Failure 400 {object} helpers.ErrorResponse "Invalid category ID"
 // @Router /categories/{id}/products [get]
@@ -183,7 +221,12 @@ func (h *CategoryHandler) GetProducts(c *gin.Context) {
 		return
 	}
 
-	products, err := h.productService.GetProductsByCategoryID(id)
+	includeInactive, ok := catalogFlag(c, "include_inactive")
+	if !ok {
+		return
+	}
+
+	products, err := h.productService.GetProductsByCategoryID(id, includeInactive)
 	if err != nil {
 		helpers.InternalError(c, "Failed to get products", err.Error())
 		return
@@ -0,0 +1,9 @@
+package handlers
+
+import "time"
+
+// exportFilename names a spreadsheet download after its contents and the
+// day it was taken
+func exportFilename(name string) string {
+	return name + "-" + time.Now().Format("20060102")
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"retail-core-api/database"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	db      *sql.DB
+	build   models.BuildInfo
+	timeout time.Duration
+}
+
+// NewHealthHandler creates a new health handler instance
+func NewHealthHandler(db *sql.DB, build models.BuildInfo, timeout time.Duration) *HealthHandler {
+	return &HealthHandler{db: db, build: build, timeout: timeout}
+}
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Reports whether the process is up. Does not check dependencies.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} helpers.Response
+// @Router /health/live [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	helpers.OK(c, "Server is running successfully", gin.H{"status": "OK"})
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Pings the database and reports pool stats, schema version and build info. Returns 503 when a dependency is down.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} helpers.Response{data=models.ReadinessReport}
+// @Failure 503 {object} helpers.Response{data=models.ReadinessReport}
+// @Router /health/ready [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	report := models.ReadinessReport{
+		Status:                "ready",
+		Checks:                map[string]models.DependencyCheck{},
+		ExpectedSchemaVersion: database.SchemaVersion,
+		Build:                 h.build,
+	}
+
+	// Database connectivity
+	start := time.Now()
+	dbCheck := models.DependencyCheck{Status: "up"}
+	if err := h.db.PingContext(ctx); err != nil {
+		dbCheck.Status = "down"
+		dbCheck.Error = err.Error()
+	}
+	dbCheck.LatencyMs = time.Since(start).Milliseconds()
+	report.Checks["database"] = dbCheck
+
+	// Schema version (only meaningful if the database is reachable)
+	if dbCheck.Status == "up" {
+		version, err := database.GetSchemaVersion(ctx, h.db)
+		check := models.DependencyCheck{Status: "up"}
+		if err != nil {
+			check.Status = "down"
+			check.Error = err.Error()
+		} else if version < database.SchemaVersion {
+			check.Status = "down"
+			check.Error = "database schema is behind the expected version"
+		}
+		report.SchemaVersion = version
+		report.Checks["migrations"] = check
+	}
+
+	stats := h.db.Stats()
+	report.DBPool = models.DBPoolStats{
+		MaxOpen: stats.MaxOpenConnections,
+		Open:    stats.OpenConnections,
+		InUse:   stats.InUse,
+		Idle:    stats.Idle,
+	}
+
+	for _, check := range report.Checks {
+		if check.Status != "up" {
+			report.Status = "not_ready"
+		}
+	}
+
+	if report.Status != "ready" {
+		c.JSON(http.StatusServiceUnavailable, helpers.Response{
+			Status:  false,
+			Message: "Service is not ready",
+			Data:    report,
+		})
+		return
+	}
+	helpers.OK(c, "Service is ready", report)
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"retail-core-api/helpers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public keys that verify issued JWTs
+type JWKSHandler struct {
+	keys *helpers.JWTKeys
+}
+
+// NewJWKSHandler creates a new JWKS handler instance
+func NewJWKSHandler(keys *helpers.JWTKeys) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens, in standard JWKS format (not wrapped in the API envelope). Empty when tokens are signed with HS256.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} helpers.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetHandler handles forgotten password recovery endpoints
+type PasswordResetHandler struct {
+	service services.PasswordResetService
+}
+
+// NewPasswordResetHandler creates a new password reset handler instance
+func NewPasswordResetHandler(service services.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{service: service}
+}
+
+// clientInfo describes the client of an unauthenticated request
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: c.GetString(helpers.RequestIDKey),
+	}
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Email a single-use reset link. The response is the same whether or not the email belongs to an account.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.ForgotPasswordInput true "Account email"
+// @Success 200 {object} helpers.Response
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body"
+// @Failure 429 {object} helpers.ErrorResponse "Too many reset requests for this email or client; see the Retry-After header"
+// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
+// @Router /auth/forgot-password [post]
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var input models.ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.service.RequestReset(input.Email, clientInfo(c)); err != nil {
+		if retryAfter, ok := helpers.RetryAfter(err); ok {
+			helpers.TooManyRequests(c, err.Error(), retryAfter)
+			return
+		}
+		helpers.InternalError(c, "Failed to request password reset", err.Error())
+		return
+	}
+
+	helpers.OK(c, "If the email belongs to an account, a reset link has been sent", nil)
+}
+
+// ResetPassword godoc
+// @Summary Reset password with a token
+// @Description Set a new password using the token from a reset email. Tokens are single-use and expire.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.PasswordResetInput true "Reset token and new password"
+// @Success 200 {object} helpers.Response
+// @Failure 400 {object} helpers.ErrorResponse "Invalid or expired token, or password policy violation"
+// @Router /auth/reset-password [post]
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var input models.PasswordResetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.service.Reset(input.Token, input.NewPassword, clientInfo(c)); err != nil {
+		if helpers.IsValidation(err) {
+			helpers.BadRequest(c, err.Error())
+			return
+		}
+		helpers.InternalError(c, "Failed to reset password", err.Error())
+		return
+	}
+
+	helpers.OK(c, "Password reset successfully", nil)
+}
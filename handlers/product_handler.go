@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"net/http"
 	"retail-core-api/helpers"
+	"retail-core-api/middleware"
 	"retail-core-api/models"
 	"retail-core-api/services"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,14 +24,32 @@ func NewProductHandler(service services.ProductService) *ProductHandler {
 
 // List godoc
 // @Summary Get all products (paginated)
-// @Description Retrieve a paginated list of products. Supports search by name and filter by category_id.
+// @Description Retrieve a paginated list of products. Supports search across name, SKU and category name, ranked by relevance, and filter by category_id. Inactive and archived products are hidden unless include_inactive=true or include_deleted=true is passed by a caller with product.write. With format=csv|xlsx or a matching Accept header, every matching product is downloaded as a spreadsheet instead.
 // @Tags Products
 // @Produce json
-// @Param search query string false "Search product by name (case-insensitive partial match)"
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param search query string false "Search name, SKU and category (word prefixes, tolerates typos)"
 // @Param category_id query int false "Filter by category ID"
+// @Param include_inactive query bool false "Include inactive products (requires product.write)"
+// @Param include_deleted query bool false "Include archived products (requires product.write)"
+// @Param min_price query int false "Minimum price"
+// @Param max_price query int false "Maximum price"
+// @Param min_stock query int false "Minimum stock"
+// @Param max_stock query int false "Maximum stock"
+// @Param low_stock query bool false "Only products with stock below the low-stock threshold (10)"
+// @Param is_active query bool false "Filter by active status (false requires product.write)"
+// @Param unit query string false "Filter by unit, e.g. pcs"
+// @Param updated_since query string false "Only products updated at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param sort query string false "Comma-separated sort fields, '-' for descending: id, name, price, stock, created_at, updated_at, units_sold (default: relevance when searching, else -id)"
 // @Param page query int false "Page number (default: 1)"
 // @Param limit query int false "Items per page (default: 20)"
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; replaces page"
+// @Param include_total query bool false "Set to false to skip counting the total (default: true)"
+// @Param format query string false "json (default), csv or xlsx"
 // @Success 200 {object} helpers.PaginatedResponse
+// @Failure 400 {object} helpers.ErrorResponse "Invalid filter, sort, cursor or format"
+// @Failure 403 {object} helpers.ErrorResponse "include_inactive, include_deleted or is_active=false without product.write"
 // @Router /products [get]
 func (h *ProductHandler) List(c *gin.Context) {
 	params := models.ProductListParams{
@@ -46,6 +67,60 @@ func (h *ProductHandler) List(c *gin.Context) {
 		}
 	}
 
+	var ok bool
+	if params.IncludeInactive, ok = catalogFlag(c, "include_inactive"); !ok {
+		return
+	}
+	if params.IncludeDeleted, ok = catalogFlag(c, "include_deleted"); !ok {
+		return
+	}
+
+	if params.MinPrice, ok = queryInt(c, "min_price"); !ok {
+		return
+	}
+	if params.MaxPrice, ok = queryInt(c, "max_price"); !ok {
+		return
+	}
+	if params.MinStock, ok = queryInt(c, "min_stock"); !ok {
+		return
+	}
+	if params.MaxStock, ok = queryInt(c, "max_stock"); !ok {
+		return
+	}
+	lowStock, ok := queryBool(c, "low_stock")
+	if !ok {
+		return
+	}
+	params.LowStock = lowStock != nil && *lowStock
+	if params.IsActive, ok = queryBool(c, "is_active"); !ok {
+		return
+	}
+	if params.IsActive != nil && !*params.IsActive && !middleware.HasPermission(c, models.PermProductWrite) {
+		helpers.Forbidden(c, "is_active=false requires the product.write permission")
+		return
+	}
+	params.Unit = strings.TrimSpace(c.Query("unit"))
+	if params.UpdatedSince, ok = queryTime(c, "updated_since"); !ok {
+		return
+	}
+
+	sort, err := helpers.ParseSort(c.Query("sort"), models.ProductSortFields)
+	if err != nil {
+		helpers.BadRequest(c, err.Error())
+		return
+	}
+	params.Sort = sort
+
+	format, err := helpers.ExportFormat(c)
+	if err != nil {
+		helpers.BadRequest(c, err.Error())
+		return
+	}
+	if format != "" {
+		h.export(c, format, params)
+		return
+	}
+
 	if page := c.Query("page"); page != "" {
 		if p, err := strconv.Atoi(page); err == nil {
 			params.Page = p
@@ -63,9 +138,16 @@ func (h *ProductHandler) List(c *gin.Context) {
 	if params.Limit <= 0 {
 		params.Limit = 20
 	}
+	if params.Cursor, params.SkipTotal, ok = listPaging(c); !ok {
+		return
+	}
 
 	result, err := h.service.GetAllProducts(params)
 	if err != nil {
+		if helpers.IsValidation(err) {
+			helpers.BadRequest(c, err.Error())
+			return
+		}
 		helpers.InternalError(c, "Failed to retrieve products", err.Error())
 		return
 	}
@@ -75,9 +157,58 @@ func (h *ProductHandler) List(c *gin.Context) {
 		Limit:      result.Limit,
 		Total:      result.Total,
 		TotalPages: result.TotalPages,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
 	})
 }
 
+// export downloads every product matching params as a spreadsheet
+func (h *ProductHandler) export(c *gin.Context, format string, params models.ProductListParams) {
+	w := helpers.NewExportWriter(c, format, exportFilename("products"),
+		"id", "sku", "name", "category", "price", "stock", "unit", "is_active", "image_url", "deleted_at", "created_at", "updated_at")
+	err := h.service.ExportProducts(params, func(p models.Product) error {
+		return w.Write(p.ID, p.SKU, p.Name, p.CategoryName, p.Price, p.Stock, p.Unit, p.IsActive, p.ImageURL, p.DeletedAt, p.CreatedAt, p.UpdatedAt)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		w.Fail(err)
+	}
+}
+
+// Autocomplete godoc
+// @Summary Product search suggestions
+// @Description Return the top matching active products for search-as-you-type, ranked by relevance. Matches word prefixes of name, SKU and category, and tolerates typos in names.
+// @Tags Products
+// @Produce json
+// @Param q query string true "Search text"
+// @Param limit query int false "Maximum suggestions (default: 10, max: 20)"
+// @Success 200 {object} helpers.Response{data=[]models.ProductSuggestion} "Suggestions retrieved successfully"
+// @Router /api/products/autocomplete [get]
+func (h *ProductHandler) Autocomplete(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	suggestions, err := h.service.Autocomplete(c.Query("q"), limit)
+	if err != nil {
+		helpers.InternalError(c, "Failed to search products", err.Error())
+		return
+	}
+	helpers.OK(c, "Suggestions retrieved successfully", suggestions)
+}
+
+// catalogFlag reads a boolean query parameter that widens a product listing
+// beyond what the POS catalog shows. Only callers who maintain the catalog
+// may set it; for anyone else a 403 is sent and ok is false.
+func catalogFlag(c *gin.Context, name string) (value bool, ok bool) {
+	value, _ = strconv.ParseBool(c.Query(name))
+	if value && !middleware.HasPermission(c, models.PermProductWrite) {
+		helpers.Forbidden(c, name+" requires the product.write permission")
+		return false, false
+	}
+	return value, true
+}
+
 // GetByID godoc
 // @Summary Get a product by ID
 // @Description Retrieve details of a specific product by its ID with category name
@@ -134,13 +265,12 @@ func (h *ProductHandler) Create(c *gin.Context) {
 		Price:      input.Price,
 		Stock:      input.Stock,
 		SKU:        input.SKU,
-		ImageURL:   input.ImageURL,
 		Unit:       input.Unit,
 		IsActive:   isActive,
 		CategoryID: input.CategoryID,
 	}
 
-	created, err := h.service.CreateProduct(product)
+	created, err := h.service.CreateProduct(actorFromContext(c), product)
 	if err != nil {
 		helpers.BadRequest(c, err.Error())
 		return
@@ -178,7 +308,6 @@ func (h *ProductHandler) Update(c *gin.Context) {
 		Price:      input.Price,
 		Stock:      input.Stock,
 		SKU:        input.SKU,
-		ImageURL:   input.ImageURL,
 		Unit:       input.Unit,
 		CategoryID: input.CategoryID,
 	}
@@ -189,11 +318,14 @@ func (h *ProductHandler) Update(c *gin.Context) {
 		product.IsActive = true
 	}
 
-	updated, err := h.service.UpdateProduct(id, product)
+	updated, err := h.service.UpdateProduct(actorFromContext(c), id, product)
 	if err != nil {
-		if helpers.IsNotFound(err) || err.Error() == "product not found" {
+		switch {
+		case helpers.IsNotFound(err) || err.Error() == "product not found":
 			helpers.NotFound(c, "Product not found")
-		} else {
+		case helpers.IsConflict(err):
+			helpers.Error(c, http.StatusConflict, err.Error())
+		default:
 			helpers.BadRequest(c, err.Error())
 		}
 		return
@@ -203,13 +335,14 @@ func (h *ProductHandler) Update(c *gin.Context) {
 
 // Delete godoc
 // @Summary Delete a product
-// @Description Delete a product by its ID
+// @Description Archive a product by its ID. It is hidden from listings and checkout but kept for past transactions, and can be restored.
 // @Tags Products
 // @Produce json
 // @Param id path int true "Product ID"
 // @Success 200 {object} helpers.Response "Product deleted successfully"
 // @Failure 400 {object} helpers.ErrorResponse "Invalid product ID"
 // @Failure 404 {object} helpers.ErrorResponse "Product not found"
+// @Failure 409 {object} helpers.ErrorResponse "Product is already archived"
 // @Router /products/{id} [delete]
 func (h *ProductHandler) Delete(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -218,14 +351,51 @@ func (h *ProductHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	err = h.service.DeleteProduct(id)
+	err = h.service.DeleteProduct(actorFromContext(c), id)
 	if err != nil {
 		if helpers.IsNotFound(err) || err.Error() == "product not found" {
 			helpers.NotFound(c, "Product not found")
 			return
 		}
+		if helpers.IsConflict(err) {
+			helpers.Error(c, http.StatusConflict, err.Error())
+			return
+		}
 		helpers.InternalError(c, "Failed to delete product", err.Error())
 		return
 	}
 	helpers.OK(c, "Product deleted successfully", nil)
 }
+
+// Restore godoc
+// @Summary Restore an archived product
+// @Description Bring an archived product back into listings and checkout
+// @Tags Products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} helpers.Response{data=models.Product} "Product restored successfully"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid product ID"
+// @Failure 404 {object} helpers.ErrorResponse "Product not found"
+// @Failure 409 {object} helpers.ErrorResponse "Product is not archived"
+// @Router /products/{id}/restore [post]
+func (h *ProductHandler) Restore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		helpers.BadRequest(c, "Invalid product ID")
+		return
+	}
+
+	restored, err := h.service.RestoreProduct(actorFromContext(c), id)
+	if err != nil {
+		switch {
+		case helpers.IsNotFound(err):
+			helpers.NotFound(c, "Product not found")
+		case helpers.IsConflict(err):
+			helpers.Error(c, http.StatusConflict, err.Error())
+		default:
+			helpers.InternalError(c, "Failed to restore product", err.Error())
+		}
+		return
+	}
+	helpers.OK(c, "Product restored successfully", restored)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"retail-core-api/helpers"
+	"retail-core-api/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProductImageHandler handles product image uploads and management
+type ProductImageHandler struct {
+	service     services.ProductImageService
+	maxFileSize int64
+}
+
+// NewProductImageHandler creates a new product image handler instance
+func NewProductImageHandler(service services.ProductImageService, maxFileSize int64) *ProductImageHandler {
+	return &ProductImageHandler{service: service, maxFileSize: maxFileSize}
+}
+
+// imageIDs parses the product and image IDs of an image route. For invalid
+// IDs a 400 is sent and ok is false.
+func imageIDs(c *gin.Context) (productID, imageID int, ok bool) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || productID <= 0 {
+		helpers.BadRequest(c, "Invalid product ID")
+		return 0, 0, false
+	}
+	imageID, err = strconv.Atoi(c.Param("imageId"))
+	if err != nil || imageID <= 0 {
+		helpers.BadRequest(c, "Invalid image ID")
+		return 0, 0, false
+	}
+	return productID, imageID, true
+}
+
+// List godoc
+// @Summary List product images
+// @Description Get the images of a product, primary first, with thumbnail URLs
+// @Tags Products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Success 200 {object} helpers.Response{data=[]models.ProductImage} "Successfully retrieved images"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid product ID"
+// @Failure 404 {object} helpers.ErrorResponse "Product not found"
+// @Router /api/products/{id}/images [get]
+func (h *ProductImageHandler) List(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || productID <= 0 {
+		helpers.BadRequest(c, "Invalid product ID")
+		return
+	}
+
+	images, err := h.service.List(productID)
+	if err != nil {
+		if helpers.IsNotFound(err) {
+			helpers.NotFound(c, "Product not found")
+			return
+		}
+		helpers.InternalError(c, "Failed to retrieve images", err.Error())
+		return
+	}
+	helpers.OK(c, "Successfully retrieved images", images)
+}
+
+// Upload godoc
+// @Summary Upload a product image
+// @Description Upload a JPEG, PNG or WebP image for a product. A thumbnail is generated. The product's first image, or one uploaded with primary=true, becomes its primary image and its image_url.
+// @Tags Products
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param file formData file true "Image file"
+// @Param primary formData bool false "Make this the primary image"
+// @Success 201 {object} helpers.Response{data=models.ProductImage} "Image uploaded successfully"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid or unsupported image"
+// @Failure 404 {object} helpers.ErrorResponse "Product not found"
+// @Failure 409 {object} helpers.ErrorResponse "Product has the maximum number of images"
+// @Failure 413 {object} helpers.ErrorResponse "File too large"
+// @Router /api/products/{id}/images [post]
+func (h *ProductImageHandler) Upload(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || productID <= 0 {
+		helpers.BadRequest(c, "Invalid product ID")
+		return
+	}
+
+	limitUpload(c, h.maxFileSize)
+	header, err := c.FormFile("file")
+	if isBodyTooLarge(err) {
+		helpers.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File is larger than %d bytes", h.maxFileSize))
+		return
+	}
+	if err != nil {
+		helpers.BadRequest(c, "A file upload named 'file' is required")
+		return
+	}
+	if header.Size > h.maxFileSize {
+		helpers.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File is larger than %d bytes", h.maxFileSize))
+		return
+	}
+
+	var primary bool
+	if v := c.PostForm("primary"); v != "" {
+		if primary, err = strconv.ParseBool(v); err != nil {
+			helpers.BadRequest(c, "Invalid primary")
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		helpers.InternalError(c, "Failed to read upload", err.Error())
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, h.maxFileSize+1))
+	if err != nil {
+		helpers.InternalError(c, "Failed to read upload", err.Error())
+		return
+	}
+
+	image, err := h.service.Upload(c.Request.Context(), actorFromContext(c), productID, data, primary)
+	if err != nil {
+		switch {
+		case helpers.IsNotFound(err):
+			helpers.NotFound(c, "Product not found")
+		case helpers.IsValidation(err):
+			helpers.BadRequest(c, err.Error())
+		case helpers.IsConflict(err):
+			helpers.Error(c, http.StatusConflict, err.Error())
+		default:
+			helpers.InternalError(c, "Failed to upload image", err.Error())
+		}
+		return
+	}
+	helpers.Created(c, "Image uploaded successfully", image)
+}
+
+// SetPrimary godoc
+// @Summary Set the primary product image
+// @Description Make an image the product's primary image; its URL becomes the product's image_url
+// @Tags Products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param imageId path int true "Image ID"
+// @Success 200 {object} helpers.Response{data=models.ProductImage} "Primary image updated"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid ID"
+// @Failure 404 {object} helpers.ErrorResponse "Image not found"
+// @Router /api/products/{id}/images/{imageId}/primary [put]
+func (h *ProductImageHandler) SetPrimary(c *gin.Context) {
+	productID, imageID, ok := imageIDs(c)
+	if !ok {
+		return
+	}
+
+	image, err := h.service.SetPrimary(actorFromContext(c), productID, imageID)
+	if err != nil {
+		if helpers.IsNotFound(err) {
+			helpers.NotFound(c, "Image not found")
+			return
+		}
+		helpers.InternalError(c, "Failed to update image", err.Error())
+		return
+	}
+	helpers.OK(c, "Primary image updated", image)
+}
+
+// Delete godoc
+// @Summary Delete a product image
+// @Description Delete an image and its thumbnail. If it was the primary image, the oldest remaining image becomes primary.
+// @Tags Products
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param imageId path int true "Image ID"
+// @Success 200 {object} helpers.Response "Image deleted successfully"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid ID"
+// @Failure 404 {object} helpers.ErrorResponse "Image not found"
+// @Router /api/products/{id}/images/{imageId} [delete]
+func (h *ProductImageHandler) Delete(c *gin.Context) {
+	productID, imageID, ok := imageIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), actorFromContext(c), productID, imageID); err != nil {
+		if helpers.IsNotFound(err) {
+			helpers.NotFound(c, "Image not found")
+			return
+		}
+		helpers.InternalError(c, "Failed to delete image", err.Error())
+		return
+	}
+	helpers.OK(c, "Image deleted successfully", nil)
+}
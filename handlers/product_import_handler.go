@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadTimeout replaces the server's read and write timeouts on upload
+// routes, so a large file on a slow link, and the processing that follows,
+// are not cut off by SERVER_READ_TIMEOUT and SERVER_WRITE_TIMEOUT
+const uploadTimeout = 5 * time.Minute
+
+// multipartOverhead allows for the multipart framing and small form fields
+// sent along with an uploaded file
+const multipartOverhead = 1 << 20
+
+// limitUpload extends the connection deadlines for an upload and caps the
+// request body at maxFileSize plus the multipart overhead, so an oversized
+// upload is refused while it is read instead of being spooled to disk first
+func limitUpload(c *gin.Context, maxFileSize int64) {
+	rc := http.NewResponseController(c.Writer)
+	deadline := time.Now().Add(uploadTimeout)
+	if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		helpers.Logger(c).Warn("Failed to extend upload read deadline", "error", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		helpers.Logger(c).Warn("Failed to extend upload write deadline", "error", err)
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize+multipartOverhead)
+}
+
+// isBodyTooLarge reports whether err comes from a body cut off by limitUpload
+func isBodyTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+// ProductImportHandler handles bulk product import uploads
+type ProductImportHandler struct {
+	service     services.ProductImportService
+	maxFileSize int64
+}
+
+// NewProductImportHandler creates a new product import handler instance
+func NewProductImportHandler(service services.ProductImportService, maxFileSize int64) *ProductImportHandler {
+	return &ProductImportHandler{service: service, maxFileSize: maxFileSize}
+}
+
+// Import godoc
+// @Summary Import products from CSV or XLSX
+// @Description Upsert products by SKU from a CSV file or the first sheet of an XLSX workbook, with at most 10000 data rows. The first row is the header; columns are matched to the fields sku, name, price, stock, unit, is_active and category by name unless mapping says otherwise. Categories are matched by name and created when missing. Every row is validated first and nothing is written if any row fails. With dry_run=true the per-row report is returned without writing. batch_size commits in batches; when a batch fails, resume_from_row tells where to restart with start_row.
+// @Tags Products
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file"
+// @Param format formData string false "csv or xlsx (default: from the file extension)"
+// @Param mapping formData string false "JSON object mapping fields to column headers, e.g. {\"name\":\"Product Name\"}"
+// @Param dry_run formData bool false "Validate and report without writing"
+// @Param batch_size formData int false "Rows per committed batch (default: whole file in one transaction)"
+// @Param start_row formData int false "Skip rows before this file row when resuming"
+// @Success 200 {object} helpers.Response{data=models.ProductImportResult} "Import completed or dry run report"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid file or options"
+// @Failure 413 {object} helpers.ErrorResponse "File too large"
+// @Failure 422 {object} helpers.Response{data=models.ProductImportResult} "Rows failed validation; nothing was imported"
+// @Failure 500 {object} helpers.Response{data=models.ProductImportResult} "A batch failed; earlier batches were committed"
+// @Router /api/products/import [post]
+func (h *ProductImportHandler) Import(c *gin.Context) {
+	limitUpload(c, h.maxFileSize)
+	header, err := c.FormFile("file")
+	if isBodyTooLarge(err) {
+		helpers.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File is larger than %d bytes", h.maxFileSize))
+		return
+	}
+	if err != nil {
+		helpers.BadRequest(c, "A file upload named 'file' is required")
+		return
+	}
+	if header.Size > h.maxFileSize {
+		helpers.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("File is larger than %d bytes", h.maxFileSize))
+		return
+	}
+
+	opts := models.ProductImportOptions{
+		Format: strings.ToLower(c.PostForm("format")),
+	}
+	if opts.Format == "" {
+		opts.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+	if v := c.PostForm("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &opts.Mapping); err != nil {
+			helpers.BadRequest(c, "Invalid mapping", err.Error())
+			return
+		}
+	}
+	if v := c.PostForm("dry_run"); v != "" {
+		if opts.DryRun, err = strconv.ParseBool(v); err != nil {
+			helpers.BadRequest(c, "Invalid dry_run")
+			return
+		}
+	}
+	if v := c.PostForm("batch_size"); v != "" {
+		if opts.BatchSize, err = strconv.Atoi(v); err != nil || opts.BatchSize < 0 {
+			helpers.BadRequest(c, "Invalid batch_size")
+			return
+		}
+	}
+	if v := c.PostForm("start_row"); v != "" {
+		if opts.StartRow, err = strconv.Atoi(v); err != nil || opts.StartRow < 0 {
+			helpers.BadRequest(c, "Invalid start_row")
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		helpers.InternalError(c, "Failed to read upload", err.Error())
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.Import(actorFromContext(c), file, opts)
+	if err != nil {
+		switch {
+		case result != nil && helpers.IsValidation(err):
+			helpers.Failure(c, http.StatusUnprocessableEntity, err.Error(), result)
+		case helpers.IsValidation(err):
+			helpers.BadRequest(c, err.Error())
+		case result != nil:
+			helpers.Failure(c, http.StatusInternalServerError, "Import stopped: "+err.Error(), result)
+		default:
+			helpers.InternalError(c, "Failed to import products", err.Error())
+		}
+		return
+	}
+
+	message := "Products imported successfully"
+	if result.DryRun {
+		message = "Dry run completed; nothing was written"
+	}
+	helpers.OK(c, message, result)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryInt parses an optional integer query parameter. For a malformed value
+// a 400 is sent and ok is false.
+func queryInt(c *gin.Context, name string) (value *int, ok bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		helpers.BadRequest(c, "Invalid "+name)
+		return nil, false
+	}
+	return &v, true
+}
+
+// queryBool parses an optional boolean query parameter. For a malformed
+// value a 400 is sent and ok is false.
+func queryBool(c *gin.Context, name string) (value *bool, ok bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		helpers.BadRequest(c, "Invalid "+name)
+		return nil, false
+	}
+	return &v, true
+}
+
+// queryTime parses an optional RFC 3339 timestamp or YYYY-MM-DD date query
+// parameter. For a malformed value a 400 is sent and ok is false.
+func queryTime(c *gin.Context, name string) (value *time.Time, ok bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if v, err := time.Parse(layout, raw); err == nil {
+			return &v, true
+		}
+	}
+	helpers.BadRequest(c, "Invalid "+name+"; use YYYY-MM-DD or RFC 3339")
+	return nil, false
+}
+
+// listPaging reads the keyset pagination parameters of a list: the opaque
+// cursor from a previous page's meta, and include_total=false to skip the
+// COUNT(*). For malformed values a 400 is sent and ok is false.
+func listPaging(c *gin.Context) (cursor *models.Cursor, skipTotal bool, ok bool) {
+	cursor, err := helpers.DecodeCursor(c.Query("cursor"))
+	if err != nil {
+		helpers.BadRequest(c, err.Error())
+		return nil, false, false
+	}
+	includeTotal, ok := queryBool(c, "include_total")
+	if !ok {
+		return nil, false, false
+	}
+	return cursor, includeTotal != nil && !*includeTotal, true
+}
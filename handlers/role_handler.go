@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler handles HTTP requests for roles and permissions
+type RoleHandler struct {
+	service services.RoleService
+}
+
+// NewRoleHandler creates a new role handler instance
+func NewRoleHandler(service services.RoleService) *RoleHandler {
+	return &RoleHandler{service: service}
+}
+
+// respondRoleError maps role service errors to HTTP responses
+func respondRoleError(c *gin.Context, err error) {
+	switch {
+	case helpers.IsNotFound(err):
+		helpers.NotFound(c, err.Error())
+	case helpers.IsConflict(err):
+		helpers.Error(c, http.StatusConflict, err.Error())
+	case helpers.IsValidation(err):
+		helpers.BadRequest(c, err.Error())
+	default:
+		helpers.InternalError(c, "Failed to process role", err.Error())
+	}
+}
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description Retrieve every permission that can be granted to a role
+// @Tags Roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} helpers.Response{data=[]string}
+// @Router /api/permissions [get]
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	helpers.OK(c, "Successfully retrieved permissions", models.AllPermissions)
+}
+
+// List godoc
+// @Summary List roles
+// @Description Retrieve all roles with their permissions
+// @Tags Roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} helpers.Response{data=[]models.Role}
+// @Router /api/roles [get]
+func (h *RoleHandler) List(c *gin.Context) {
+	roles, err := h.service.GetAllRoles()
+	if err != nil {
+		helpers.InternalError(c, "Failed to retrieve roles", err.Error())
+		return
+	}
+	helpers.OK(c, "Successfully retrieved roles", roles)
+}
+
+// GetByID godoc
+// @Summary Get a role by ID
+// @Description Retrieve a single role with its permissions
+// @Tags Roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Success 200 {object} helpers.Response{data=models.Role}
+// @Failure 404 {object} helpers.ErrorResponse "Role not found"
+// @Router /api/roles/{id} [get]
+func (h *RoleHandler) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		helpers.BadRequest(c, "Invalid role ID")
+		return
+	}
+
+	role, err := h.service.GetRoleByID(id)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	helpers.OK(c, "Role retrieved successfully", role)
+}
+
+// Create godoc
+// @Summary Create a custom role
+// @Description Define a new role with a set of permissions
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param role body models.RoleInput true "Role definition"
+// @Success 201 {object} helpers.Response{data=models.Role}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body or validation error"
+// @Failure 409 {object} helpers.ErrorResponse "Role name already exists"
+// @Router /api/roles [post]
+func (h *RoleHandler) Create(c *gin.Context) {
+	var input models.RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	role, err := h.service.CreateRole(actorFromContext(c), input)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	helpers.Created(c, "Role created successfully", role)
+}
+
+// Update godoc
+// @Summary Update a custom role
+// @Description Rename a custom role or replace its permissions. System roles are read-only.
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Param role body models.RoleInput true "Role definition"
+// @Success 200 {object} helpers.Response{data=models.Role}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body or validation error"
+// @Failure 404 {object} helpers.ErrorResponse "Role not found"
+// @Failure 409 {object} helpers.ErrorResponse "Role name already exists"
+// @Router /api/roles/{id} [put]
+func (h *RoleHandler) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		helpers.BadRequest(c, "Invalid role ID")
+		return
+	}
+
+	var input models.RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	role, err := h.service.UpdateRole(actorFromContext(c), id, input)
+	if err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	helpers.OK(c, "Role updated successfully", role)
+}
+
+// Delete godoc
+// @Summary Delete a custom role
+// @Description Delete a custom role that is not assigned to any user
+// @Tags Roles
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Success 200 {object} helpers.Response
+// @Failure 400 {object} helpers.ErrorResponse "System role"
+// @Failure 404 {object} helpers.ErrorResponse "Role not found"
+// @Failure 409 {object} helpers.ErrorResponse "Role is still assigned to users"
+// @Router /api/roles/{id} [delete]
+func (h *RoleHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		helpers.BadRequest(c, "Invalid role ID")
+		return
+	}
+
+	if err := h.service.DeleteRole(actorFromContext(c), id); err != nil {
+		respondRoleError(c, err)
+		return
+	}
+	helpers.OK(c, "Role deleted successfully", nil)
+}
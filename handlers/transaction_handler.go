@@ -20,15 +20,20 @@ func NewTransactionHandler(service services.TransactionService) *TransactionHand
 	return &TransactionHandler{service: service}
 }
 
+// ApprovalTokenHeader carries a manager approval token for sensitive actions
+const ApprovalTokenHeader = "X-Approval-Token"
+
 // Checkout godoc
 // @Summary Process checkout
-// @Description Process a checkout with items, payment method, optional discount and notes
+// @Description Process a checkout with items, payment method, optional discount and notes. Discounts above the configured threshold need a manager approval token.
 // @Tags Transactions
 // @Accept json
 // @Produce json
 // @Param request body models.CheckoutRequest true "Checkout request"
+// @Param X-Approval-Token header string false "Manager approval token for large discounts"
 // @Success 201 {object} helpers.Response{data=models.Transaction} "Checkout successful"
-// @Failure 400 {object} helpers.ErrorResponse "Invalid request body or validation error"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body, validation error or inactive product"
+// @Failure 403 {object} helpers.ErrorResponse "Manager approval required"
 // @Failure 500 {object} helpers.ErrorResponse "Server error or insufficient stock"
 // @Router /api/checkout [post]
 func (h *TransactionHandler) Checkout(c *gin.Context) {
@@ -38,14 +43,17 @@ func (h *TransactionHandler) Checkout(c *gin.Context) {
 		return
 	}
 
-	transaction, err := h.service.Checkout(req)
+	transaction, err := h.service.Checkout(actorFromContext(c), req, c.GetHeader(ApprovalTokenHeader))
 	if err != nil {
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "not found") || strings.Contains(errMsg, "insufficient stock") || strings.Contains(errMsg, "cannot be empty") || strings.Contains(errMsg, "invalid") {
-			helpers.BadRequest(c, errMsg)
+		if helpers.IsApprovalRequired(err) {
+			helpers.Forbidden(c, err.Error())
 			return
 		}
-		helpers.InternalError(c, errMsg)
+		if helpers.IsValidation(err) || helpers.IsNotFound(err) || helpers.IsInsufficientStock(err) || helpers.IsProductInactive(err) {
+			helpers.BadRequest(c, err.Error())
+			return
+		}
+		helpers.InternalError(c, err.Error())
 		return
 	}
 	helpers.Created(c, "Checkout successful", transaction)
@@ -53,22 +61,56 @@ func (h *TransactionHandler) Checkout(c *gin.Context) {
 
 // ListTransactions godoc
 // @Summary Get all transactions
-// @Description Retrieve a paginated list of all transactions with optional date range filter
+// @Description Retrieve a paginated list of all transactions with optional date range filter. With format=csv|xlsx or a matching Accept header, every transaction in the range is downloaded as a spreadsheet instead.
 // @Tags Transactions
 // @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param page query int false "Page number (default: 1)"
 // @Param limit query int false "Items per page (default: 20, max: 100)"
+// @Param cursor query string false "Opaque next_cursor or prev_cursor from a previous page; replaces page"
+// @Param include_total query bool false "Set to false to skip counting the total (default: true)"
 // @Param start_date query string false "Start date filter (YYYY-MM-DD)"
 // @Param end_date query string false "End date filter (YYYY-MM-DD)"
+// @Param sort query string false "Comma-separated sort fields, '-' for descending: id, created_at, total_amount, discount, item_count (default: -created_at)"
+// @Param format query string false "json (default), csv or xlsx"
 // @Success 200 {object} helpers.Response{data=models.PaginatedTransactions} "Successfully retrieved transactions"
+// @Failure 400 {object} helpers.ErrorResponse "Invalid sort, cursor or unsupported format"
 // @Router /api/transactions [get]
 func (h *TransactionHandler) ListTransactions(c *gin.Context) {
-	page, limit := helpers.ParsePagination(c)
-	startDate := strings.TrimSpace(c.Query("start_date"))
-	endDate := strings.TrimSpace(c.Query("end_date"))
+	params := models.TransactionListParams{
+		StartDate: strings.TrimSpace(c.Query("start_date")),
+		EndDate:   strings.TrimSpace(c.Query("end_date")),
+	}
+	params.Page, params.Limit = helpers.ParsePagination(c)
+	var ok bool
+	if params.Cursor, params.SkipTotal, ok = listPaging(c); !ok {
+		return
+	}
+
+	sort, err := helpers.ParseSort(c.Query("sort"), models.TransactionSortFields)
+	if err != nil {
+		helpers.BadRequest(c, err.Error())
+		return
+	}
+	params.Sort = sort
+
+	format, err := helpers.ExportFormat(c)
+	if err != nil {
+		helpers.BadRequest(c, err.Error())
+		return
+	}
+	if format != "" {
+		h.exportTransactions(c, format, params)
+		return
+	}
 
-	result, err := h.service.GetAllTransactions(page, limit, startDate, endDate)
+	result, err := h.service.GetAllTransactions(params)
 	if err != nil {
+		if helpers.IsValidation(err) {
+			helpers.BadRequest(c, err.Error())
+			return
+		}
 		helpers.InternalError(c, "Failed to retrieve transactions", err.Error())
 		return
 	}
@@ -77,9 +119,27 @@ func (h *TransactionHandler) ListTransactions(c *gin.Context) {
 		Limit:      result.Limit,
 		Total:      result.Total,
 		TotalPages: result.TotalPages,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
 	})
 }
 
+// exportTransactions downloads every transaction in the date range as a
+// spreadsheet
+func (h *TransactionHandler) exportTransactions(c *gin.Context, format string, params models.TransactionListParams) {
+	w := helpers.NewExportWriter(c, format, exportFilename("transactions"),
+		"id", "created_at", "status", "payment_method", "item_count", "discount", "total_amount")
+	err := h.service.ExportTransactions(params, func(t models.TransactionListItem) error {
+		return w.Write(t.ID, t.CreatedAt, t.Status, t.PaymentMethod, t.ItemCount, t.Discount, t.TotalAmount)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		w.Fail(err)
+	}
+}
+
 // GetTransactionByID godoc
 // @Summary Get a transaction by ID
 // @Description Retrieve details of a specific transaction including its items
@@ -111,12 +171,14 @@ func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
 
 // VoidTransaction godoc
 // @Summary Void a transaction
-// @Description Void a transaction and restore product stock
+// @Description Void a transaction and restore product stock. Needs a manager approval token unless the caller can grant approvals.
 // @Tags Transactions
 // @Produce json
 // @Param id path int true "Transaction ID"
+// @Param X-Approval-Token header string false "Manager approval token"
 // @Success 200 {object} helpers.Response "Transaction voided successfully"
 // @Failure 400 {object} helpers.ErrorResponse "Invalid transaction ID or already voided"
+// @Failure 403 {object} helpers.ErrorResponse "Manager approval required"
 // @Failure 500 {object} helpers.ErrorResponse "Server error"
 // @Router /api/transactions/{id}/void [patch]
 func (h *TransactionHandler) VoidTransaction(c *gin.Context) {
@@ -126,8 +188,12 @@ func (h *TransactionHandler) VoidTransaction(c *gin.Context) {
 		return
 	}
 
-	err = h.service.VoidTransaction(id)
+	err = h.service.VoidTransaction(actorFromContext(c), id, c.GetHeader(ApprovalTokenHeader))
 	if err != nil {
+		if helpers.IsApprovalRequired(err) {
+			helpers.Forbidden(c, err.Error())
+			return
+		}
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "not found") || strings.Contains(errMsg, "already voided") {
 			helpers.BadRequest(c, errMsg)
@@ -157,13 +223,16 @@ func (h *TransactionHandler) DailyReport(c *gin.Context) {
 
 // ReportByRange godoc
 // @Summary Get sales report by date range
-// @Description Retrieve the sales summary for a specific date range
+// @Description Retrieve the sales summary for a specific date range, as JSON or, with format=csv|xlsx or a matching Accept header, as a one-row spreadsheet
 // @Tags Reports
 // @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Param format query string false "json (default), csv or xlsx"
 // @Success 200 {object} helpers.Response{data=models.SalesReport} "Successfully retrieved report"
-// @Failure 400 {object} helpers.ErrorResponse "Missing start_date or end_date"
+// @Failure 400 {object} helpers.ErrorResponse "Missing start_date or end_date, or unsupported format"
 // @Router /api/report [get]
 func (h *TransactionHandler) ReportByRange(c *gin.Context) {
 	startDate := strings.TrimSpace(c.Query("start_date"))
@@ -174,23 +243,51 @@ func (h *TransactionHandler) ReportByRange(c *gin.Context) {
 		return
 	}
 
+	format, err := helpers.ExportFormat(c)
+	if err != nil {
+		helpers.BadRequest(c, err.Error())
+		return
+	}
+
 	report, err := h.service.GetSalesReportByDateRange(startDate, endDate)
 	if err != nil {
 		helpers.InternalError(c, "Failed to retrieve report", err.Error())
 		return
 	}
+
+	if format != "" {
+		w := helpers.NewExportWriter(c, format, exportFilename("sales-report"),
+			"start_date", "end_date", "total_revenue", "total_transactions", "best_selling_product", "best_selling_qty")
+		var bestName string
+		var bestQty *int
+		if best := report.BestSellingProduct; best != nil {
+			bestName, bestQty = best.Name, &best.QtySold
+		}
+		err := w.Write(startDate, endDate, report.TotalRevenue, report.TotalTransactions, bestName, bestQty)
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			w.Fail(err)
+		}
+		return
+	}
 	helpers.OK(c, "Successfully retrieved report", report)
 }
 
 // ReportSummary godoc
 // @Summary Get aggregated report summary
-// @Description Retrieve aggregated report summary with category breakdown for a date range
+// @Description Retrieve aggregated report summary with category breakdown for a date range. With category_level, sales of subcategories are rolled up to their ancestor at that depth of the category tree. With format=csv|xlsx or a matching Accept header, the category breakdown is downloaded as a spreadsheet ending in a total row.
 // @Tags Reports
 // @Produce json
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Param category_level query int false "Roll the breakdown up to this tree depth (1 = root categories, default 0 = no rollup)"
+// @Param format query string false "json (default), csv or xlsx"
 // @Success 200 {object} helpers.Response{data=models.ReportSummary} "Successfully retrieved report summary"
-// @Failure 400 {object} helpers.ErrorResponse "Missing start_date or end_date"
+// @Failure 400 {object} helpers.ErrorResponse "Missing start_date or end_date, or unsupported format"
 // @Router /api/report/summary [get]
 func (h *TransactionHandler) ReportSummary(c *gin.Context) {
 	startDate := strings.TrimSpace(c.Query("start_date"))
@@ -201,11 +298,47 @@ func (h *TransactionHandler) ReportSummary(c *gin.Context) {
 		return
 	}
 
-	summary, err := h.service.GetReportSummary(startDate, endDate)
+	categoryLevel := 0
+	if v := c.Query("category_level"); v != "" {
+		level, err := strconv.Atoi(v)
+		if err != nil || level < 0 {
+			helpers.BadRequest(c, "Invalid category_level")
+			return
+		}
+		categoryLevel = level
+	}
+
+	format, err := helpers.ExportFormat(c)
+	if err != nil {
+		helpers.BadRequest(c, err.Error())
+		return
+	}
+
+	summary, err := h.service.GetReportSummary(startDate, endDate, categoryLevel)
 	if err != nil {
 		helpers.InternalError(c, "Failed to retrieve report summary", err.Error())
 		return
 	}
+
+	if format != "" {
+		w := helpers.NewExportWriter(c, format, exportFilename("report-summary"),
+			"category_id", "category_name", "revenue", "transactions")
+		for _, cr := range summary.CategoryBreakdown {
+			if err = w.Write(cr.CategoryID, cr.CategoryName, cr.Revenue, cr.Transactions); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = w.Write(nil, "Total", summary.TotalRevenue, summary.TotalTransactions)
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		if err != nil {
+			w.Fail(err)
+		}
+		return
+	}
 	helpers.OK(c, "Successfully retrieved report summary", summary)
 }
 
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler handles two-factor enrollment endpoints
+type TwoFactorHandler struct {
+	service services.TwoFactorService
+}
+
+// NewTwoFactorHandler creates a new two-factor handler instance
+func NewTwoFactorHandler(service services.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{service: service}
+}
+
+// respondTwoFactorError maps two-factor service errors to HTTP responses
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case helpers.IsValidation(err):
+		helpers.BadRequest(c, err.Error())
+	case helpers.IsConflict(err):
+		helpers.Error(c, http.StatusConflict, err.Error())
+	case helpers.IsNotFound(err):
+		helpers.Unauthorized(c, err.Error())
+	default:
+		helpers.InternalError(c, "Failed to process two-factor request", err.Error())
+	}
+}
+
+// Setup godoc
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret and otpauth:// provisioning URI for an authenticator app. Confirm it with /auth/2fa/enable.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.TwoFactorSetupInput true "Password confirmation"
+// @Success 200 {object} helpers.Response{data=models.TwoFactorSetup}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid request body or wrong password"
+// @Failure 409 {object} helpers.ErrorResponse "Two-factor authentication already enabled"
+// @Router /auth/2fa/setup [post]
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	var input models.TwoFactorSetupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	setup, err := h.service.Setup(actorFromContext(c), input.Password)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	helpers.OK(c, "Scan the provisioning URI with your authenticator app", setup)
+}
+
+// Enable godoc
+// @Summary Enable two-factor authentication
+// @Description Confirm enrollment with a code from the authenticator app. Returns single-use recovery codes, shown only once. Log in again afterwards.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.TwoFactorCodeInput true "Current TOTP code"
+// @Success 200 {object} helpers.Response{data=models.RecoveryCodes}
+// @Failure 400 {object} helpers.ErrorResponse "Invalid code or no pending enrollment"
+// @Failure 409 {object} helpers.ErrorResponse "Two-factor authentication already enabled"
+// @Router /auth/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	var input models.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	codes, err := h.service.Enable(actorFromContext(c), input.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	helpers.OK(c, "Two-factor authentication enabled; store the recovery codes safely", codes)
+}
+
+// Disable godoc
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication. Not allowed for roles that require it.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.TwoFactorDisableInput true "Password and current TOTP or recovery code"
+// @Success 200 {object} helpers.Response
+// @Failure 400 {object} helpers.ErrorResponse "Invalid password or code, or two-factor required for role"
+// @Router /auth/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var input models.TwoFactorDisableInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.service.Disable(actorFromContext(c), input.Password, input.Code); err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	helpers.OK(c, "Two-factor authentication disabled", nil)
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"net/http"
 	"retail-core-api/helpers"
+	"retail-core-api/middleware"
 	"retail-core-api/models"
 	"retail-core-api/services"
 	"strconv"
@@ -62,19 +64,37 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 	helpers.OK(c, "User retrieved successfully", user)
 }
 
+// respondUserError maps user service errors to HTTP responses
+func respondUserError(c *gin.Context, err error) {
+	switch {
+	case helpers.IsNotFound(err):
+		helpers.NotFound(c, err.Error())
+	case helpers.IsConflict(err):
+		helpers.Error(c, http.StatusConflict, err.Error())
+	case helpers.IsForbidden(err):
+		helpers.Forbidden(c, err.Error())
+	case helpers.IsValidation(err):
+		helpers.BadRequest(c, err.Error())
+	default:
+		helpers.InternalError(c, "Failed to process user", err.Error())
+	}
+}
+
 // Update godoc
 // @Summary Update a user
-// @Description Update user details (owner only)
+// @Description Partially update a user's name, email or role (owner only). Omitted fields are unchanged. Passwords are changed via reset-password, and the last active owner cannot be demoted.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "User ID"
-// @Param body body models.UserInput true "User data"
-// @Success 200 {object} helpers.Response
+// @Param body body models.UserPatch true "Fields to change"
+// @Success 200 {object} helpers.Response{data=models.User}
 // @Failure 400 {object} helpers.Response
+// @Failure 403 {object} helpers.Response "Owner account or role beyond the caller's permissions"
 // @Failure 404 {object} helpers.Response
-// @Router /api/users/{id} [put]
+// @Failure 409 {object} helpers.Response "Email taken or last active owner"
+// @Router /api/users/{id} [patch]
 func (h *UserHandler) Update(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -82,42 +102,217 @@ func (h *UserHandler) Update(c *gin.Context) {
 		return
 	}
 
-	var input models.UserInput
+	var input models.UserPatch
 	if err := c.ShouldBindJSON(&input); err != nil {
 		helpers.BadRequest(c, "Invalid request body", err.Error())
 		return
 	}
 
-	user, err := h.userService.Update(id, input)
+	user, err := h.userService.Update(actorFromContext(c), id, input)
 	if err != nil {
-		helpers.BadRequest(c, err.Error())
+		respondUserError(c, err)
 		return
 	}
 
 	helpers.OK(c, "User updated successfully", user)
 }
 
-// Delete godoc
-// @Summary Delete a user
-// @Description Soft delete a user (owner only)
+// Activate godoc
+// @Summary Activate a user
+// @Description Reactivate a deactivated user so they can log in again (owner only)
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} helpers.Response{data=models.User}
+// @Failure 403 {object} helpers.Response "Owner account or role beyond the caller's permissions"
+// @Failure 404 {object} helpers.Response
+// @Router /api/users/{id}/activate [post]
+func (h *UserHandler) Activate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		helpers.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	user, err := h.userService.Activate(actorFromContext(c), id)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	helpers.OK(c, "User activated successfully", user)
+}
+
+// Deactivate godoc
+// @Summary Deactivate a user
+// @Description Soft delete a user so they can no longer log in (owner only). The last active owner cannot be deactivated. DELETE /api/users/{id} does the same.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} helpers.Response{data=models.User}
+// @Failure 403 {object} helpers.Response "Owner account or role beyond the caller's permissions"
+// @Failure 404 {object} helpers.Response
+// @Failure 409 {object} helpers.Response "Last active owner"
+// @Router /api/users/{id}/deactivate [post]
+func (h *UserHandler) Deactivate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		helpers.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	user, err := h.userService.Deactivate(actorFromContext(c), id)
+	if err != nil {
+		respondUserError(c, err)
+		return
+	}
+
+	helpers.OK(c, "User deactivated successfully", user)
+}
+
+// Unlock godoc
+// @Summary Unlock a user account
+// @Description Clear the failed login counter and any lockout of a user (owner only)
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} helpers.Response{data=models.User}
+// @Failure 403 {object} helpers.Response "Owner account or role beyond the caller's permissions"
+// @Failure 404 {object} helpers.Response
+// @Router /api/users/{id}/unlock [post]
+func (h *UserHandler) Unlock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		helpers.BadRequest(c, "Invalid user ID")
+		return
+	}
+
+	user, err := h.userService.Unlock(actorFromContext(c), id)
+	if err != nil {
+		helpers.NotFound(c, err.Error())
+		return
+	}
+
+	helpers.OK(c, "User unlocked successfully", user)
+}
+
+// ResetPassword godoc
+// @Summary Reset a user's password
+// @Description Set a temporary password that the user must change at their next login (owner only)
 // @Tags Users
+// @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "User ID"
+// @Param body body models.ResetPasswordInput true "Temporary password"
 // @Success 200 {object} helpers.Response
+// @Failure 400 {object} helpers.Response
+// @Failure 403 {object} helpers.Response "Owner account or role beyond the caller's permissions"
 // @Failure 404 {object} helpers.Response
-// @Router /api/users/{id} [delete]
-func (h *UserHandler) Delete(c *gin.Context) {
+// @Router /api/users/{id}/reset-password [post]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		helpers.BadRequest(c, "Invalid user ID")
 		return
 	}
 
-	if err := h.userService.Delete(id); err != nil {
+	var input models.ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	if err := h.userService.ResetPassword(actorFromContext(c), id, input.NewPassword); err != nil {
+		switch {
+		case helpers.IsNotFound(err):
+			helpers.NotFound(c, err.Error())
+		case helpers.IsValidation(err):
+			helpers.BadRequest(c, err.Error())
+		default:
+			helpers.InternalError(c, "Failed to reset password", err.Error())
+		}
+		return
+	}
+
+	helpers.OK(c, "Password reset successfully; the user must change it at next login", nil)
+}
+
+// respondProfileError maps profile errors to HTTP responses
+func respondProfileError(c *gin.Context, err error) {
+	switch {
+	case helpers.IsNotFound(err):
 		helpers.NotFound(c, err.Error())
+	case helpers.IsValidation(err):
+		helpers.BadRequest(c, err.Error())
+	default:
+		helpers.InternalError(c, "Failed to process profile", err.Error())
+	}
+}
+
+// Me godoc
+// @Summary Get my profile
+// @Description Get the account of the logged-in user
+// @Tags Profile
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} helpers.Response{data=models.User}
+// @Failure 404 {object} helpers.Response
+// @Router /api/me [get]
+func (h *UserHandler) Me(c *gin.Context) {
+	user, err := h.userService.GetProfile(actorFromContext(c))
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	helpers.OK(c, "Profile retrieved successfully", user)
+}
+
+// UpdateMe godoc
+// @Summary Update my profile
+// @Description Change the logged-in user's name and/or password. A new password requires current_password. The role cannot be changed here.
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body models.ProfileInput true "Profile changes"
+// @Success 200 {object} helpers.Response{data=models.User}
+// @Failure 400 {object} helpers.Response
+// @Router /api/me [put]
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	var input models.ProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.BadRequest(c, "Invalid request body", err.Error())
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(actorFromContext(c), input)
+	if err != nil {
+		respondProfileError(c, err)
+		return
+	}
+
+	helpers.OK(c, "Profile updated successfully", user)
+}
+
+// MySessions godoc
+// @Summary List my sessions
+// @Description List the logged-in user's unexpired sessions, one per login, marking the current one
+// @Tags Profile
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} helpers.Response{data=[]models.Session}
+// @Router /api/me/sessions [get]
+func (h *UserHandler) MySessions(c *gin.Context) {
+	sessions, err := h.userService.GetSessions(actorFromContext(c), c.GetInt(middleware.SessionIDKey))
+	if err != nil {
+		respondProfileError(c, err)
 		return
 	}
 
-	helpers.OK(c, "User deleted successfully", nil)
+	helpers.OK(c, "Sessions retrieved successfully", sessions)
 }
@@ -0,0 +1,34 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"retail-core-api/models"
+)
+
+// errInvalidCursor is returned for cursor tokens that cannot be decoded
+var errInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor turns a cursor into the opaque token handed to clients
+func EncodeCursor(cur models.Cursor) string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token made by EncodeCursor. An empty token means no
+// cursor and returns nil.
+func DecodeCursor(token string) (*models.Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cur models.Cursor
+	if err := json.Unmarshal(data, &cur); err != nil || len(cur.Keys) == 0 {
+		return nil, errInvalidCursor
+	}
+	return &cur, nil
+}
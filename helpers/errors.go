@@ -1,6 +1,9 @@
 package helpers
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // Sentinel errors for common application error conditions.
 var (
@@ -9,6 +12,11 @@ var (
 	ErrUnauthorized = errors.New("unauthorized")
 	ErrForbidden    = errors.New("forbidden")
 	ErrConflict     = errors.New("conflict")
+
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrProductInactive   = errors.New("product inactive")
+	ErrApprovalRequired  = errors.New("approval required")
+	ErrTooManyRequests   = errors.New("too many requests")
 )
 
 // AppError wraps an error with an application-specific message so callers can
@@ -27,6 +35,21 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
+// ThrottleError is returned when a caller is throttled. RetryAfter tells the
+// client how long to wait before trying again.
+type ThrottleError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return e.Message
+}
+
+func (e *ThrottleError) Unwrap() error {
+	return ErrTooManyRequests
+}
+
 // NewNotFoundError creates an AppError wrapping ErrNotFound.
 func NewNotFoundError(message string) *AppError {
 	return &AppError{Err: ErrNotFound, Message: message}
@@ -37,6 +60,36 @@ func NewValidationError(message string) *AppError {
 	return &AppError{Err: ErrValidation, Message: message}
 }
 
+// NewConflictError creates an AppError wrapping ErrConflict.
+func NewConflictError(message string) *AppError {
+	return &AppError{Err: ErrConflict, Message: message}
+}
+
+// NewForbiddenError creates an AppError wrapping ErrForbidden.
+func NewForbiddenError(message string) *AppError {
+	return &AppError{Err: ErrForbidden, Message: message}
+}
+
+// NewInsufficientStockError creates an AppError wrapping ErrInsufficientStock.
+func NewInsufficientStockError(message string) *AppError {
+	return &AppError{Err: ErrInsufficientStock, Message: message}
+}
+
+// NewProductInactiveError creates an AppError wrapping ErrProductInactive.
+func NewProductInactiveError(message string) *AppError {
+	return &AppError{Err: ErrProductInactive, Message: message}
+}
+
+// NewApprovalRequiredError creates an AppError wrapping ErrApprovalRequired.
+func NewApprovalRequiredError(message string) *AppError {
+	return &AppError{Err: ErrApprovalRequired, Message: message}
+}
+
+// NewTooManyRequestsError creates a ThrottleError wrapping ErrTooManyRequests.
+func NewTooManyRequestsError(message string, retryAfter time.Duration) *ThrottleError {
+	return &ThrottleError{Message: message, RetryAfter: retryAfter}
+}
+
 // IsNotFound reports whether err (or any error in its chain) is ErrNotFound.
 func IsNotFound(err error) bool {
 	return errors.Is(err, ErrNotFound)
@@ -46,3 +99,42 @@ func IsNotFound(err error) bool {
 func IsValidation(err error) bool {
 	return errors.Is(err, ErrValidation)
 }
+
+// IsConflict reports whether err (or any error in its chain) is ErrConflict.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsForbidden reports whether err (or any error in its chain) is ErrForbidden.
+func IsForbidden(err error) bool {
+	return errors.Is(err, ErrForbidden)
+}
+
+// IsInsufficientStock reports whether err (or any error in its chain) is ErrInsufficientStock.
+func IsInsufficientStock(err error) bool {
+	return errors.Is(err, ErrInsufficientStock)
+}
+
+// IsProductInactive reports whether err (or any error in its chain) is ErrProductInactive.
+func IsProductInactive(err error) bool {
+	return errors.Is(err, ErrProductInactive)
+}
+
+// IsApprovalRequired reports whether err (or any error in its chain) is ErrApprovalRequired.
+func IsApprovalRequired(err error) bool {
+	return errors.Is(err, ErrApprovalRequired)
+}
+
+// IsTooManyRequests reports whether err (or any error in its chain) is ErrTooManyRequests.
+func IsTooManyRequests(err error) bool {
+	return errors.Is(err, ErrTooManyRequests)
+}
+
+// RetryAfter returns the retry delay carried by a ThrottleError in err's chain.
+func RetryAfter(err error) (time.Duration, bool) {
+	var throttle *ThrottleError
+	if errors.As(err, &throttle) {
+		return throttle.RetryAfter, true
+	}
+	return 0, false
+}
@@ -0,0 +1,261 @@
+package helpers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// Export formats offered by list and report endpoints besides JSON
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+
+	MIMECSV  = "text/csv"
+	MIMEXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// exportFlushRows is how many CSV rows are buffered before they are flushed
+// to the client
+const exportFlushRows = 500
+
+// exportWriteTimeout replaces the server's WriteTimeout for exports, which
+// may take longer. The deadline is pushed back each time a chunk is sent, so
+// a large download only fails when the client stops reading.
+const exportWriteTimeout = 2 * time.Minute
+
+// MaxXLSXRows caps XLSX exports. excelize assembles the finished workbook in
+// memory before sending it, so bigger exports must use CSV, which streams.
+const MaxXLSXRows = 100_000
+
+// ExportFormat returns the spreadsheet format requested with ?format=csv|xlsx
+// or the Accept header, or "" when the caller wants the usual JSON.
+func ExportFormat(c *gin.Context) (string, error) {
+	switch format := strings.ToLower(c.Query("format")); format {
+	case "":
+		// Fall back to the Accept header
+	case "json":
+		return "", nil
+	case ExportCSV, ExportXLSX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q; use json, csv or xlsx", format)
+	}
+
+	switch c.NegotiateFormat(gin.MIMEJSON, MIMECSV, MIMEXLSX) {
+	case MIMECSV:
+		return ExportCSV, nil
+	case MIMEXLSX:
+		return ExportXLSX, nil
+	}
+	return "", nil
+}
+
+// ExportWriter writes a table as a CSV or XLSX attachment. CSV rows are
+// streamed to the client as they are written; XLSX rows go through
+// excelize's stream writer, which spills to disk for large sheets, and the
+// workbook is built in memory and sent on Close, so XLSX exports are limited
+// to MaxXLSXRows data rows.
+type ExportWriter struct {
+	c        *gin.Context
+	format   string
+	filename string
+	header   []string
+
+	started bool
+	rows    int
+
+	csv *csv.Writer
+
+	xlsx      *excelize.File
+	stream    *excelize.StreamWriter
+	dateStyle int
+}
+
+// NewExportWriter creates a writer for an attachment named filename (without
+// extension) whose first row is header
+func NewExportWriter(c *gin.Context, format, filename string, header ...string) *ExportWriter {
+	return &ExportWriter{c: c, format: format, filename: filename, header: header}
+}
+
+// start writes the header row. For CSV it also commits the response headers.
+func (w *ExportWriter) start() error {
+	w.started = true
+	w.extendDeadline()
+	header := make([]interface{}, len(w.header))
+	for i, h := range w.header {
+		header[i] = h
+	}
+
+	if w.format == ExportCSV {
+		w.setHeaders(MIMECSV)
+		w.csv = csv.NewWriter(w.c.Writer)
+		return w.Write(header...)
+	}
+
+	w.xlsx = excelize.NewFile()
+	stream, err := w.xlsx.NewStreamWriter("Sheet1")
+	if err != nil {
+		return err
+	}
+	w.stream = stream
+	format := "yyyy-mm-dd hh:mm:ss"
+	if w.dateStyle, err = w.xlsx.NewStyle(&excelize.Style{CustomNumFmt: &format}); err != nil {
+		return err
+	}
+	return w.Write(header...)
+}
+
+// setHeaders marks the response as a file download
+func (w *ExportWriter) setHeaders(contentType string) {
+	w.c.Header("Content-Type", contentType)
+	w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, w.filename, w.format))
+	w.c.Status(http.StatusOK)
+}
+
+// Write appends one row. Values may be strings, numbers, bools, times and
+// pointers to those; nil pointers become empty cells.
+func (w *ExportWriter) Write(values ...interface{}) error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	if w.format == ExportCSV {
+		record := make([]string, len(values))
+		for i, v := range values {
+			record[i] = csvCell(v)
+		}
+		if err := w.csv.Write(record); err != nil {
+			return err
+		}
+		w.rows++
+		if w.rows%exportFlushRows == 0 {
+			w.extendDeadline()
+			w.csv.Flush()
+			w.c.Writer.Flush()
+			return w.csv.Error()
+		}
+		return nil
+	}
+
+	// The first row is the header
+	if w.rows > MaxXLSXRows {
+		return NewValidationError(fmt.Sprintf("XLSX exports are limited to %d rows; narrow the filters or use format=csv", MaxXLSXRows))
+	}
+
+	cells := make([]interface{}, len(values))
+	for i, v := range values {
+		v = derefCell(v)
+		if t, ok := v.(time.Time); ok {
+			cells[i] = excelize.Cell{StyleID: w.dateStyle, Value: t}
+			continue
+		}
+		cells[i] = v
+	}
+	w.rows++
+	cell, err := excelize.CoordinatesToCellName(1, w.rows)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, cells)
+}
+
+// Close finishes the file. An export without data rows still has its header.
+func (w *ExportWriter) Close() error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	if w.format == ExportCSV {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+
+	defer w.xlsx.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	w.extendDeadline()
+	w.setHeaders(MIMEXLSX)
+	return w.xlsx.Write(w.c.Writer)
+}
+
+// extendDeadline moves the write deadline of the response exportWriteTimeout
+// into the future
+func (w *ExportWriter) extendDeadline() {
+	rc := http.NewResponseController(w.c.Writer)
+	if err := rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		Logger(w.c).Warn("Failed to extend export write deadline", "error", err)
+	}
+}
+
+// Fail reports an export error. Before anything reached the client this is
+// a normal JSON error; afterwards the download can only be cut short, so the
+// error is logged and the response aborted.
+func (w *ExportWriter) Fail(err error) {
+	if w.xlsx != nil {
+		w.xlsx.Close()
+	}
+	if !w.c.Writer.Written() {
+		w.c.Writer.Header().Del("Content-Type")
+		w.c.Writer.Header().Del("Content-Disposition")
+		if IsValidation(err) {
+			BadRequest(w.c, err.Error())
+			return
+		}
+		InternalError(w.c, "Failed to export", err.Error())
+		return
+	}
+	Logger(w.c).Error("Export failed after the response started", "error", err, "rows", w.rows)
+	w.c.Abort()
+}
+
+// derefCell unwraps pointer values so nil pointers become empty cells
+func derefCell(v interface{}) interface{} {
+	switch p := v.(type) {
+	case *int:
+		if p == nil {
+			return nil
+		}
+		return *p
+	case *string:
+		if p == nil {
+			return nil
+		}
+		return *p
+	case *time.Time:
+		if p == nil {
+			return nil
+		}
+		return *p
+	}
+	return v
+}
+
+// csvCell formats a value for CSV. Text starting with a formula character is
+// prefixed with a quote so spreadsheet apps do not evaluate it.
+func csvCell(v interface{}) string {
+	switch v := derefCell(v).(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,247 @@
+package helpers
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwtKey is a verification key, with its private half when it signs
+type jwtKey struct {
+	id      string
+	method  jwt.SigningMethod
+	public  crypto.PublicKey
+	private crypto.PrivateKey
+	jwk     JWK
+}
+
+// JWTKeys signs and verifies JWTs. With asymmetric keys, tokens are signed
+// with the current key (RS256 or EdDSA, chosen from the key type) and carry
+// its kid; older keys stay valid for verification so keys can be rotated
+// without logging everyone out. Without asymmetric keys it falls back to
+// HS256 with a shared secret.
+type JWTKeys struct {
+	signing *jwtKey
+	keys    map[string]*jwtKey
+	secret  []byte
+}
+
+// NewHMACKeys returns HS256 keys using a shared secret
+func NewHMACKeys(secret string) *JWTKeys {
+	return &JWTKeys{secret: []byte(secret)}
+}
+
+// LoadJWTKeys loads the PEM private key used for signing and any extra PEM
+// keys (public or private) accepted for verification during rotation
+func LoadJWTKeys(signingKeyFile string, verifyKeyFiles []string) (*JWTKeys, error) {
+	signing, err := loadJWTKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("%s: signing key must be a private key", signingKeyFile)
+	}
+
+	k := &JWTKeys{signing: signing, keys: map[string]*jwtKey{signing.id: signing}}
+	for _, file := range verifyKeyFiles {
+		key, err := loadJWTKey(file)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[key.id] = key
+	}
+	return k, nil
+}
+
+// Algorithm returns the signing algorithm
+func (k *JWTKeys) Algorithm() string {
+	if k.signing == nil {
+		return jwt.SigningMethodHS256.Alg()
+	}
+	return k.signing.method.Alg()
+}
+
+// Sign returns a signed token for the claims
+func (k *JWTKeys) Sign(claims jwt.Claims) (string, error) {
+	if k.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.id
+	return token.SignedString(k.signing.private)
+}
+
+// Parse verifies a token's signature and expiry and returns its claims.
+// Asymmetric tokens must name a known kid and match that key's algorithm.
+func (k *JWTKeys) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if k.signing == nil {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return k.secret, nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.public, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
+}
+
+// JWKS returns the public verification keys. It is empty for HS256, whose
+// secret must never be published.
+func (k *JWTKeys) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	if k.signing == nil {
+		return set
+	}
+
+	// Current signing key first, then the rest in a stable order
+	set.Keys = append(set.Keys, k.signing.jwk)
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		if id != k.signing.id {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		set.Keys = append(set.Keys, k.keys[id].jwk)
+	}
+	return set
+}
+
+// loadJWTKey reads an RSA or Ed25519 key from a PEM file. The kid is the
+// RFC 7638 thumbprint of the public key, so it is stable across restarts.
+func loadJWTKey(file string) (*jwtKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+
+	var private crypto.PrivateKey
+	var public crypto.PublicKey
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		private = key
+	} else if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		private = key
+	} else if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		public = key
+	} else if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		public = key
+	} else {
+		return nil, fmt.Errorf("%s: unsupported key format", file)
+	}
+
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		public = &key.PublicKey
+	case ed25519.PrivateKey:
+		public = key.Public()
+	case nil:
+	default:
+		return nil, fmt.Errorf("%s: unsupported private key type %T", file, private)
+	}
+
+	var jwk JWK
+	var method jwt.SigningMethod
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA keys must be at least 2048 bits", file)
+		}
+		method = jwt.SigningMethodRS256
+		jwk = JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+		jwk = JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(key)}
+	default:
+		return nil, fmt.Errorf("%s: unsupported public key type %T", file, public)
+	}
+
+	jwk.Kid = jwkThumbprint(jwk)
+	jwk.Use = "sig"
+	jwk.Alg = method.Alg()
+
+	return &jwtKey{id: jwk.Kid, method: method, public: public, private: private, jwk: jwk}, nil
+}
+
+// jwkThumbprint computes the RFC 7638 SHA-256 thumbprint of a JWK
+func jwkThumbprint(jwk JWK) string {
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	canonical, _ := json.Marshal(members)
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
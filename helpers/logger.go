@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys for request-scoped values shared between middleware and handlers
+const (
+	RequestIDKey = "request_id"
+	LoggerKey    = "logger"
+)
+
+// NewLogger builds a structured logger writing to w. format is "json" or
+// "text"; level is one of debug, info, warn or error (defaults to info).
+func NewLogger(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLogLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(handler)
+}
+
+// ParseLogLevel converts a level name into a slog.Level, defaulting to info
+func ParseLogLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Logger returns the request-scoped logger stored by middleware.Logger,
+// falling back to the default logger outside of a request.
+func Logger(c *gin.Context) *slog.Logger {
+	if c != nil {
+		if v, ok := c.Get(LoggerKey); ok {
+			if l, ok := v.(*slog.Logger); ok {
+				return l
+			}
+		}
+	}
+	return slog.Default()
+}
+
+// WithLogFields enriches the request-scoped logger with additional fields
+// so that every later log line of the request carries them.
+func WithLogFields(c *gin.Context, args ...any) {
+	c.Set(LoggerKey, Logger(c).With(args...))
+}
@@ -1,7 +1,10 @@
 package helpers
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,17 +26,22 @@ type PaginatedResponse struct {
 
 // ErrorResponse is the standard error response envelope
 type ErrorResponse struct {
-	Status  bool   `json:"status" example:"false"`
-	Message string `json:"message" example:"Error occurred"`
-	Error   string `json:"error,omitempty" example:"validation detail"`
+	Status    bool   `json:"status" example:"false"`
+	Message   string `json:"message" example:"Error occurred"`
+	Error     string `json:"error,omitempty" example:"validation detail"`
+	RequestID string `json:"request_id,omitempty" example:"3f9c2a7e1b5d4c8e9a0b1c2d3e4f5a6b"`
 }
 
-// PaginationMeta holds pagination metadata
+// PaginationMeta holds pagination metadata. Page is left out for cursor
+// pages, Total and TotalPages when the count was skipped, and the cursors
+// when there is no page in that direction.
 type PaginationMeta struct {
-	Page       int `json:"page" example:"1"`
-	Limit      int `json:"limit" example:"20"`
-	Total      int `json:"total" example:"150"`
-	TotalPages int `json:"total_pages" example:"8"`
+	Page       int    `json:"page,omitempty" example:"1"`
+	Limit      int    `json:"limit" example:"20"`
+	Total      *int   `json:"total,omitempty" example:"150"`
+	TotalPages *int   `json:"total_pages,omitempty" example:"8"`
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJrIjpbIjQyIl0sInMiOiI5ZjJjIn0"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 // Success sends a standard success response
@@ -48,8 +56,9 @@ func Success(c *gin.Context, statusCode int, message string, data interface{}) {
 // Error sends a standard error response
 func Error(c *gin.Context, statusCode int, message string, err ...string) {
 	resp := ErrorResponse{
-		Status:  false,
-		Message: message,
+		Status:    false,
+		Message:   message,
+		RequestID: c.GetString(RequestIDKey),
 	}
 	if len(err) > 0 && err[0] != "" {
 		resp.Error = err[0]
@@ -57,6 +66,22 @@ func Error(c *gin.Context, statusCode int, message string, err ...string) {
 	c.JSON(statusCode, resp)
 }
 
+// Failure sends an unsuccessful response that still carries data, such as a
+// report of what went wrong
+func Failure(c *gin.Context, statusCode int, message string, data interface{}) {
+	c.JSON(statusCode, Response{
+		Status:  false,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// AbortWithError sends a standard error response and stops the handler chain
+func AbortWithError(c *gin.Context, statusCode int, message string) {
+	Error(c, statusCode, message)
+	c.Abort()
+}
+
 // Created sends a 201 success response
 func Created(c *gin.Context, message string, data interface{}) {
 	Success(c, http.StatusCreated, message, data)
@@ -92,6 +117,17 @@ func Forbidden(c *gin.Context, message string) {
 	Error(c, http.StatusForbidden, message)
 }
 
+// TooManyRequests sends a 429 error response with a Retry-After header in
+// whole seconds, rounded up
+func TooManyRequests(c *gin.Context, message string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	Error(c, http.StatusTooManyRequests, message)
+}
+
 // Paginated sends a standard paginated response
 func Paginated(c *gin.Context, message string, data interface{}, meta PaginationMeta) {
 	c.JSON(http.StatusOK, PaginatedResponse{
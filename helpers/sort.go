@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"fmt"
+	"retail-core-api/models"
+	"strings"
+)
+
+// ParseSort parses a sort query value: comma-separated field names, each
+// descending when prefixed with "-" (e.g. "-price,name"). Only fields in
+// allowed are accepted, so the result is safe to map onto SQL columns.
+func ParseSort(raw string, allowed []string) ([]models.SortTerm, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var terms []models.SortTerm
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		term := models.SortTerm{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !contains(allowed, term.Field) {
+			return nil, fmt.Errorf("cannot sort by %q; allowed: %s", term.Field, strings.Join(allowed, ", "))
+		}
+		if seen[term.Field] {
+			continue
+		}
+		seen[term.Field] = true
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// contains reports whether list holds s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
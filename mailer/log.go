@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer is meant for local development: instead of sending messages it
+// appends them to a file. Without a file only the recipient and subject are
+// logged; bodies carry reset tokens and never go to the application log.
+type LogMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogMailer creates a mailer that writes messages to path, or logs their
+// recipient and subject when path is empty
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{path: path}
+}
+
+// Send records the message
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if m.path == "" {
+		slog.InfoContext(ctx, "Email (not sent, set MAIL_LOG_FILE to keep the body)", "to", msg.To, "subject", msg.Subject)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n----\n\n",
+		time.Now().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	return err
+}
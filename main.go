@@ -1,17 +1,27 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
 	"retail-core-api/config"
 	"retail-core-api/database"
 	"retail-core-api/docs"
 	"retail-core-api/handlers"
 	"retail-core-api/helpers"
+	"retail-core-api/mailer"
+	"retail-core-api/metrics"
 	"retail-core-api/middleware"
+	"retail-core-api/models"
 	"retail-core-api/repositories"
 	"retail-core-api/services"
+	"retail-core-api/storage"
+	"runtime"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -29,6 +39,7 @@ import (
 // @description - Product Management (CRUD with category, search, pagination)
 // @description - Transaction / Checkout (multi-item with payment method, discount, notes)
 // @description - Void Transactions
+// @description - Manager PIN approval for voids and large discounts
 // @description - Sales Reports (daily, date range, summary with category breakdown)
 // @description - Dashboard Statistics
 
@@ -45,6 +56,14 @@ import (
 // @in header
 // @name Authorization
 
+// Build information, overridden at build time via
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildTime=..."
+var (
+	version   = "1.0"
+	commit    = "unknown"
+	buildTime = "unknown"
+)
+
 func main() {
 	// Load configuration
 	cfg, err := config.LoadConfig()
@@ -52,6 +71,10 @@ func main() {
 		log.Fatal("Failed to load config:", err)
 	}
 
+	// Structured logger shared by the whole app
+	logger := helpers.NewLogger(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+
 	// Configure Swagger
 	docs.SwaggerInfo.Host = cfg.SwaggerHost()
 	docs.SwaggerInfo.Schemes = cfg.SwaggerSchemes()
@@ -61,21 +84,40 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// JWT signing keys
+	jwtKeys := helpers.NewHMACKeys(cfg.JWTSecret)
+	if cfg.JWTPrivateKeyFile != "" {
+		jwtKeys, err = helpers.LoadJWTKeys(cfg.JWTPrivateKeyFile, cfg.JWTVerifyKeyFiles)
+		if err != nil {
+			logger.Error("Failed to load JWT keys", "error", err)
+			os.Exit(1)
+		}
+	}
+	logger.Info("JWT signing configured", "algorithm", jwtKeys.Algorithm())
+
 	// ============================================
 	// DATABASE CONNECTION
 	// ============================================
 	db, err := database.InitDB(cfg.DBConn)
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		logger.Error("Failed to initialize database", "error", err)
+		os.Exit(1)
 	}
-	defer database.CloseDB()
 
 	// Run database migrations
-	err = database.RunMigrations(db)
+	err = database.RunMigrations(db, database.OwnerSeed{
+		Name:     cfg.InitialOwnerName,
+		Email:    cfg.InitialOwnerEmail,
+		Password: cfg.InitialOwnerPassword,
+	})
 	if err != nil {
-		log.Fatal("Failed to run migrations:", err)
+		logger.Error("Failed to run migrations", "error", err)
+		os.Exit(1)
 	}
 
+	// Expose connection pool statistics as Prometheus gauges
+	metrics.RegisterDBStats(db)
+
 	// ============================================
 	// DEPENDENCY INJECTION
 	// ============================================
@@ -85,102 +127,313 @@ func main() {
 	productRepo := repositories.NewProductRepository(db)
 	transactionRepo := repositories.NewTransactionRepository(db)
 	userRepo := repositories.NewUserRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	roleRepo := repositories.NewRoleRepository(db)
+	approvalRepo := repositories.NewApprovalRepository(db)
+	loginAttemptRepo := repositories.NewLoginAttemptRepository(db)
+	passwordResetRepo := repositories.NewPasswordResetRepository(db)
+	twoFactorRepo := repositories.NewTwoFactorRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	productImportRepo := repositories.NewProductImportRepository(db)
+	productImageRepo := repositories.NewProductImageRepository(db)
+
+	// Outgoing mail
+	var mail mailer.Mailer = mailer.NewLogMailer(cfg.MailLogFile)
+	if cfg.MailDriver == "smtp" {
+		mail = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	}
+
+	// Uploaded files
+	var store storage.Storage = storage.NewLocalStorage(cfg.StorageLocalDir, cfg.StoragePublicURL)
+	if cfg.StorageDriver == "s3" {
+		s3Store, err := storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			UseSSL:    cfg.S3UseSSL,
+			PublicURL: cfg.StoragePublicURL,
+		})
+		if err != nil {
+			logger.Error("Failed to configure file storage", "error", err)
+			os.Exit(1)
+		}
+		store = s3Store
+	}
+
+	// Password rules for registration, resets and self-service changes
+	passwordPolicy := helpers.PasswordPolicy{
+		MinLength:        cfg.PasswordMinLength,
+		RequireMixedCase: cfg.PasswordRequireMixedCase,
+		RequireDigit:     cfg.PasswordRequireDigit,
+		RequireSymbol:    cfg.PasswordRequireSymbol,
+	}
+	loginPolicy := services.LoginPolicy{
+		MaxFailedAttempts:  cfg.LoginMaxFailedAttempts,
+		LockoutDuration:    cfg.LoginLockoutDuration,
+		MaxLockoutDuration: cfg.LoginMaxLockoutDuration,
+		IPMaxAttempts:      cfg.LoginIPMaxAttempts,
+		IPWindow:           cfg.LoginIPWindow,
+	}
 
 	// Services
-	categoryService := services.NewCategoryService(categoryRepo)
-	productService := services.NewProductService(productRepo, categoryRepo)
-	transactionService := services.NewTransactionService(transactionRepo)
-	authService := services.NewAuthService(userRepo, cfg.JWTSecret)
-	userService := services.NewUserService(userRepo)
+	auditService := services.NewAuditService(auditRepo)
+	roleService := services.NewRoleService(roleRepo, auditService)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, auditService)
+	categoryService := services.NewCategoryService(categoryRepo, auditService)
+	productService := services.NewProductService(productRepo, categoryRepo, auditService)
+	productImportService := services.NewProductImportService(productImportRepo, categoryRepo, auditService)
+	productImageService := services.NewProductImageService(productImageRepo, productRepo, store, auditService, services.ImagePolicy{
+		MaxFileSize:   cfg.ImageMaxFileSize,
+		ThumbnailSize: cfg.ImageThumbnailSize,
+	})
+	approvalService := services.NewApprovalService(approvalRepo, userRepo, roleService, auditService, cfg.ApprovalTokenTTL, loginPolicy)
+	transactionService := services.NewTransactionService(transactionRepo, auditService, approvalService, services.ApprovalPolicy{
+		DiscountThresholdPercent: cfg.ApprovalDiscountThresholdPercent,
+		RequireVoidApproval:      cfg.ApprovalRequiredForVoid,
+	})
+	twoFactorService := services.NewTwoFactorService(userRepo, twoFactorRepo, auditService, cfg.TwoFactorIssuer, cfg.TwoFactorRequireOwner)
+	authService := services.NewAuthService(userRepo, roleRepo, loginAttemptRepo, sessionRepo, twoFactorService, auditService, loginPolicy, passwordPolicy, jwtKeys)
+	userService := services.NewUserService(userRepo, roleRepo, sessionRepo, auditService, passwordPolicy)
+	passwordResetService := services.NewPasswordResetService(userRepo, passwordResetRepo, sessionRepo, mail, auditService, passwordPolicy, services.PasswordResetPolicy{
+		TokenTTL:         cfg.PasswordResetTokenTTL,
+		ResetURL:         cfg.PasswordResetURL,
+		EmailMaxRequests: cfg.PasswordResetEmailMaxRequests,
+		IPMaxRequests:    cfg.PasswordResetIPMaxRequests,
+		RateWindow:       cfg.PasswordResetRateWindow,
+	})
 
 	// Handlers
 	categoryHandler := handlers.NewCategoryHandler(categoryService, productService)
 	productHandler := handlers.NewProductHandler(productService)
+	productImportHandler := handlers.NewProductImportHandler(productImportService, cfg.ImportMaxFileSize)
+	productImageHandler := handlers.NewProductImageHandler(productImageService, cfg.ImageMaxFileSize)
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
 	authHandler := handlers.NewAuthHandler(authService)
+	passwordResetHandler := handlers.NewPasswordResetHandler(passwordResetService)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService)
 	userHandler := handlers.NewUserHandler(userService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	roleHandler := handlers.NewRoleHandler(roleService)
+	approvalHandler := handlers.NewApprovalHandler(approvalService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	jwksHandler := handlers.NewJWKSHandler(jwtKeys)
+	healthHandler := handlers.NewHealthHandler(db, models.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	}, cfg.HealthCheckTimeout)
 
 	// ============================================
 	// ROUTER SETUP
 	// ============================================
 	r := gin.New()
-	r.Use(middleware.Logger())
-	r.Use(gin.Recovery())
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Logger(logger))
+	r.Use(middleware.Metrics())
+	r.Use(middleware.Recovery())
 	r.Use(middleware.CORS())
 
 	// ── Health & Info ──────────────────────────
-	r.GET("/health", func(c *gin.Context) {
-		helpers.OK(c, "Server is running successfully", gin.H{"status": "OK"})
-	})
+	r.GET("/health", healthHandler.Live)
+	r.GET("/health/live", healthHandler.Live)
+	r.GET("/health/ready", healthHandler.Ready)
+
+	// ── Prometheus Metrics ────────────────────
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	r.GET("/", func(c *gin.Context) {
 		helpers.OK(c, "Retail Core API", gin.H{
 			"name":    "Retail Core API",
-			"version": "1.0",
+			"version": version,
 			"status":  "running",
 		})
 	})
 
+	// ── JWT verification keys for other services ──
+	r.GET("/.well-known/jwks.json", jwksHandler.JWKS)
+
+	// ── Uploaded files (local storage driver) ──
+	if cfg.StorageDriver == "local" {
+		r.Static(config.LocalUploadsPath, cfg.StorageLocalDir)
+	}
+
 	// ── Swagger Documentation ─────────────────
 	r.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// ── Auth (public) ─────────────────────────
+	// Authentication (JWT or API key) followed by permission resolution for
+	// the caller's role
+	authenticated := []gin.HandlerFunc{
+		middleware.Auth(jwtKeys, apiKeyService, authService),
+		middleware.LoadPermissions(roleService),
+	}
+	requirePermission := middleware.RequirePermission
+
+	// ── Auth ──────────────────────────────────
 	auth := r.Group("/auth")
 	{
 		auth.POST("/login", authHandler.Login)
-		auth.POST("/register", authHandler.Register)
+		auth.POST("/login/2fa", authHandler.LoginTwoFactor)
+		auth.POST("/forgot-password", passwordResetHandler.ForgotPassword)
+		auth.POST("/reset-password", passwordResetHandler.ResetPassword)
+
+		// Allowed even while a password change is pending
+		auth.POST("/change-password", middleware.Auth(jwtKeys, apiKeyService, authService), authHandler.ChangePassword)
+
+		// Two-factor enrollment; setup and enable stay reachable while
+		// enrollment is required
+		auth.POST("/2fa/setup", middleware.Auth(jwtKeys, apiKeyService, authService), twoFactorHandler.Setup)
+		auth.POST("/2fa/enable", middleware.Auth(jwtKeys, apiKeyService, authService), twoFactorHandler.Enable)
+		auth.POST("/2fa/disable", middleware.Auth(jwtKeys, apiKeyService, authService), twoFactorHandler.Disable)
+
+		// Creating accounts is restricted to user managers
+		auth.POST("/register",
+			middleware.Auth(jwtKeys, apiKeyService, authService),
+			middleware.LoadPermissions(roleService),
+			requirePermission(models.PermUserManage),
+			authHandler.Register,
+		)
 	}
 
 	// ── Protected API routes ──────────────────
+	// Every route declares the permission it requires; see models.DefaultRoles
+	// for what each built-in role is granted.
 	api := r.Group("/api")
-	api.Use(middleware.Auth(cfg.JWTSecret))
+	api.Use(authenticated...)
 	{
 		// Categories
-		api.GET("/categories", categoryHandler.List)
-		api.GET("/categories/:id", categoryHandler.GetByID)
-		api.GET("/categories/:id/products", categoryHandler.GetProducts)
-		api.POST("/categories", categoryHandler.Create)
-		api.PUT("/categories/:id", categoryHandler.Update)
-		api.DELETE("/categories/:id", categoryHandler.Delete)
+		api.GET("/categories", requirePermission(models.PermCategoryRead), categoryHandler.List)
+		api.GET("/categories/:id", requirePermission(models.PermCategoryRead), categoryHandler.GetByID)
+		api.GET("/categories/:id/products", requirePermission(models.PermProductRead), categoryHandler.GetProducts)
+		api.POST("/categories", requirePermission(models.PermCategoryWrite), categoryHandler.Create)
+		api.PUT("/categories/:id", requirePermission(models.PermCategoryWrite), categoryHandler.Update)
+		api.DELETE("/categories/:id", requirePermission(models.PermCategoryWrite), categoryHandler.Delete)
 
 		// Products
-		api.GET("/products", productHandler.List)
-		api.GET("/products/:id", productHandler.GetByID)
-		api.POST("/products", productHandler.Create)
-		api.PUT("/products/:id", productHandler.Update)
-		api.DELETE("/products/:id", productHandler.Delete)
+		api.GET("/products", requirePermission(models.PermProductRead), productHandler.List)
+		api.GET("/products/autocomplete", requirePermission(models.PermProductRead), productHandler.Autocomplete)
+		api.GET("/products/:id", requirePermission(models.PermProductRead), productHandler.GetByID)
+		api.POST("/products", requirePermission(models.PermProductWrite), productHandler.Create)
+		api.POST("/products/import", requirePermission(models.PermProductWrite), productImportHandler.Import)
+		api.PUT("/products/:id", requirePermission(models.PermProductWrite), productHandler.Update)
+		api.DELETE("/products/:id", requirePermission(models.PermProductWrite), productHandler.Delete)
+		api.POST("/products/:id/restore", requirePermission(models.PermProductWrite), productHandler.Restore)
+		api.GET("/products/:id/images", requirePermission(models.PermProductRead), productImageHandler.List)
+		api.POST("/products/:id/images", requirePermission(models.PermProductWrite), productImageHandler.Upload)
+		api.PUT("/products/:id/images/:imageId/primary", requirePermission(models.PermProductWrite), productImageHandler.SetPrimary)
+		api.DELETE("/products/:id/images/:imageId", requirePermission(models.PermProductWrite), productImageHandler.Delete)
 
 		// Transactions / Checkout
-		api.POST("/checkout", transactionHandler.Checkout)
-		api.GET("/transactions", transactionHandler.ListTransactions)
-		api.GET("/transactions/:id", transactionHandler.GetTransactionByID)
-		api.PATCH("/transactions/:id/void", transactionHandler.VoidTransaction)
+		api.POST("/checkout", requirePermission(models.PermTransactionCreate), transactionHandler.Checkout)
+		api.GET("/transactions", requirePermission(models.PermTransactionRead), transactionHandler.ListTransactions)
+		api.GET("/transactions/:id", requirePermission(models.PermTransactionRead), transactionHandler.GetTransactionByID)
+		api.PATCH("/transactions/:id/void", requirePermission(models.PermTransactionVoid), transactionHandler.VoidTransaction)
+
+		// Manager approvals (PIN entry on the cashier's terminal)
+		api.POST("/approvals", requirePermission(models.PermTransactionCreate), approvalHandler.Grant)
+		api.PUT("/approvals/pin", requirePermission(models.PermApprovalGrant), approvalHandler.SetPIN)
 
 		// Dashboard
-		api.GET("/dashboard", transactionHandler.Dashboard)
+		api.GET("/dashboard", requirePermission(models.PermDashboardRead), transactionHandler.Dashboard)
 
 		// Reports
-		api.GET("/report/today", transactionHandler.DailyReport)
-		api.GET("/report", transactionHandler.ReportByRange)
-		api.GET("/report/summary", transactionHandler.ReportSummary)
+		api.GET("/report/today", requirePermission(models.PermReportRead), transactionHandler.DailyReport)
+		api.GET("/report", requirePermission(models.PermReportRead), transactionHandler.ReportByRange)
+		api.GET("/report/summary", requirePermission(models.PermReportRead), transactionHandler.ReportSummary)
+
+		// Own profile, available to every logged-in user
+		api.GET("/me", userHandler.Me)
+		api.PUT("/me", userHandler.UpdateMe)
+		api.GET("/me/sessions", userHandler.MySessions)
 
-		// Users (owner only)
+		// Users
 		users := api.Group("/users")
-		users.Use(middleware.RequireRole("owner"))
+		users.Use(requirePermission(models.PermUserManage))
 		{
 			users.GET("", userHandler.GetAll)
 			users.GET("/:id", userHandler.GetByID)
-			users.PUT("/:id", userHandler.Update)
-			users.DELETE("/:id", userHandler.Delete)
+			users.PATCH("/:id", userHandler.Update)
+			users.PUT("/:id", userHandler.Update) // same partial semantics, kept for existing clients
+			users.DELETE("/:id", userHandler.Deactivate)
+			users.POST("/:id/activate", userHandler.Activate)
+			users.POST("/:id/deactivate", userHandler.Deactivate)
+			users.POST("/:id/unlock", userHandler.Unlock)
+			users.POST("/:id/reset-password", userHandler.ResetPassword)
+		}
+
+		// Roles & permissions
+		api.GET("/permissions", requirePermission(models.PermRoleManage), roleHandler.ListPermissions)
+		roles := api.Group("/roles")
+		roles.Use(requirePermission(models.PermRoleManage))
+		{
+			roles.GET("", roleHandler.List)
+			roles.GET("/:id", roleHandler.GetByID)
+			roles.POST("", roleHandler.Create)
+			roles.PUT("/:id", roleHandler.Update)
+			roles.DELETE("/:id", roleHandler.Delete)
+		}
+
+		// Audit log
+		api.GET("/audit-log", requirePermission(models.PermAuditRead), auditHandler.List)
+		api.GET("/login-attempts", requirePermission(models.PermAuditRead), authHandler.ListLoginAttempts)
+
+		// API keys for machine clients
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(requirePermission(models.PermAPIKeyManage))
+		{
+			apiKeys.GET("", apiKeyHandler.List)
+			apiKeys.POST("", apiKeyHandler.Create)
+			apiKeys.DELETE("/:id", apiKeyHandler.Revoke)
 		}
 	}
 
 	// ── Start Server ──────────────────────────
 	addr := "0.0.0.0:" + cfg.Port
-	fmt.Printf("Server running on %s\n", addr)
-	fmt.Printf("API Documentation: http://localhost:%s/docs/index.html\n", cfg.Port)
+	logger.Info("Server running", "addr", addr)
+	logger.Info("API Documentation", "url", "http://localhost:"+cfg.Port+"/docs/index.html")
 
-	if err := http.ListenAndServe(addr, r); err != nil {
-		log.Fatal("Failed to start server:", err)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           r,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Failed to start server", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	// ── Graceful Shutdown ─────────────────────
+	// Stop accepting new connections on SIGINT/SIGTERM, let in-flight
+	// requests (e.g. a checkout mid-transaction) finish within the
+	// shutdown deadline, then release the database pool.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+
+	logger.Info("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server forced to shutdown", "error", err)
+	}
+
+	database.CloseDB()
+	logger.Info("Server exited")
 }
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric exposed by the API
+const namespace = "retail"
+
+// HTTP metrics, recorded by middleware.Metrics
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Business metrics, recorded by TransactionService
+var (
+	CheckoutsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checkouts_total",
+		Help:      "Total number of successful checkouts.",
+	})
+
+	CheckoutFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checkout_failures_total",
+		Help:      "Total number of failed checkouts by reason.",
+	}, []string{"reason"})
+
+	InsufficientStockTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "insufficient_stock_rejections_total",
+		Help:      "Total number of checkouts rejected because of insufficient stock.",
+	})
+
+	RevenueTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "revenue_total",
+		Help:      "Total revenue of successful checkouts after discount, in the smallest currency unit.",
+	})
+
+	VoidsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "voids_total",
+		Help:      "Total number of voided transactions.",
+	})
+
+	LoginAttemptsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Total number of login attempts, by result.",
+	}, []string{"result"})
+)
+
+// Checkout failure reasons used as the "reason" label
+const (
+	ReasonValidation        = "validation"
+	ReasonNotFound          = "not_found"
+	ReasonInsufficientStock = "insufficient_stock"
+	ReasonProductInactive   = "product_inactive"
+	ReasonApprovalRequired  = "approval_required"
+	ReasonInternal          = "internal"
+)
+
+// RegisterDBStats exposes the connection pool statistics of db as gauges
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// Handler returns the HTTP handler serving the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -1,18 +1,62 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
+// Routes reachable with a restricted token: a user with a pending forced
+// password change may only call ChangePasswordPath, and a user who must
+// enroll in two-factor authentication only the enrollment routes.
+const (
+	ChangePasswordPath  = "/auth/change-password"
+	TwoFactorSetupPath  = "/auth/2fa/setup"
+	TwoFactorEnablePath = "/auth/2fa/enable"
+)
+
+// APIKeyHeader carries an API key for machine clients
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyIDKey is the Gin context key holding the ID of the API key that
+// authenticated the request
+const APIKeyIDKey = "api_key_id"
+
+// SessionIDKey is the Gin context key holding the session ID of the access
+// token, for tokens issued since sessions were recorded
+const SessionIDKey = "session_id"
+
+// APIKeyAuthenticator resolves a presented API key, returning nil for keys
+// that are unknown, revoked or expired
+type APIKeyAuthenticator interface {
+	Authenticate(rawKey, ip string) (*models.APIKey, error)
+}
+
+// SessionAuthenticator resolves the account behind an access token,
+// returning nil when the user is gone or deactivated or the token's session
+// was revoked. sessionID is 0 for tokens without a recorded session.
+type SessionAuthenticator interface {
+	AuthenticateSession(userID, sessionID int) (*models.User, error)
+}
+
 // Auth validates the JWT token from the Authorization header or cookie
 // and sets user_id, user_email, user_role, user_name in the Gin context.
-func Auth(jwtSecret string) gin.HandlerFunc {
+// The account is looked up on every request, so the role and a pending
+// password change are the current ones, and deactivated users and revoked
+// sessions are turned away at once instead of when the token expires.
+// Two-factor challenge tokens are rejected, and restricted tokens only reach
+// the routes listed above. Requests with an X-API-Key header are
+// authenticated by apiKeys instead and carry the key's permissions.
+func Auth(keys *helpers.JWTKeys, apiKeys APIKeyAuthenticator, sessions SessionAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if rawKey := c.GetHeader(APIKeyHeader); rawKey != "" {
+			authenticateAPIKey(c, apiKeys, rawKey)
+			return
+		}
+
 		var tokenString string
 
 		// Try Authorization header first
@@ -20,10 +64,7 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 		if authHeader != "" {
 			parts := strings.SplitN(authHeader, " ", 2)
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-					"status":  false,
-					"message": "Invalid authorization format, expected: Bearer <token>",
-				})
+				helpers.AbortWithError(c, http.StatusUnauthorized, "Invalid authorization format, expected: Bearer <token>")
 				return
 			}
 			tokenString = parts[1]
@@ -37,75 +78,103 @@ func Auth(jwtSecret string) gin.HandlerFunc {
 		}
 
 		if tokenString == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"status":  false,
-				"message": "Authorization required",
-			})
+			helpers.AbortWithError(c, http.StatusUnauthorized, "Authorization required")
 			return
 		}
 
 		// Parse and validate JWT
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"status":  false,
-				"message": "Invalid or expired token",
-			})
+		claims, err := keys.Parse(tokenString)
+		if err != nil {
+			helpers.AbortWithError(c, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"status":  false,
-				"message": "Invalid token claims",
-			})
+		// Only access tokens authenticate requests; tokens issued before the
+		// typ claim existed are access tokens
+		if typ, ok := claims["typ"]; ok && typ != models.TokenTypeAccess {
+			helpers.AbortWithError(c, http.StatusUnauthorized, "Invalid token type")
 			return
 		}
 
-		// Extract claims and set in context
-		if userID, ok := claims["user_id"].(float64); ok {
-			c.Set("user_id", int(userID))
+		userID, _ := claims["user_id"].(float64)
+		sessionID, _ := claims["sid"].(float64)
+		user, err := sessions.AuthenticateSession(int(userID), int(sessionID))
+		if err != nil {
+			helpers.Logger(c).Error("Failed to authenticate session", "error", err)
+			helpers.AbortWithError(c, http.StatusInternalServerError, "Failed to authenticate session")
+			return
 		}
-		if email, ok := claims["email"].(string); ok {
-			c.Set("user_email", email)
+		if user == nil {
+			helpers.AbortWithError(c, http.StatusUnauthorized, "Session has ended, please log in again")
+			return
 		}
-		if role, ok := claims["role"].(string); ok {
-			c.Set("user_role", role)
+
+		c.Set("user_id", user.ID)
+		c.Set("user_email", user.Email)
+		c.Set("user_role", user.Role)
+		c.Set("user_name", user.Name)
+		if sessionID != 0 {
+			c.Set(SessionIDKey, int(sessionID))
 		}
-		if name, ok := claims["name"].(string); ok {
-			c.Set("user_name", name)
+
+		// Attach the caller identity to every later log line of the request
+		helpers.WithLogFields(c, "user_id", user.ID, "role", user.Role)
+
+		// The password change comes first; the token issued after it still
+		// carries the enrollment requirement
+		mustChange := user.MustChangePassword
+		mustEnroll, _ := claims["must_enroll_2fa"].(bool)
+		switch path := c.FullPath(); {
+		case mustChange && path != ChangePasswordPath:
+			helpers.AbortWithError(c, http.StatusForbidden, "Password change required, use POST "+ChangePasswordPath)
+			return
+		case !mustChange && mustEnroll && path != TwoFactorSetupPath && path != TwoFactorEnablePath:
+			helpers.AbortWithError(c, http.StatusForbidden, "Two-factor enrollment required, use POST "+TwoFactorSetupPath)
+			return
 		}
 
 		c.Next()
 	}
 }
 
+// authenticateAPIKey authenticates a request made with an API key. The key
+// acts under its own name with exactly the permissions it was granted, so
+// LoadPermissions leaves them untouched.
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, rawKey string) {
+	key, err := apiKeys.Authenticate(rawKey, c.ClientIP())
+	if err != nil {
+		helpers.Logger(c).Error("Failed to authenticate API key", "error", err)
+		helpers.AbortWithError(c, http.StatusInternalServerError, "Failed to authenticate API key")
+		return
+	}
+	if key == nil {
+		helpers.AbortWithError(c, http.StatusUnauthorized, "Invalid or expired API key")
+		return
+	}
+
+	c.Set(APIKeyIDKey, key.ID)
+	c.Set("user_name", "api-key:"+key.Name)
+	c.Set("user_role", models.APIKeyRole)
+	c.Set(PermissionsKey, key.Permissions)
+
+	helpers.WithLogFields(c, "api_key_id", key.ID, "api_key", key.Prefix, "role", models.APIKeyRole)
+
+	c.Next()
+}
+
 // RequireRole returns middleware that checks if the authenticated user
 // has one of the specified roles.
 func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("user_role")
 		if !exists {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				"status":  false,
-				"message": "Access denied",
-			})
+			helpers.AbortWithError(c, http.StatusForbidden, "Access denied")
 			return
 		}
 
 		role, ok := userRole.(string)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				"status":  false,
-				"message": "Invalid user role",
-			})
+			helpers.AbortWithError(c, http.StatusForbidden, "Invalid user role")
 			return
 		}
 
@@ -116,9 +185,6 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 			}
 		}
 
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-			"status":  false,
-			"message": "Insufficient permissions",
-		})
+		helpers.AbortWithError(c, http.StatusForbidden, "Insufficient permissions")
 	}
 }
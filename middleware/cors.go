@@ -12,7 +12,8 @@ func CORS() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:3000", "http://localhost:4173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Content-Type", "Authorization", "Accept", "X-Requested-With"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", "Accept", "X-Requested-With", RequestIDHeader, "X-Approval-Token", APIKeyHeader},
+		ExposeHeaders:    []string{RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           24 * time.Hour,
 	})
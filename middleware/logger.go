@@ -1,33 +1,55 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
+	"retail-core-api/helpers"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Logger returns a custom request logging middleware
-func Logger() gin.HandlerFunc {
+// Logger returns a structured request logging middleware. It stores a
+// request-scoped logger carrying the request ID in the Gin context (see
+// helpers.Logger) and emits one log line per request once it completes.
+func Logger(logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
 
+		c.Set(helpers.LoggerKey, logger.With(helpers.RequestIDKey, c.GetString(helpers.RequestIDKey)))
+
 		// Process request
 		c.Next()
 
-		latency := time.Since(start)
 		status := c.Writer.Status()
-		method := c.Request.Method
-		clientIP := c.ClientIP()
-
+		attrs := []any{
+			"status", status,
+			"method", c.Request.Method,
+			"path", path,
+			"route", c.FullPath(),
+			"client_ip", c.ClientIP(),
+			"user_agent", c.Request.UserAgent(),
+			"latency_ms", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+		}
 		if query != "" {
-			path = path + "?" + query
+			attrs = append(attrs, "query", query)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
 		}
 
-		log.Printf("[%d] %s %s | %s | %v",
-			status, method, path, clientIP, latency,
-		)
+		// helpers.Logger already carries request_id and, for authenticated
+		// requests, the user_id (or api_key_id) and role fields added by Auth
+		helpers.Logger(c).Log(c.Request.Context(), level, "request completed", attrs...)
 	}
 }
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"retail-core-api/helpers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionsKey is the Gin context key holding the caller's permissions
+const PermissionsKey = "permissions"
+
+// PermissionResolver resolves the permissions granted to a role
+type PermissionResolver interface {
+	Permissions(role string) ([]string, error)
+}
+
+// LoadPermissions resolves the permissions of the authenticated user's role
+// and stores them in the Gin context for RequirePermission and
+// HasPermission. It must run after Auth.
+func LoadPermissions(resolver PermissionResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get(PermissionsKey); exists {
+			c.Next()
+			return
+		}
+
+		permissions, err := resolver.Permissions(c.GetString("user_role"))
+		if err != nil {
+			helpers.Logger(c).Error("Failed to resolve permissions", "error", err)
+			helpers.AbortWithError(c, http.StatusInternalServerError, "Failed to resolve permissions")
+			return
+		}
+
+		c.Set(PermissionsKey, permissions)
+		c.Next()
+	}
+}
+
+// RequirePermission returns middleware that checks if the authenticated
+// caller has been granted the given permission.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, permission) {
+			helpers.AbortWithError(c, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the caller of the current request has been
+// granted the given permission.
+func HasPermission(c *gin.Context, permission string) bool {
+	value, exists := c.Get(PermissionsKey)
+	if !exists {
+		return false
+	}
+	permissions, ok := value.([]string)
+	if !ok {
+		return false
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"io"
+	"retail-core-api/helpers"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery recovers from panics, logs them through the request-scoped
+// logger and responds with the standard error envelope.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		helpers.Logger(c).Error("panic recovered", "error", err, "stack", string(debug.Stack()))
+		helpers.InternalError(c, "Internal server error")
+		c.Abort()
+	})
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// APIKeyRole is the actor role recorded for requests made with an API key
+const APIKeyRole = "api_key"
+
+// APIKey is an owner-managed credential for machine clients. Only a hash of
+// the secret is stored; Prefix identifies the key in lists and logs.
+// @Description API key metadata (the secret is only shown once, on creation)
+type APIKey struct {
+	ID          int        `json:"id" example:"1"`
+	Name        string     `json:"name" example:"E-commerce sync"`
+	Prefix      string     `json:"prefix" example:"rk_3f9c2a7e1b5d"`
+	Permissions []string   `json:"permissions" example:"product.read,product.write"`
+	CreatedBy   *int       `json:"created_by" example:"1"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2027-01-01T00:00:00Z"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" example:"2026-02-08T12:00:00Z"`
+	LastUsedIP  string     `json:"last_used_ip,omitempty" example:"203.0.113.7"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" example:"2026-03-01T00:00:00Z"`
+	CreatedAt   time.Time  `json:"created_at" example:"2026-02-08T12:00:00Z"`
+	KeyHash     string     `json:"-"`
+}
+
+// APIKeyInput represents the request body for creating an API key
+// @Description Name, permission scope and optional expiry of a new API key
+type APIKeyInput struct {
+	Name        string     `json:"name" example:"E-commerce sync" binding:"required"`
+	Permissions []string   `json:"permissions" example:"product.read,product.write" binding:"required,min=1"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2027-01-01T00:00:00Z"`
+}
+
+// APIKeyCreated is returned once when a key is created
+// @Description New API key with its secret; store it now, it cannot be retrieved again
+type APIKeyCreated struct {
+	APIKey
+	Key string `json:"key" example:"rk_3f9c2a7e1b5d_Jx0v4m0d3Q9Yb1cK2lq8m5rW7tZp4sNn6hUe0aFgXyI"`
+}
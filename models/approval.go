@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Actions that can require a manager approval
+const (
+	ApprovalActionVoid     = "transaction.void"
+	ApprovalActionDiscount = "transaction.discount"
+)
+
+// ApprovalRequest is submitted on a cashier's terminal when a manager enters
+// their PIN to approve a sensitive action
+// @Description Manager credentials and the action being approved
+type ApprovalRequest struct {
+	Email         string `json:"email" example:"manager@retail.com" binding:"required,email"`
+	PIN           string `json:"pin" example:"4821" binding:"required"`
+	Action        string `json:"action" example:"transaction.void" binding:"required,oneof=transaction.void transaction.discount"`
+	TransactionID *int   `json:"transaction_id,omitempty" example:"42"`
+	// Discount and GrossAmount are required for transaction.discount; the
+	// token only approves a checkout with exactly these amounts
+	Discount    *int `json:"discount,omitempty" example:"50000"`
+	GrossAmount *int `json:"gross_amount,omitempty" example:"250000"`
+}
+
+// ApprovalBinding is what an approval is used for. A grant only authorizes
+// an action whose binding matches the one it was issued for: a void its
+// transaction, a discount its discount and gross amount.
+type ApprovalBinding struct {
+	TransactionID *int
+	Discount      *int
+	GrossAmount   *int
+}
+
+// ApprovalResponse carries a single-use approval token
+// @Description Single-use approval token to pass in the X-Approval-Token header
+type ApprovalResponse struct {
+	ApprovalToken string    `json:"approval_token" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Action        string    `json:"action" example:"transaction.void"`
+	ApprovedBy    int       `json:"approved_by" example:"2"`
+	ApproverName  string    `json:"approver_name" example:"Store Manager"`
+	ExpiresAt     time.Time `json:"expires_at" example:"2026-02-08T12:05:00Z"`
+}
+
+// ApprovalGrant is a stored approval issued by a manager
+type ApprovalGrant struct {
+	ID         int
+	ApproverID int
+	Action     string
+	ApprovalBinding
+	ExpiresAt time.Time
+}
+
+// PINInput represents the request body for setting an approval PIN
+// @Description Current password confirmation and the new approval PIN (4-8 digits)
+type PINInput struct {
+	CurrentPassword string `json:"current_password" example:"password123" binding:"required"`
+	PIN             string `json:"pin" example:"4821" binding:"required"`
+}
+
+// ApprovalAttempt is a recorded approval PIN check. ApproverID is nil when
+// the approver email did not match an active user.
+type ApprovalAttempt struct {
+	ApproverID     *int
+	CallerUserID   *int
+	CallerAPIKeyID *int
+	Success        bool
+	IP             string
+}
+
+// FailureStreak summarizes the failed PIN checks since the last successful one
+type FailureStreak struct {
+	Count       int
+	LastFailure time.Time
+}
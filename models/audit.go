@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionVoid    = "void"
+	AuditActionApprove = "approve"
+	AuditActionSetPIN  = "set_pin"
+	AuditActionLock    = "lock"
+	AuditActionUnlock  = "unlock"
+
+	AuditActionChangePassword = "change_password"
+	AuditActionResetPassword  = "reset_password"
+	AuditActionEnable2FA      = "enable_2fa"
+	AuditActionDisable2FA     = "disable_2fa"
+	AuditActionRevoke         = "revoke"
+	AuditActionActivate       = "activate"
+	AuditActionDeactivate     = "deactivate"
+	AuditActionArchive        = "archive"
+	AuditActionRestore        = "restore"
+	AuditActionImport         = "import"
+	AuditActionApprovalFailed = "approval_failed"
+)
+
+// Audited entity types
+const (
+	AuditEntityCategory    = "category"
+	AuditEntityProduct     = "product"
+	AuditEntityTransaction = "transaction"
+	AuditEntityUser        = "user"
+	AuditEntityRole        = "role"
+	AuditEntityApproval    = "approval"
+	AuditEntityAPIKey      = "api_key"
+	AuditEntityImage       = "product_image"
+)
+
+// Actor identifies who performed an action, taken from the authenticated request
+type Actor struct {
+	UserID      *int
+	APIKeyID    *int
+	Name        string
+	Role        string
+	Permissions []string
+	IP          string
+	UserAgent   string
+	RequestID   string
+}
+
+// Can reports whether the actor has been granted the given permission
+func (a Actor) Can(permission string) bool {
+	for _, p := range a.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditLog represents a single audit log entry
+// @Description Record of a mutating action with actor, entity and before/after state
+type AuditLog struct {
+	ID         int             `json:"id" example:"1"`
+	ActorID    *int            `json:"actor_id" example:"1"`
+	ActorName  string          `json:"actor_name" example:"Admin"`
+	ActorRole  string          `json:"actor_role" example:"owner"`
+	APIKeyID   *int            `json:"api_key_id,omitempty" example:"2"`
+	Action     string          `json:"action" example:"update" enums:"create,update,delete,void"`
+	EntityType string          `json:"entity_type" example:"product"`
+	EntityID   int             `json:"entity_id" example:"3"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	Changes    json.RawMessage `json:"changes,omitempty" swaggertype:"object"`
+	IP         string          `json:"ip" example:"203.0.113.7"`
+	RequestID  string          `json:"request_id" example:"3f9c2a7e1b5d4c8e9a0b1c2d3e4f5a6b"`
+	CreatedAt  time.Time       `json:"created_at" example:"2026-02-08T12:00:00Z"`
+}
+
+// AuditLogListParams holds the query parameters for listing audit log entries
+type AuditLogListParams struct {
+	EntityType string
+	EntityID   *int
+	ActorID    *int
+	Action     string
+	StartDate  string
+	EndDate    string
+	Page       int
+	Limit      int
+}
+
+// PaginatedAuditLogs represents a paginated list of audit log entries
+// @Description Paginated list of audit log entries
+type PaginatedAuditLogs struct {
+	Data       []AuditLog `json:"data"`
+	Total      int        `json:"total" example:"100"`
+	Page       int        `json:"page" example:"1"`
+	Limit      int        `json:"limit" example:"20"`
+	TotalPages int        `json:"total_pages" example:"5"`
+}
@@ -2,14 +2,18 @@ package models
 
 import "time"
 
-// Category represents a category entity
-// @Description Category information with ID, name and description
+// Category represents a category entity. Categories form a tree through
+// ParentID; Children is only filled in the tree view.
+// @Description Category information with ID, name, description and parent
 type Category struct {
-	ID          int       `json:"id" example:"1"`
-	Name        string    `json:"name" example:"Electronics" binding:"required"`
-	Description string    `json:"description" example:"Electronic devices and gadgets"`
-	CreatedAt   time.Time `json:"created_at" example:"2024-01-30T12:00:00Z"`
-	UpdatedAt   time.Time `json:"updated_at" example:"2024-01-30T12:00:00Z"`
+	ID          int        `json:"id" example:"1"`
+	Name        string     `json:"name" example:"Electronics" binding:"required"`
+	Description string     `json:"description" example:"Electronic devices and gadgets"`
+	ParentID    *int       `json:"parent_id" example:"3"`
+	Children    []Category `json:"children,omitempty"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty" example:"2024-02-01T12:00:00Z"`
+	CreatedAt   time.Time  `json:"created_at" example:"2024-01-30T12:00:00Z"`
+	UpdatedAt   time.Time  `json:"updated_at" example:"2024-01-30T12:00:00Z"`
 }
 
 // CategoryInput represents the input for creating/updating a category
@@ -17,4 +21,28 @@ type Category struct {
 type CategoryInput struct {
 	Name        string `json:"name" example:"Electronics" binding:"required"`
 	Description string `json:"description" example:"Electronic devices and gadgets"`
+	ParentID    *int   `json:"parent_id" example:"3"`
+}
+
+// Outcomes of a category deletion
+const (
+	CategoryDeleted    = "deleted"
+	CategoryReassigned = "reassigned"
+	CategoryArchived   = "archived"
+)
+
+// CategoryDeleteOptions controls what happens to the products and
+// subcategories of a category being deleted
+type CategoryDeleteOptions struct {
+	ReassignTo *int // move them to this category, then delete
+	Archive    bool // archive the category and its subtree, deactivating their products
+}
+
+// CategoryDeleteResult reports the outcome of a category deletion
+// @Description Outcome of deleting a category and how many products it affected
+type CategoryDeleteResult struct {
+	Action             string `json:"action" example:"reassigned" enums:"deleted,reassigned,archived"`
+	AffectedProducts   int    `json:"affected_products" example:"12"`
+	AffectedCategories int    `json:"affected_categories" example:"2"`
+	ReassignedTo       *int   `json:"reassigned_to,omitempty" example:"4"`
 }
\ No newline at end of file
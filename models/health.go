@@ -0,0 +1,38 @@
+package models
+
+// BuildInfo describes the running binary
+// @Description Build information of the running API instance
+type BuildInfo struct {
+	Version   string `json:"version" example:"1.0"`
+	Commit    string `json:"commit" example:"a1b2c3d"`
+	BuildTime string `json:"build_time" example:"2026-02-08T12:00:00Z"`
+	GoVersion string `json:"go_version" example:"go1.24.12"`
+}
+
+// DBPoolStats reports the state of the database connection pool
+// @Description Database connection pool statistics
+type DBPoolStats struct {
+	MaxOpen int `json:"max_open" example:"25"`
+	Open    int `json:"open" example:"6"`
+	InUse   int `json:"in_use" example:"2"`
+	Idle    int `json:"idle" example:"4"`
+}
+
+// DependencyCheck is the result of checking a single dependency
+// @Description Result of a single dependency check
+type DependencyCheck struct {
+	Status    string `json:"status" example:"up" enums:"up,down"`
+	LatencyMs int64  `json:"latency_ms" example:"3"`
+	Error     string `json:"error,omitempty" example:"connection refused"`
+}
+
+// ReadinessReport is the response body of the readiness probe
+// @Description Readiness report with dependency checks, pool stats and build info
+type ReadinessReport struct {
+	Status                string                     `json:"status" example:"ready" enums:"ready,not_ready"`
+	Checks                map[string]DependencyCheck `json:"checks"`
+	DBPool                DBPoolStats                `json:"db_pool"`
+	SchemaVersion         int                        `json:"schema_version" example:"1"`
+	ExpectedSchemaVersion int                        `json:"expected_schema_version" example:"1"`
+	Build                 BuildInfo                  `json:"build"`
+}
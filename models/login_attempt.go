@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Login attempt outcomes
+const (
+	LoginResultSuccess             = "success"
+	LoginResultInvalidCredentials  = "invalid_credentials"
+	LoginResultInvalidSecondFactor = "invalid_second_factor"
+	LoginResultInactive            = "inactive"
+	LoginResultLocked              = "locked"
+	LoginResultThrottled           = "throttled"
+)
+
+// ClientInfo describes the client a login request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+// LoginAttempt is a recorded login attempt
+// @Description Login attempt with its outcome and client details
+type LoginAttempt struct {
+	ID        int       `json:"id" example:"1"`
+	Email     string    `json:"email" example:"admin@retail.com"`
+	UserID    *int      `json:"user_id" example:"1"`
+	Result    string    `json:"result" example:"invalid_credentials" enums:"success,invalid_credentials,invalid_second_factor,inactive,locked,throttled"`
+	IP        string    `json:"ip" example:"203.0.113.7"`
+	UserAgent string    `json:"user_agent" example:"Mozilla/5.0"`
+	RequestID string    `json:"request_id" example:"3f9c2a7e1b5d4c8e9a0b1c2d3e4f5a6b"`
+	CreatedAt time.Time `json:"created_at" example:"2026-02-08T12:00:00Z"`
+}
+
+// LoginAttemptListParams holds the query parameters for listing login attempts
+type LoginAttemptListParams struct {
+	Email     string
+	UserID    *int
+	IP        string
+	Result    string
+	StartDate string
+	EndDate   string
+	Page      int
+	Limit     int
+}
+
+// PaginatedLoginAttempts represents a paginated list of login attempts
+// @Description Paginated list of login attempts
+type PaginatedLoginAttempts struct {
+	Data       []LoginAttempt `json:"data"`
+	Total      int            `json:"total" example:"100"`
+	Page       int            `json:"page" example:"1"`
+	Limit      int            `json:"limit" example:"20"`
+	TotalPages int            `json:"total_pages" example:"5"`
+}
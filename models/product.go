@@ -2,31 +2,37 @@ package models
 
 import "time"
 
-// Product represents a product entity
+// LowStockThreshold is the stock level below which a product counts as low
+// on stock
+const LowStockThreshold = 10
+
+// Product represents a product entity. Deleted products are archived
+// (DeletedAt set) rather than removed, so past transactions keep them.
 // @Description Product information with ID, name, price, stock, and category relationship
 type Product struct {
-	ID           int       `json:"id" example:"1"`
-	Name         string    `json:"name" example:"iPhone 15 Pro" binding:"required"`
-	Price        int       `json:"price" example:"15000000" binding:"required"`
-	Stock        int       `json:"stock" example:"50" binding:"required"`
-	SKU          string    `json:"sku" example:"IP15PRO-001"`
-	ImageURL     string    `json:"image_url" example:"https://example.com/img.jpg"`
-	Unit         string    `json:"unit" example:"pcs"`
-	IsActive     bool      `json:"is_active" example:"true"`
-	CategoryID   *int      `json:"category_id" example:"1"`
-	CategoryName string    `json:"category_name,omitempty" example:"Electronics"`
-	CreatedAt    time.Time `json:"created_at" example:"2024-01-30T12:00:00Z"`
-	UpdatedAt    time.Time `json:"updated_at" example:"2024-01-30T12:00:00Z"`
+	ID           int        `json:"id" example:"1"`
+	Name         string     `json:"name" example:"iPhone 15 Pro" binding:"required"`
+	Price        int        `json:"price" example:"15000000" binding:"required"`
+	Stock        int        `json:"stock" example:"50" binding:"required"`
+	SKU          string     `json:"sku" example:"IP15PRO-001"`
+	ImageURL     string     `json:"image_url" example:"https://example.com/img.jpg"` // URL of the primary image, managed through the image endpoints
+	Unit         string     `json:"unit" example:"pcs"`
+	IsActive     bool       `json:"is_active" example:"true"`
+	CategoryID   *int       `json:"category_id" example:"1"`
+	CategoryName string     `json:"category_name,omitempty" example:"Electronics"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty" example:"2024-02-01T12:00:00Z"` // set while archived
+	CreatedAt    time.Time  `json:"created_at" example:"2024-01-30T12:00:00Z"`
+	UpdatedAt    time.Time  `json:"updated_at" example:"2024-01-30T12:00:00Z"`
 }
 
-// ProductInput represents the input for creating/updating a product
-// @Description Input model for creating or updating a product (ID is auto-generated)
+// ProductInput represents the input for creating/updating a product. The
+// image_url of a product follows its primary image and is not set here.
+// @Description Input model for creating or updating a product (ID is auto-generated; image_url is set by uploading images)
 type ProductInput struct {
 	Name       string `json:"name" example:"iPhone 15 Pro" binding:"required"`
 	Price      int    `json:"price" example:"15000000" binding:"required"`
 	Stock      int    `json:"stock" example:"50" binding:"required"`
 	SKU        string `json:"sku" example:"IP15PRO-001"`
-	ImageURL   string `json:"image_url" example:"https://example.com/img.jpg"`
 	Unit       string `json:"unit" example:"pcs"`
 	IsActive   *bool  `json:"is_active" example:"true"`
 	CategoryID *int   `json:"category_id" example:"1"`
@@ -34,18 +40,44 @@ type ProductInput struct {
 
 // ProductListParams holds the query parameters for listing products
 type ProductListParams struct {
-	Search     string
-	CategoryID *int
-	Page       int
-	Limit      int
+	Search          string
+	CategoryID      *int
+	MinPrice        *int
+	MaxPrice        *int
+	MinStock        *int
+	MaxStock        *int
+	LowStock        bool
+	IsActive        *bool
+	Unit            string
+	UpdatedSince    *time.Time
+	IncludeInactive bool
+	IncludeDeleted  bool
+	Sort            []SortTerm
+	// Cursor continues a keyset-paginated listing; Page is ignored with it
+	Cursor    *Cursor
+	SkipTotal bool
+	Page      int
+	Limit     int
 }
 
 // PaginatedProducts represents a paginated list of products
 // @Description Paginated list of products
 type PaginatedProducts struct {
 	Data       []Product      `json:"data"`
-	Total      int            `json:"total" example:"100"`
-	Page       int            `json:"page" example:"1"`
+	Total      *int           `json:"total,omitempty" example:"100"`
+	Page       int            `json:"page,omitempty" example:"1"`
 	Limit      int            `json:"limit" example:"20"`
-	TotalPages int            `json:"total_pages" example:"5"`
+	TotalPages *int           `json:"total_pages,omitempty" example:"5"`
+	NextCursor string         `json:"next_cursor,omitempty" example:"eyJrIjpbIjQyIl0sInMiOiI5ZjJjIn0"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+// ProductSuggestion is a compact search hit for autocomplete
+// @Description Product match for search-as-you-type
+type ProductSuggestion struct {
+	ID    int    `json:"id" example:"1"`
+	Name  string `json:"name" example:"Indomie Goreng"`
+	SKU   string `json:"sku" example:"IDM-GRG-01"`
+	Price int    `json:"price" example:"3000"`
+	Stock int    `json:"stock" example:"120"`
 }
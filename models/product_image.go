@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Content types accepted for product image uploads
+const (
+	ImageTypeJPEG = "image/jpeg"
+	ImageTypePNG  = "image/png"
+	ImageTypeWebP = "image/webp"
+)
+
+// MaxProductImages is how many images a product can have
+const MaxProductImages = 10
+
+// ProductImage is an uploaded product photo and its thumbnail. The primary
+// image of a product is also exposed as the product's image_url.
+// @Description Product image with thumbnail and primary flag
+type ProductImage struct {
+	ID           int       `json:"id" example:"1"`
+	ProductID    int       `json:"product_id" example:"12"`
+	URL          string    `json:"url" example:"/uploads/products/12/9f2c4b1e.jpg"`
+	ThumbnailURL string    `json:"thumbnail_url" example:"/uploads/products/12/9f2c4b1e_thumb.jpg"`
+	ContentType  string    `json:"content_type" example:"image/jpeg"`
+	Size         int64     `json:"size" example:"284133"`
+	Width        int       `json:"width" example:"1600"`
+	Height       int       `json:"height" example:"1200"`
+	IsPrimary    bool      `json:"is_primary" example:"true"`
+	CreatedAt    time.Time `json:"created_at" example:"2026-02-08T12:00:00Z"`
+
+	// Storage keys of the image and thumbnail; URLs are derived from them
+	StorageKey   string `json:"-"`
+	ThumbnailKey string `json:"-"`
+}
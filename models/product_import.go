@@ -0,0 +1,93 @@
+package models
+
+// Import file formats
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatXLSX = "xlsx"
+)
+
+// MaxImportRows is the most data rows, besides the header, an import file
+// may have
+const MaxImportRows = 10_000
+
+// Product import fields that a file column can be mapped to
+const (
+	ImportFieldSKU      = "sku"
+	ImportFieldName     = "name"
+	ImportFieldPrice    = "price"
+	ImportFieldStock    = "stock"
+	ImportFieldUnit     = "unit"
+	ImportFieldIsActive = "is_active"
+	ImportFieldCategory = "category"
+)
+
+// ImportFields lists every field a product import column can be mapped to
+var ImportFields = []string{
+	ImportFieldSKU,
+	ImportFieldName,
+	ImportFieldPrice,
+	ImportFieldStock,
+	ImportFieldUnit,
+	ImportFieldIsActive,
+	ImportFieldCategory,
+}
+
+// ProductImportOptions controls how an uploaded product file is imported
+type ProductImportOptions struct {
+	Format string
+	// Mapping maps import fields to the header of the file column holding
+	// them. Fields that are not mapped are looked up by their own name.
+	Mapping map[string]string
+	DryRun  bool
+	// BatchSize commits the import in batches of this many rows. Zero
+	// applies the whole file in a single transaction.
+	BatchSize int
+	// StartRow skips data rows before this file row, to resume a batched
+	// import that stopped part way.
+	StartRow int
+}
+
+// ProductImportRow is a validated file row ready to be written. ProductID is
+// 0 when the row creates a new product. Fields lists the import fields the
+// row has a value for; an update writes only those and keeps the product's
+// current values for the rest.
+type ProductImportRow struct {
+	Row          int
+	ProductID    int
+	Product      Product
+	Fields       []string
+	CategoryName string
+}
+
+// ProductImportChange is a product an import wrote, as it was before the
+// row (nil when the row created it) and after
+type ProductImportChange struct {
+	Row    int
+	Before *Product
+	After  *Product
+}
+
+// ProductImportError describes why a file row was rejected
+// @Description Validation error for one row of an import file
+type ProductImportError struct {
+	Row     int    `json:"row" example:"7"`
+	Field   string `json:"field,omitempty" example:"price"`
+	Message string `json:"message" example:"price must be a whole number"`
+}
+
+// ProductImportResult reports what an import did, or would do on a dry run
+// @Description Outcome of a product import with per-row errors
+type ProductImportResult struct {
+	DryRun            bool                 `json:"dry_run" example:"false"`
+	Applied           bool                 `json:"applied" example:"true"`
+	TotalRows         int                  `json:"total_rows" example:"1200"`
+	Skipped           int                  `json:"skipped" example:"0"`
+	Created           int                  `json:"created" example:"1150"`
+	Updated           int                  `json:"updated" example:"50"`
+	Failed            int                  `json:"failed" example:"0"`
+	CategoriesCreated []string             `json:"categories_created"`
+	BatchesCommitted  int                  `json:"batches_committed" example:"3"`
+	LastCommittedRow  int                  `json:"last_committed_row,omitempty" example:"1201"`
+	ResumeFromRow     *int                 `json:"resume_from_row,omitempty" example:"502"`
+	Errors            []ProductImportError `json:"errors"`
+}
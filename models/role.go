@@ -0,0 +1,117 @@
+package models
+
+import "time"
+
+// Permissions checked by middleware.RequirePermission
+const (
+	PermCategoryRead      = "category.read"
+	PermCategoryWrite     = "category.write"
+	PermProductRead       = "product.read"
+	PermProductWrite      = "product.write"
+	PermTransactionCreate = "transaction.create"
+	PermTransactionRead   = "transaction.read"
+	PermTransactionVoid   = "transaction.void"
+	PermApprovalGrant     = "approval.grant"
+	PermDashboardRead     = "dashboard.read"
+	PermReportRead        = "report.read"
+	PermUserManage        = "user.manage"
+	PermRoleManage        = "role.manage"
+	PermAuditRead         = "audit.read"
+	PermAPIKeyManage      = "apikey.manage"
+)
+
+// AllPermissions lists every permission known to the API
+var AllPermissions = []string{
+	PermCategoryRead,
+	PermCategoryWrite,
+	PermProductRead,
+	PermProductWrite,
+	PermTransactionCreate,
+	PermTransactionRead,
+	PermTransactionVoid,
+	PermApprovalGrant,
+	PermDashboardRead,
+	PermReportRead,
+	PermUserManage,
+	PermRoleManage,
+	PermAuditRead,
+	PermAPIKeyManage,
+}
+
+// IsValidPermission reports whether p is a known permission
+func IsValidPermission(p string) bool {
+	for _, known := range AllPermissions {
+		if known == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in role names
+const (
+	RoleOwner      = "owner"
+	RoleManager    = "manager"
+	RoleCashier    = "cashier"
+	RoleStockClerk = "stock_clerk"
+)
+
+// DefaultRoles are the system roles seeded on startup. Their permissions are
+// kept in sync by the migrations and cannot be changed through the API.
+var DefaultRoles = []Role{
+	{
+		Name:        RoleOwner,
+		Description: "Full access to the store, users, roles and audit log",
+		Permissions: AllPermissions,
+	},
+	{
+		Name:        RoleManager,
+		Description: "Runs the store floor: catalog, sales, voids and reports",
+		Permissions: []string{
+			PermCategoryRead, PermCategoryWrite,
+			PermProductRead, PermProductWrite,
+			PermTransactionCreate, PermTransactionRead, PermTransactionVoid,
+			PermApprovalGrant,
+			PermDashboardRead, PermReportRead,
+		},
+	},
+	{
+		Name:        RoleCashier,
+		Description: "Rings up sales at the point of sale; voids need a manager approval",
+		Permissions: []string{
+			PermCategoryRead,
+			PermProductRead,
+			PermTransactionCreate, PermTransactionRead, PermTransactionVoid,
+			PermDashboardRead,
+		},
+	},
+	{
+		Name:        RoleStockClerk,
+		Description: "Maintains the catalog and stock levels",
+		Permissions: []string{
+			PermCategoryRead, PermCategoryWrite,
+			PermProductRead, PermProductWrite,
+			PermDashboardRead,
+		},
+	},
+}
+
+// Role represents a named set of permissions assigned to users
+// @Description Role with its granted permissions
+type Role struct {
+	ID          int       `json:"id" example:"1"`
+	Name        string    `json:"name" example:"manager"`
+	Description string    `json:"description" example:"Runs the store floor"`
+	Permissions []string  `json:"permissions" example:"product.read,product.write"`
+	IsSystem    bool      `json:"is_system" example:"true"`
+	CreatedAt   time.Time `json:"created_at" example:"2026-01-30T12:00:00Z"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2026-01-30T12:00:00Z"`
+}
+
+// RoleInput represents the input for creating/updating a custom role
+// @Description Input model for creating or updating a custom role
+type RoleInput struct {
+	Name        string   `json:"name" example:"shift_lead" binding:"required"`
+	Description string   `json:"description" example:"Cashier who can also void sales"`
+	Permissions []string `json:"permissions" example:"transaction.create,transaction.void" binding:"required"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Session is a login session, recorded whenever an access token is issued
+// @Description Login session with the client it was issued to
+type Session struct {
+	ID        int       `json:"id" example:"12"`
+	UserID    int       `json:"user_id" example:"1"`
+	IP        string    `json:"ip" example:"203.0.113.7"`
+	UserAgent string    `json:"user_agent" example:"Mozilla/5.0"`
+	CreatedAt time.Time `json:"created_at" example:"2026-02-08T12:00:00Z"`
+	ExpiresAt time.Time `json:"expires_at" example:"2026-02-09T12:00:00Z"`
+	Current   bool      `json:"current" example:"true"` // the session of the token making the request
+}
+
+// ProfileInput represents the request body for updating one's own profile
+// @Description New name and/or password; changing the password requires the current one
+type ProfileInput struct {
+	Name            string  `json:"name,omitempty" example:"John Doe"`
+	CurrentPassword string  `json:"current_password,omitempty" example:"password123"`
+	NewPassword     string  `json:"new_password,omitempty" example:"N3wSecret!"`
+	Role            *string `json:"role,omitempty" swaggerignore:"true"` // rejected, roles are managed by owners
+}
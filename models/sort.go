@@ -0,0 +1,24 @@
+package models
+
+// SortTerm is one key of a list ordering, parsed from a sort parameter such
+// as "-price,name"
+type SortTerm struct {
+	Field string
+	Desc  bool
+}
+
+// ProductSortFields are the fields the product list can be sorted by
+var ProductSortFields = []string{"id", "name", "price", "stock", "created_at", "updated_at", "units_sold"}
+
+// TransactionSortFields are the fields the transaction list can be sorted by
+var TransactionSortFields = []string{"id", "created_at", "total_amount", "discount", "item_count"}
+
+// Cursor marks a position in a keyset-paginated list: the sort key values of
+// a row, as text, ending with its id. Before asks for the rows preceding the
+// position instead of those following it. Sort fingerprints the ordering the
+// cursor was issued for, so it cannot be replayed against another one.
+type Cursor struct {
+	Keys   []string `json:"k"`
+	Before bool     `json:"b,omitempty"`
+	Sort   string   `json:"s"`
+}
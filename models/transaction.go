@@ -5,14 +5,16 @@ import "time"
 // Transaction represents a completed transaction
 // @Description Transaction information with details of purchased items
 type Transaction struct {
-	ID            int                 `json:"id" example:"1"`
-	TotalAmount   int                 `json:"total_amount" example:"45000"`
-	PaymentMethod string              `json:"payment_method" example:"cash"`
-	Discount      int                 `json:"discount" example:"0"`
-	Notes         string              `json:"notes" example:""`
-	Status        string              `json:"status" example:"active"`
-	CreatedAt     time.Time           `json:"created_at" example:"2026-02-08T12:00:00Z"`
-	Details       []TransactionDetail `json:"details"`
+	ID             int                 `json:"id" example:"1"`
+	TotalAmount    int                 `json:"total_amount" example:"45000"`
+	PaymentMethod  string              `json:"payment_method" example:"cash"`
+	Discount       int                 `json:"discount" example:"0"`
+	Notes          string              `json:"notes" example:""`
+	Status         string              `json:"status" example:"active"`
+	ApprovedBy     *int                `json:"approved_by" example:"2"`
+	VoidApprovedBy *int                `json:"void_approved_by" example:"2"`
+	CreatedAt      time.Time           `json:"created_at" example:"2026-02-08T12:00:00Z"`
+	Details        []TransactionDetail `json:"details"`
 }
 
 // TransactionDetail represents a single item in a transaction
@@ -22,6 +24,7 @@ type TransactionDetail struct {
 	TransactionID int    `json:"transaction_id" example:"1"`
 	ProductID     int    `json:"product_id" example:"3"`
 	ProductName   string `json:"product_name,omitempty" example:"Indomie Goreng"`
+	ProductSKU    string `json:"product_sku,omitempty" example:"IDM-GRG-01"`
 	Quantity      int    `json:"quantity" example:"5"`
 	UnitPrice     int    `json:"unit_price" example:"3000"`
 	Subtotal      int    `json:"subtotal" example:"15000"`
@@ -41,6 +44,8 @@ type CheckoutRequest struct {
 	PaymentMethod string         `json:"payment_method" example:"cash"`
 	Discount      int            `json:"discount" example:"0"`
 	Notes         string         `json:"notes" example:""`
+	// ApprovedBy is set by the service when the discount was approved
+	ApprovedBy *int `json:"-"`
 }
 
 // SalesReport represents the sales summary response
@@ -81,14 +86,29 @@ type TransactionListItem struct {
 	CreatedAt     time.Time `json:"created_at" example:"2026-02-08T12:00:00Z"`
 }
 
+// TransactionListParams holds the filters and ordering for listing
+// transactions. StartDate and EndDate are YYYY-MM-DD and may be empty.
+type TransactionListParams struct {
+	StartDate string
+	EndDate   string
+	Sort      []SortTerm
+	// Cursor continues a keyset-paginated listing; Page is ignored with it
+	Cursor    *Cursor
+	SkipTotal bool
+	Page      int
+	Limit     int
+}
+
 // PaginatedTransactions represents a paginated list of transactions
 // @Description Paginated list of transactions
 type PaginatedTransactions struct {
 	Data       []TransactionListItem `json:"data"`
-	Total      int                   `json:"total" example:"100"`
-	Page       int                   `json:"page" example:"1"`
+	Total      *int                  `json:"total,omitempty" example:"100"`
+	Page       int                   `json:"page,omitempty" example:"1"`
 	Limit      int                   `json:"limit" example:"10"`
-	TotalPages int                   `json:"total_pages" example:"10"`
+	TotalPages *int                  `json:"total_pages,omitempty" example:"10"`
+	NextCursor string                `json:"next_cursor,omitempty" example:"eyJrIjpbIjQyIl0sInMiOiI5ZjJjIn0"`
+	PrevCursor string                `json:"prev_cursor,omitempty"`
 }
 
 // CategoryRevenue represents revenue breakdown per category
@@ -103,8 +123,9 @@ type CategoryRevenue struct {
 // ReportSummary represents the aggregated report summary
 // @Description Aggregated report summary with category breakdown
 type ReportSummary struct {
-	TotalRevenue       int                `json:"total_revenue" example:"15000000"`
-	TotalTransactions  int                `json:"total_transactions" example:"100"`
+	TotalRevenue       int                 `json:"total_revenue" example:"15000000"`
+	TotalTransactions  int                 `json:"total_transactions" example:"100"`
 	BestSellingProduct *BestSellingProduct `json:"best_selling_product"`
-	CategoryBreakdown  []CategoryRevenue  `json:"category_breakdown"`
+	CategoryBreakdown  []CategoryRevenue   `json:"category_breakdown"`
+	CategoryLevel      int                 `json:"category_level" example:"1"` // tree depth the breakdown is rolled up to, 0 = not rolled up
 }
@@ -0,0 +1,40 @@
+package models
+
+// TwoFactorSetupInput represents the request body for starting TOTP enrollment
+// @Description Password confirmation for two-factor enrollment
+type TwoFactorSetupInput struct {
+	Password string `json:"password" example:"password123" binding:"required"`
+}
+
+// TwoFactorSetup carries the secret to load into an authenticator app
+// @Description TOTP secret and otpauth:// provisioning URI (render it as a QR code)
+type TwoFactorSetup struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/Retail%20Core:admin@retail.com?algorithm=SHA1&digits=6&issuer=Retail+Core&period=30&secret=JBSWY3DPEHPK3PXP"`
+}
+
+// TwoFactorCodeInput represents a request body carrying a TOTP code
+// @Description Current code from the authenticator app
+type TwoFactorCodeInput struct {
+	Code string `json:"code" example:"123456" binding:"required"`
+}
+
+// TwoFactorDisableInput represents the request body for turning off two-factor authentication
+// @Description Password and a current TOTP or recovery code
+type TwoFactorDisableInput struct {
+	Password string `json:"password" example:"password123" binding:"required"`
+	Code     string `json:"code" example:"123456" binding:"required"`
+}
+
+// RecoveryCodes lists one-time recovery codes, shown only once
+// @Description Single-use recovery codes for when the authenticator is unavailable
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes" example:"ABCD-EFGH-IJKL-MNOP"`
+}
+
+// TwoFactorLoginInput represents the second step of a two-factor login
+// @Description Challenge token from /auth/login and a TOTP or recovery code
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" example:"eyJhbGciOiJIUzI1NiIs..." binding:"required"`
+	Code           string `json:"code" example:"123456" binding:"required"`
+}
@@ -5,22 +5,35 @@ import "time"
 // User represents a user entity
 // @Description User information with identity and role
 type User struct {
-	ID        int       `json:"id" example:"1"`
-	Name      string    `json:"name" example:"John Doe"`
-	Email     string    `json:"email" example:"john@example.com"`
-	Password  string    `json:"-"` // never exposed in JSON
-	Role      string    `json:"role" example:"owner" enums:"owner,cashier"`
-	IsActive  bool      `json:"is_active" example:"true"`
-	CreatedAt time.Time `json:"created_at" example:"2026-01-30T12:00:00Z"`
+	ID                 int        `json:"id" example:"1"`
+	Name               string     `json:"name" example:"John Doe"`
+	Email              string     `json:"email" example:"john@example.com"`
+	Password           string     `json:"-"` // never exposed in JSON
+	Role               string     `json:"role" example:"owner"`
+	IsActive           bool       `json:"is_active" example:"true"`
+	MustChangePassword bool       `json:"must_change_password" example:"false"` // only /auth/change-password is allowed while set
+	TwoFactorEnabled   bool       `json:"two_factor_enabled" example:"false"`
+	LockedUntil        *time.Time `json:"locked_until,omitempty" example:"2026-01-30T12:15:00Z"` // set while locked after failed logins
+	CreatedAt          time.Time  `json:"created_at" example:"2026-01-30T12:00:00Z"`
 }
 
-// UserInput represents the input for creating/updating a user
-// @Description Input model for creating or updating a user
+// UserInput represents the input for creating a user
+// @Description Input model for creating a user
 type UserInput struct {
 	Name     string `json:"name" example:"John Doe" binding:"required"`
 	Email    string `json:"email" example:"john@example.com" binding:"required,email"`
-	Password string `json:"password" example:"secret123" binding:"required,min=6"`
-	Role     string `json:"role" example:"cashier" binding:"required,oneof=owner cashier"`
+	Password string `json:"password" example:"Secret123" binding:"required"`
+	Role     string `json:"role" example:"cashier" binding:"required"`
+}
+
+// UserPatch represents a partial update of a user. Omitted fields are left
+// unchanged; passwords are reset through /api/users/{id}/reset-password.
+// @Description Fields to change on a user; omitted fields keep their value
+type UserPatch struct {
+	Name     *string `json:"name,omitempty" example:"John Doe"`
+	Email    *string `json:"email,omitempty" example:"john@example.com" binding:"omitempty,email"`
+	Role     *string `json:"role,omitempty" example:"manager"`
+	Password *string `json:"password,omitempty" swaggerignore:"true"` // rejected, see ResetPasswordInput
 }
 
 // LoginInput represents the login request body
@@ -30,9 +43,46 @@ type LoginInput struct {
 	Password string `json:"password" example:"password123" binding:"required"`
 }
 
-// LoginResponse represents the login response
-// @Description Login response with JWT token and user info
+// JWT token types, carried in the "typ" claim. Tokens without the claim are
+// access tokens issued before it was introduced.
+const (
+	TokenTypeAccess             = "access"
+	TokenTypeTwoFactorChallenge = "2fa_challenge"
+)
+
+// LoginResponse represents the login response. Accounts with two-factor
+// authentication get a challenge token instead of the JWT, to be exchanged
+// at /auth/login/2fa.
+// @Description Login response with JWT token and user info, or a two-factor challenge
 type LoginResponse struct {
-	Token string `json:"token" example:"eyJhbGciOiJIUzI1NiIs..."`
-	User  User   `json:"user"`
+	Token             string `json:"token,omitempty" example:"eyJhbGciOiJIUzI1NiIs..."`
+	User              *User  `json:"user,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty" example:"false"`
+	ChallengeToken    string `json:"challenge_token,omitempty" example:"eyJhbGciOiJIUzI1NiIs..."`
+}
+
+// ChangePasswordInput represents the request body for changing one's own password
+// @Description Current password and the new password
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" example:"password123" binding:"required"`
+	NewPassword     string `json:"new_password" example:"N3wSecret!" binding:"required"`
+}
+
+// ResetPasswordInput represents the request body for an admin password reset
+// @Description Temporary password the user must change at next login
+type ResetPasswordInput struct {
+	NewPassword string `json:"new_password" example:"Temp0rary!" binding:"required"`
+}
+
+// ForgotPasswordInput represents the request body for requesting a password reset
+// @Description Email of the account to recover
+type ForgotPasswordInput struct {
+	Email string `json:"email" example:"john@example.com" binding:"required,email"`
+}
+
+// PasswordResetInput represents the request body for completing a password reset
+// @Description Reset token from the email and the new password
+type PasswordResetInput struct {
+	Token       string `json:"token" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" binding:"required"`
+	NewPassword string `json:"new_password" example:"N3wSecret!" binding:"required"`
 }
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"retail-core-api/models"
+	"time"
+)
+
+// APIKeyRepository defines the interface for API key data access
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	GetAll() ([]models.APIKey, error)
+	GetByID(id int) (*models.APIKey, error)
+	GetByPrefix(prefix string) (*models.APIKey, error)
+	Revoke(id int) (bool, error)
+	TouchLastUsed(id int, ip string, at time.Time) error
+}
+
+// apiKeyRepository implements APIKeyRepository interface with PostgreSQL
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository instance
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, permissions, created_by, expires_at,
+	       last_used_at, last_used_ip, revoked_at, created_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	var k models.APIKey
+	var permissions []byte
+	err := row.Scan(
+		&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &permissions, &k.CreatedBy, &k.ExpiresAt,
+		&k.LastUsedAt, &k.LastUsedIP, &k.RevokedAt, &k.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(permissions, &k.Permissions); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Create inserts a new API key and fills in its ID and creation time
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	permissions, err := json.Marshal(key.Permissions)
+	if err != nil {
+		return err
+	}
+
+	var expiresAt interface{}
+	if key.ExpiresAt != nil {
+		expiresAt = key.ExpiresAt.UTC()
+	}
+
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, permissions, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(
+		query, key.Name, key.Prefix, key.KeyHash, string(permissions), key.CreatedBy, expiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetAll returns every API key, including revoked and expired ones, newest first
+func (r *apiKeyRepository) GetAll() ([]models.APIKey, error) {
+	rows, err := r.db.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetByID returns an API key by ID, or nil if it does not exist
+func (r *apiKeyRepository) GetByID(id int) (*models.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return k, err
+}
+
+// GetByPrefix returns the API key with the given prefix, or nil if none matches
+func (r *apiKeyRepository) GetByPrefix(prefix string) (*models.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = $1", prefix))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return k, err
+}
+
+// Revoke marks a key as revoked. It returns false if the key does not exist
+// or was already revoked.
+func (r *apiKeyRepository) Revoke(id int) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL", id,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// TouchLastUsed records when and from where a key was last used. To avoid a
+// write per request it only updates if the stored value is over a minute old.
+func (r *apiKeyRepository) TouchLastUsed(id int, ip string, at time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = $2, last_used_ip = $3
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2 - INTERVAL '1 minute')
+	`
+	_, err := r.db.Exec(query, id, at.UTC(), ip)
+	return err
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"database/sql"
+	"retail-core-api/models"
+)
+
+// ApprovalRepository defines the interface for approval grant data access
+type ApprovalRepository interface {
+	Create(grant models.ApprovalGrant, tokenHash string) (int, error)
+	Consume(tokenHash, action string, binding models.ApprovalBinding, usedBy *int) (*models.ApprovalGrant, error)
+	Release(id int) error
+	RecordAttempt(attempt models.ApprovalAttempt) error
+	ApproverFailures(approverID int) (*models.FailureStreak, error)
+	CallerFailures(userID, apiKeyID *int) (*models.FailureStreak, error)
+}
+
+// approvalRepository implements ApprovalRepository interface with PostgreSQL
+type approvalRepository struct {
+	db *sql.DB
+}
+
+// NewApprovalRepository creates a new approval repository instance
+func NewApprovalRepository(db *sql.DB) ApprovalRepository {
+	return &approvalRepository{db: db}
+}
+
+// Create stores a new approval grant identified by the hash of its token
+func (r *approvalRepository) Create(grant models.ApprovalGrant, tokenHash string) (int, error) {
+	query := `
+		INSERT INTO approval_grants (approver_id, action, transaction_id, discount, gross_amount, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	var id int
+	err := r.db.QueryRow(query, grant.ApproverID, grant.Action, grant.TransactionID, grant.Discount, grant.GrossAmount,
+		tokenHash, grant.ExpiresAt.UTC()).Scan(&id)
+	return id, err
+}
+
+// Consume atomically marks an unused, unexpired grant for the given action
+// as used and returns it. Void grants only match their transaction and
+// discount grants only the discount and gross amount they were issued for;
+// a grant without a binding never matches either. It returns nil when no
+// grant matches.
+func (r *approvalRepository) Consume(tokenHash, action string, binding models.ApprovalBinding, usedBy *int) (*models.ApprovalGrant, error) {
+	query := `
+		UPDATE approval_grants
+		SET used_at = CURRENT_TIMESTAMP, used_by = $3
+		WHERE token_hash = $1 AND action = $2
+		  AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		  AND CASE action
+		        WHEN $4 THEN transaction_id = $6
+		        WHEN $5 THEN discount = $7 AND gross_amount = $8
+		        ELSE transaction_id IS NULL OR transaction_id = $6
+		      END
+		RETURNING id, approver_id, action, transaction_id, discount, gross_amount, expires_at
+	`
+	var grant models.ApprovalGrant
+	err := r.db.QueryRow(query, tokenHash, action, usedBy, models.ApprovalActionVoid, models.ApprovalActionDiscount,
+		binding.TransactionID, binding.Discount, binding.GrossAmount).Scan(
+		&grant.ID, &grant.ApproverID, &grant.Action, &grant.TransactionID, &grant.Discount, &grant.GrossAmount, &grant.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Release makes a consumed grant usable again, used when the approved
+// operation failed and was rolled back
+func (r *approvalRepository) Release(id int) error {
+	_, err := r.db.Exec(`UPDATE approval_grants SET used_at = NULL, used_by = NULL WHERE id = $1`, id)
+	return err
+}
+
+// RecordAttempt stores an approval PIN check
+func (r *approvalRepository) RecordAttempt(attempt models.ApprovalAttempt) error {
+	query := `
+		INSERT INTO approval_attempts (approver_id, caller_user_id, caller_api_key_id, success, ip)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.Exec(query, attempt.ApproverID, attempt.CallerUserID, attempt.CallerAPIKeyID, attempt.Success, attempt.IP)
+	return err
+}
+
+// ApproverFailures returns the failed PIN checks against an approver since
+// their last successful one
+func (r *approvalRepository) ApproverFailures(approverID int) (*models.FailureStreak, error) {
+	return r.failureStreak("approver_id", approverID)
+}
+
+// CallerFailures returns the failed PIN checks made by a user or API key
+// since its last successful one
+func (r *approvalRepository) CallerFailures(userID, apiKeyID *int) (*models.FailureStreak, error) {
+	switch {
+	case userID != nil:
+		return r.failureStreak("caller_user_id", *userID)
+	case apiKeyID != nil:
+		return r.failureStreak("caller_api_key_id", *apiKeyID)
+	}
+	return &models.FailureStreak{}, nil
+}
+
+// failureStreak counts the failed attempts matching column = id after the
+// last successful one. column is never user input.
+func (r *approvalRepository) failureStreak(column string, id int) (*models.FailureStreak, error) {
+	query := `
+		SELECT COUNT(*), MAX(created_at) FROM approval_attempts
+		WHERE ` + column + ` = $1 AND NOT success
+		  AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM approval_attempts WHERE ` + column + ` = $1 AND success),
+			'-infinity'::timestamp
+		  )
+	`
+	var streak models.FailureStreak
+	var last sql.NullTime
+	if err := r.db.QueryRow(query, id).Scan(&streak.Count, &last); err != nil {
+		return nil, err
+	}
+	if last.Valid {
+		streak.LastFailure = last.Time
+	}
+	return &streak, nil
+}
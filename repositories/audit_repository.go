@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+)
+
+// AuditRepository defines the interface for audit log data access
+type AuditRepository interface {
+	Create(entry models.AuditLog) error
+	GetAll(params models.AuditLogListParams) (*models.PaginatedAuditLogs, error)
+}
+
+// auditRepository implements AuditRepository interface with PostgreSQL
+type auditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new audit repository instance
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// nullJSON converts an empty raw JSON value into a SQL NULL
+func nullJSON(raw []byte) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// Create inserts a new audit log entry
+func (r *auditRepository) Create(entry models.AuditLog) error {
+	query := `
+		INSERT INTO audit_log (actor_id, actor_name, actor_role, api_key_id, action, entity_type, entity_id,
+		                       before_data, after_data, changes, ip, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err := r.db.Exec(
+		query,
+		entry.ActorID, entry.ActorName, entry.ActorRole, entry.APIKeyID, entry.Action, entry.EntityType, entry.EntityID,
+		nullJSON(entry.Before), nullJSON(entry.After), nullJSON(entry.Changes), entry.IP, entry.RequestID,
+	)
+	return err
+}
+
+// GetAll returns paginated audit log entries, newest first, with optional filters
+func (r *auditRepository) GetAll(params models.AuditLogListParams) (*models.PaginatedAuditLogs, error) {
+	if params.Page <= 0 {
+		params.Page = helpers.DefaultPage
+	}
+	if params.Limit <= 0 {
+		params.Limit = helpers.DefaultLimit
+	}
+
+	// Build WHERE clause
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+
+	if params.EntityType != "" {
+		where += fmt.Sprintf(" AND a.entity_type = $%d", argIdx)
+		args = append(args, params.EntityType)
+		argIdx++
+	}
+	if params.EntityID != nil {
+		where += fmt.Sprintf(" AND a.entity_id = $%d", argIdx)
+		args = append(args, *params.EntityID)
+		argIdx++
+	}
+	if params.ActorID != nil {
+		where += fmt.Sprintf(" AND a.actor_id = $%d", argIdx)
+		args = append(args, *params.ActorID)
+		argIdx++
+	}
+	if params.Action != "" {
+		where += fmt.Sprintf(" AND a.action = $%d", argIdx)
+		args = append(args, params.Action)
+		argIdx++
+	}
+	if params.StartDate != "" {
+		where += fmt.Sprintf(" AND a.created_at::date >= $%d::date", argIdx)
+		args = append(args, params.StartDate)
+		argIdx++
+	}
+	if params.EndDate != "" {
+		where += fmt.Sprintf(" AND a.created_at::date <= $%d::date", argIdx)
+		args = append(args, params.EndDate)
+		argIdx++
+	}
+
+	// Count total
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM audit_log a"+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	// Fetch page
+	offset := (params.Page - 1) * params.Limit
+	query := fmt.Sprintf(`
+		SELECT a.id, a.actor_id, a.actor_name, a.actor_role, a.api_key_id, a.action, a.entity_type,
+		       COALESCE(a.entity_id, 0), a.before_data, a.after_data, a.changes,
+		       a.ip, a.request_id, a.created_at
+		FROM audit_log a
+		%s
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, argIdx, argIdx+1)
+	args = append(args, params.Limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditLog, 0)
+	for rows.Next() {
+		var e models.AuditLog
+		var before, after, changes []byte
+		err := rows.Scan(
+			&e.ID, &e.ActorID, &e.ActorName, &e.ActorRole, &e.APIKeyID, &e.Action, &e.EntityType,
+			&e.EntityID, &before, &after, &changes,
+			&e.IP, &e.RequestID, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		e.Before, e.After, e.Changes = before, after, changes
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedAuditLogs{
+		Data:       entries,
+		Total:      total,
+		Page:       params.Page,
+		Limit:      params.Limit,
+		TotalPages: helpers.CalcTotalPages(total, params.Limit),
+	}, nil
+}
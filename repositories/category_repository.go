@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"database/sql"
+	"retail-core-api/helpers"
 	"retail-core-api/models"
 	"time"
 )
@@ -12,7 +13,9 @@ type CategoryRepository interface {
 	GetByID(id int) (*models.Category, error)
 	Create(category models.Category) (*models.Category, error)
 	Update(id int, category models.Category) (*models.Category, error)
-	Delete(id int) error
+	Delete(id int) (products int, children int, err error)
+	DeleteReassigning(id, targetID int) (products int, children int, err error)
+	ArchiveSubtree(id int) (categories int, products int, err error)
 }
 
 // categoryRepository implements CategoryRepository interface with PostgreSQL
@@ -25,9 +28,9 @@ func NewCategoryRepository(db *sql.DB) CategoryRepository {
 	return &categoryRepository{db: db}
 }
 
-// GetAll returns all categories from database
+// GetAll returns all categories that are not archived
 func (r *categoryRepository) GetAll() ([]models.Category, error) {
-	query := `SELECT id, name, description, created_at, updated_at FROM categories ORDER BY id`
+	query := `SELECT id, name, description, parent_id, archived_at, created_at, updated_at FROM categories WHERE archived_at IS NULL ORDER BY id`
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -37,7 +40,7 @@ func (r *categoryRepository) GetAll() ([]models.Category, error) {
 	var categories []models.Category
 	for rows.Next() {
 		var cat models.Category
-		err := rows.Scan(&cat.ID, &cat.Name, &cat.Description, &cat.CreatedAt, &cat.UpdatedAt)
+		err := rows.Scan(&cat.ID, &cat.Name, &cat.Description, &cat.ParentID, &cat.ArchivedAt, &cat.CreatedAt, &cat.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -53,9 +56,9 @@ func (r *categoryRepository) GetAll() ([]models.Category, error) {
 
 // GetByID returns a category by its ID
 func (r *categoryRepository) GetByID(id int) (*models.Category, error) {
-	query := `SELECT id, name, description, created_at, updated_at FROM categories WHERE id = $1`
+	query := `SELECT id, name, description, parent_id, archived_at, created_at, updated_at FROM categories WHERE id = $1`
 	var cat models.Category
-	err := r.db.QueryRow(query, id).Scan(&cat.ID, &cat.Name, &cat.Description, &cat.CreatedAt, &cat.UpdatedAt)
+	err := r.db.QueryRow(query, id).Scan(&cat.ID, &cat.Name, &cat.Description, &cat.ParentID, &cat.ArchivedAt, &cat.CreatedAt, &cat.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -67,10 +70,10 @@ func (r *categoryRepository) GetByID(id int) (*models.Category, error) {
 
 // Create adds a new category and returns it
 func (r *categoryRepository) Create(category models.Category) (*models.Category, error) {
-	query := `INSERT INTO categories (name, description) VALUES ($1, $2) RETURNING id, name, description, created_at, updated_at`
+	query := `INSERT INTO categories (name, description, parent_id) VALUES ($1, $2, $3) RETURNING id, name, description, parent_id, archived_at, created_at, updated_at`
 	var cat models.Category
-	err := r.db.QueryRow(query, category.Name, category.Description).Scan(
-		&cat.ID, &cat.Name, &cat.Description, &cat.CreatedAt, &cat.UpdatedAt,
+	err := r.db.QueryRow(query, category.Name, category.Description, category.ParentID).Scan(
+		&cat.ID, &cat.Name, &cat.Description, &cat.ParentID, &cat.ArchivedAt, &cat.CreatedAt, &cat.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -78,12 +81,29 @@ func (r *categoryRepository) Create(category models.Category) (*models.Category,
 	return &cat, nil
 }
 
-// Update modifies an existing category
+// Update modifies an existing category. When it gets a parent, the move is
+// checked under lock so that concurrent moves cannot form a cycle.
 func (r *categoryRepository) Update(id int, category models.Category) (*models.Category, error) {
-	query := `UPDATE categories SET name = $1, description = $2, updated_at = $3 WHERE id = $4 RETURNING id, name, description, created_at, updated_at`
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if category.ParentID != nil {
+		inSubtree, err := lockForMove(tx, id, *category.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if inSubtree {
+			return nil, helpers.NewValidationError("a category cannot be moved under itself or one of its own descendants")
+		}
+	}
+
+	query := `UPDATE categories SET name = $1, description = $2, parent_id = $3, updated_at = $4 WHERE id = $5 RETURNING id, name, description, parent_id, archived_at, created_at, updated_at`
 	var cat models.Category
-	err := r.db.QueryRow(query, category.Name, category.Description, time.Now(), id).Scan(
-		&cat.ID, &cat.Name, &cat.Description, &cat.CreatedAt, &cat.UpdatedAt,
+	err = tx.QueryRow(query, category.Name, category.Description, category.ParentID, time.Now(), id).Scan(
+		&cat.ID, &cat.Name, &cat.Description, &cat.ParentID, &cat.ArchivedAt, &cat.CreatedAt, &cat.UpdatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -91,25 +111,167 @@ func (r *categoryRepository) Update(id int, category models.Category) (*models.C
 		}
 		return nil, err
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &cat, nil
 }
 
-// Delete removes a category by its ID
-func (r *categoryRepository) Delete(id int) error {
-	query := `DELETE FROM categories WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+// lockForMove locks category id, every category below it and parentID, then
+// reports whether parentID lies in id's subtree. Any two moves that could
+// together form a cycle share a locked row, so the second waits for the
+// first and checks the tree as it committed it. Rows are locked in ID order
+// so such moves queue instead of deadlocking.
+func lockForMove(tx *sql.Tx, id, parentID int) (bool, error) {
+	rows, err := tx.Query(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT id FROM categories
+		WHERE id IN (SELECT id FROM subtree) OR id = $2
+		ORDER BY id
+		FOR UPDATE
+	`, id, parentID)
+	if err != nil {
+		return false, err
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	var inSubtree bool
+	err = tx.QueryRow(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT EXISTS (SELECT 1 FROM subtree WHERE id = $2)
+	`, id, parentID).Scan(&inSubtree)
+	return inSubtree, err
+}
+
+// Delete removes a category that has no products or subcategories. When it
+// has any, nothing is deleted and their counts are returned. The category row
+// is locked before counting, which blocks products and subcategories from
+// being pointed at it until the delete commits.
+func (r *categoryRepository) Delete(id int) (products int, children int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow(`SELECT id FROM categories WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	query := `
+		SELECT (SELECT COUNT(*) FROM products WHERE category_id = $1),
+		       (SELECT COUNT(*) FROM categories WHERE parent_id = $1)
+	`
+	if err := tx.QueryRow(query, id).Scan(&products, &children); err != nil {
+		return 0, 0, err
+	}
+	if products > 0 || children > 0 {
+		return products, children, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM categories WHERE id = $1`, id); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, tx.Commit()
+}
+
+// DeleteReassigning moves the products and direct subcategories of a
+// category to targetID and deletes it, all in one transaction. Like a move
+// in Update, the target is checked to lie outside the category's subtree
+// under lock.
+func (r *categoryRepository) DeleteReassigning(id, targetID int) (products int, children int, err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	inSubtree, err := lockForMove(tx, id, targetID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if inSubtree {
+		return 0, 0, helpers.NewValidationError("reassign_to must not be the category itself or one of its subcategories")
+	}
+
+	result, err := tx.Exec(`UPDATE products SET category_id = $1, updated_at = $2 WHERE category_id = $3`, targetID, time.Now(), id)
+	if err != nil {
+		return 0, 0, err
+	}
+	moved, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`UPDATE categories SET parent_id = $1, updated_at = $2 WHERE parent_id = $3`, targetID, time.Now(), id)
+	if err != nil {
+		return 0, 0, err
+	}
+	movedChildren, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`DELETE FROM categories WHERE id = $1`, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return 0, 0, sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return int(moved), int(movedChildren), nil
+}
+
+// ArchiveSubtree archives a category and all categories below it and
+// deactivates their products, all in one transaction. Products keep their
+// category so historical reports stay accurate.
+func (r *categoryRepository) ArchiveSubtree(id int) (categories int, products int, err error) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
-	
-	rowsAffected, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	subtree := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+	`
+	now := time.Now()
+
+	result, err := tx.Exec(subtree+`
+		UPDATE categories SET archived_at = $2, updated_at = $2
+		WHERE id IN (SELECT id FROM subtree) AND archived_at IS NULL
+	`, id, now)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
-	
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	archived, _ := result.RowsAffected()
+
+	result, err = tx.Exec(subtree+`
+		UPDATE products SET is_active = false, updated_at = $2
+		WHERE category_id IN (SELECT id FROM subtree) AND is_active = true
+	`, id, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	deactivated, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
 	}
-	
-	return nil
+	return int(archived), int(deactivated), nil
 }
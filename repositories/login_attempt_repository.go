@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"time"
+)
+
+// LoginAttemptRepository defines the interface for login attempt data access
+type LoginAttemptRepository interface {
+	Create(attempt models.LoginAttempt) error
+	RecentFailureByIP(ip string, since time.Time, n int) (*time.Time, error)
+	GetAll(params models.LoginAttemptListParams) (*models.PaginatedLoginAttempts, error)
+}
+
+// loginAttemptRepository implements LoginAttemptRepository interface with PostgreSQL
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository creates a new login attempt repository instance
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// Create records a login attempt
+func (r *loginAttemptRepository) Create(attempt models.LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (email, user_id, result, ip, user_agent, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(query, attempt.Email, attempt.UserID, attempt.Result, attempt.IP, attempt.UserAgent, attempt.RequestID)
+	return err
+}
+
+// RecentFailureByIP returns the time of the n-th most recent failed
+// password or second-factor check from ip since the given time, or nil when
+// there were fewer than n. Throttled and locked attempts are not counted, so
+// the window drains even while a client keeps retrying.
+func (r *loginAttemptRepository) RecentFailureByIP(ip string, since time.Time, n int) (*time.Time, error) {
+	query := `
+		SELECT created_at FROM login_attempts
+		WHERE ip = $1 AND result IN ($2, $3) AND created_at > $4
+		ORDER BY created_at DESC
+		OFFSET $5 LIMIT 1
+	`
+	var at time.Time
+	err := r.db.QueryRow(
+		query, ip, models.LoginResultInvalidCredentials, models.LoginResultInvalidSecondFactor, since.UTC(), n-1,
+	).Scan(&at)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
+
+// GetAll returns paginated login attempts, newest first, with optional filters
+func (r *loginAttemptRepository) GetAll(params models.LoginAttemptListParams) (*models.PaginatedLoginAttempts, error) {
+	if params.Page <= 0 {
+		params.Page = helpers.DefaultPage
+	}
+	if params.Limit <= 0 {
+		params.Limit = helpers.DefaultLimit
+	}
+
+	// Build WHERE clause
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	argIdx := 1
+
+	if params.Email != "" {
+		where += fmt.Sprintf(" AND LOWER(l.email) = LOWER($%d)", argIdx)
+		args = append(args, params.Email)
+		argIdx++
+	}
+	if params.UserID != nil {
+		where += fmt.Sprintf(" AND l.user_id = $%d", argIdx)
+		args = append(args, *params.UserID)
+		argIdx++
+	}
+	if params.IP != "" {
+		where += fmt.Sprintf(" AND l.ip = $%d", argIdx)
+		args = append(args, params.IP)
+		argIdx++
+	}
+	if params.Result != "" {
+		where += fmt.Sprintf(" AND l.result = $%d", argIdx)
+		args = append(args, params.Result)
+		argIdx++
+	}
+	if params.StartDate != "" {
+		where += fmt.Sprintf(" AND l.created_at::date >= $%d::date", argIdx)
+		args = append(args, params.StartDate)
+		argIdx++
+	}
+	if params.EndDate != "" {
+		where += fmt.Sprintf(" AND l.created_at::date <= $%d::date", argIdx)
+		args = append(args, params.EndDate)
+		argIdx++
+	}
+
+	// Count total
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM login_attempts l"+where, args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	// Fetch page
+	offset := (params.Page - 1) * params.Limit
+	query := fmt.Sprintf(`
+		SELECT l.id, l.email, l.user_id, l.result, l.ip, l.user_agent, l.request_id, l.created_at
+		FROM login_attempts l
+		%s
+		ORDER BY l.created_at DESC, l.id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, argIdx, argIdx+1)
+	args = append(args, params.Limit, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]models.LoginAttempt, 0)
+	for rows.Next() {
+		var a models.LoginAttempt
+		err := rows.Scan(&a.ID, &a.Email, &a.UserID, &a.Result, &a.IP, &a.UserAgent, &a.RequestID, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedLoginAttempts{
+		Data:       attempts,
+		Total:      total,
+		Page:       params.Page,
+		Limit:      params.Limit,
+		TotalPages: helpers.CalcTotalPages(total, params.Limit),
+	}, nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// PasswordResetRepository defines the interface for password reset token data access
+type PasswordResetRepository interface {
+	Create(userID int, tokenHash string, expiresAt time.Time) error
+	Consume(tokenHash string) (*int, error)
+	InvalidateForUser(userID int) error
+	RecordRequest(email, ip string) error
+	RecentRequestByEmail(email string, since time.Time, n int) (*time.Time, error)
+	RecentRequestByIP(ip string, since time.Time, n int) (*time.Time, error)
+}
+
+// passwordResetRepository implements PasswordResetRepository interface with PostgreSQL
+type passwordResetRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository creates a new password reset repository instance
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// Create stores a reset token identified by its hash
+func (r *passwordResetRepository) Create(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(
+		`INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt.UTC(),
+	)
+	return err
+}
+
+// Consume atomically marks an unused, unexpired token as used and returns
+// the ID of its user. It returns nil when no token matches.
+func (r *passwordResetRepository) Consume(tokenHash string) (*int, error) {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id
+	`
+	var userID int
+	err := r.db.QueryRow(query, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &userID, nil
+}
+
+// InvalidateForUser marks every outstanding token of a user as used
+func (r *passwordResetRepository) InvalidateForUser(userID int) error {
+	_, err := r.db.Exec(
+		`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`,
+		userID,
+	)
+	return err
+}
+
+// RecordRequest stores a reset link request, whether or not the email
+// belongs to an account. Emails are compared case-insensitively.
+func (r *passwordResetRepository) RecordRequest(email, ip string) error {
+	_, err := r.db.Exec(
+		`INSERT INTO password_reset_requests (email, ip) VALUES ($1, $2)`,
+		strings.ToLower(email), ip,
+	)
+	return err
+}
+
+// RecentRequestByEmail returns the time of the n-th most recent reset
+// request for email since the given time, or nil when there were fewer than n
+func (r *passwordResetRepository) RecentRequestByEmail(email string, since time.Time, n int) (*time.Time, error) {
+	return r.nthRecentRequest("email", strings.ToLower(email), since, n)
+}
+
+// RecentRequestByIP returns the time of the n-th most recent reset request
+// from ip since the given time, or nil when there were fewer than n
+func (r *passwordResetRepository) RecentRequestByIP(ip string, since time.Time, n int) (*time.Time, error) {
+	return r.nthRecentRequest("ip", ip, since, n)
+}
+
+// nthRecentRequest backs RecentRequestByEmail and RecentRequestByIP. column
+// is never user input.
+func (r *passwordResetRepository) nthRecentRequest(column, value string, since time.Time, n int) (*time.Time, error) {
+	query := `
+		SELECT created_at FROM password_reset_requests
+		WHERE ` + column + ` = $1 AND created_at > $2
+		ORDER BY created_at DESC
+		OFFSET $3 LIMIT 1
+	`
+	var at time.Time
+	err := r.db.QueryRow(query, value, since.UTC(), n-1).Scan(&at)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"time"
+)
+
+// ProductImageRepository defines data access for product images
+type ProductImageRepository interface {
+	GetByProductID(productID int) ([]models.ProductImage, error)
+	GetByID(productID, imageID int) (*models.ProductImage, error)
+	Create(image models.ProductImage, imageURL string) (*models.ProductImage, error)
+	SetPrimary(productID, imageID int, imageURL string) error
+	Delete(productID, imageID int, imageURL func(storageKey string) string) (*models.ProductImage, error)
+}
+
+// productImageRepository implements ProductImageRepository with PostgreSQL
+type productImageRepository struct {
+	db *sql.DB
+}
+
+// NewProductImageRepository creates a new product image repository instance
+func NewProductImageRepository(db *sql.DB) ProductImageRepository {
+	return &productImageRepository{db: db}
+}
+
+// productImageColumns are the columns scanned by scanProductImage
+const productImageColumns = `id, product_id, storage_key, thumbnail_key, content_type,
+	size_bytes, width, height, is_primary, created_at`
+
+// scanProductImage scans a row of productImageColumns
+func scanProductImage(scanner rowScanner) (*models.ProductImage, error) {
+	var img models.ProductImage
+	err := scanner.Scan(&img.ID, &img.ProductID, &img.StorageKey, &img.ThumbnailKey, &img.ContentType,
+		&img.Size, &img.Width, &img.Height, &img.IsPrimary, &img.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &img, nil
+}
+
+// GetByProductID returns the images of a product, primary first, then in
+// upload order
+func (r *productImageRepository) GetByProductID(productID int) ([]models.ProductImage, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM product_images
+		WHERE product_id = $1
+		ORDER BY is_primary DESC, id
+	`, productImageColumns)
+
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := make([]models.ProductImage, 0)
+	for rows.Next() {
+		img, err := scanProductImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, *img)
+	}
+	return images, rows.Err()
+}
+
+// GetByID returns an image of a product, or nil if there is none
+func (r *productImageRepository) GetByID(productID, imageID int) (*models.ProductImage, error) {
+	query := fmt.Sprintf(`SELECT %s FROM product_images WHERE id = $1 AND product_id = $2`, productImageColumns)
+	img, err := scanProductImage(r.db.QueryRow(query, imageID, productID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return img, err
+}
+
+// Create records an uploaded image. It becomes the primary image when
+// image.IsPrimary is set or the product has none yet, in which case the
+// product's image_url is set to imageURL. The product row is locked so
+// concurrent uploads cannot exceed models.MaxProductImages.
+func (r *productImageRepository) Create(image models.ProductImage, imageURL string) (*models.ProductImage, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`SELECT id FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, image.ProductID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, helpers.NewNotFoundError("product not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	var hasPrimary bool
+	err = tx.QueryRow(`
+		SELECT COUNT(*), COALESCE(bool_or(is_primary), false)
+		FROM product_images WHERE product_id = $1
+	`, image.ProductID).Scan(&count, &hasPrimary)
+	if err != nil {
+		return nil, err
+	}
+	if count >= models.MaxProductImages {
+		return nil, helpers.NewConflictError(fmt.Sprintf("product already has %d images; delete one first", models.MaxProductImages))
+	}
+
+	image.IsPrimary = image.IsPrimary || !hasPrimary
+	if image.IsPrimary && hasPrimary {
+		if _, err := tx.Exec(`UPDATE product_images SET is_primary = false WHERE product_id = $1 AND is_primary`, image.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO product_images (product_id, storage_key, thumbnail_key, content_type, size_bytes, width, height, is_primary)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING %s
+	`, productImageColumns)
+	created, err := scanProductImage(tx.QueryRow(query, image.ProductID, image.StorageKey, image.ThumbnailKey,
+		image.ContentType, image.Size, image.Width, image.Height, image.IsPrimary))
+	if err != nil {
+		return nil, err
+	}
+
+	if created.IsPrimary {
+		if err := setProductImageURL(tx, image.ProductID, imageURL); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// SetPrimary makes an image the product's primary one and mirrors imageURL
+// into the product's image_url. It returns sql.ErrNoRows if the image does
+// not belong to the product.
+func (r *productImageRepository) SetPrimary(productID, imageID int, imageURL string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE product_images SET is_primary = false WHERE product_id = $1 AND is_primary AND id <> $2`, productID, imageID)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(`UPDATE product_images SET is_primary = true WHERE id = $1 AND product_id = $2`, imageID, productID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := setProductImageURL(tx, productID, imageURL); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete removes an image record. When it was the primary image the oldest
+// remaining image is promoted and the product's image_url set to
+// imageURL(its storage key), or cleared when no image is left, in the same
+// transaction. It returns sql.ErrNoRows if the image does not belong to the
+// product.
+func (r *productImageRepository) Delete(productID, imageID int, imageURL func(storageKey string) string) (*models.ProductImage, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`DELETE FROM product_images WHERE id = $1 AND product_id = $2 RETURNING %s`, productImageColumns)
+	deleted, err := scanProductImage(tx.QueryRow(query, imageID, productID))
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted.IsPrimary {
+		query := fmt.Sprintf(`
+			UPDATE product_images SET is_primary = true
+			WHERE id = (SELECT id FROM product_images WHERE product_id = $1 ORDER BY id LIMIT 1)
+			RETURNING %s
+		`, productImageColumns)
+		promoted, err := scanProductImage(tx.QueryRow(query, productID))
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		url := ""
+		if promoted != nil {
+			url = imageURL(promoted.StorageKey)
+		}
+		if err := setProductImageURL(tx, productID, url); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// setProductImageURL sets a product's image_url, the URL of its primary
+// image, inside a transaction
+func setProductImageURL(tx *sql.Tx, productID int, imageURL string) error {
+	_, err := tx.Exec(`UPDATE products SET image_url = $1, updated_at = $2 WHERE id = $3`, imageURL, time.Now(), productID)
+	return err
+}
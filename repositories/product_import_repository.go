@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"retail-core-api/models"
+	"strings"
+	"time"
+)
+
+// ProductImportRepository defines data access for bulk product imports
+type ProductImportRepository interface {
+	GetBySKUs(skus []string) (map[string][]models.Product, error)
+	ApplyBatch(rows []models.ProductImportRow) ([]models.ProductImportChange, error)
+}
+
+// productImportRepository implements ProductImportRepository with PostgreSQL
+type productImportRepository struct {
+	db *sql.DB
+}
+
+// NewProductImportRepository creates a new product import repository instance
+func NewProductImportRepository(db *sql.DB) ProductImportRepository {
+	return &productImportRepository{db: db}
+}
+
+// GetBySKUs returns the products, archived ones included, whose SKU is one
+// of skus, grouped by SKU
+func (r *productImportRepository) GetBySKUs(skus []string) (map[string][]models.Product, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		WHERE p.sku = ANY($1)
+		ORDER BY p.id
+	`, productColumns)
+
+	rows, err := r.db.Query(query, skus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make(map[string][]models.Product)
+	for rows.Next() {
+		prod, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products[prod.SKU] = append(products[prod.SKU], *prod)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// ApplyBatch writes rows in one transaction and returns each product as it
+// was before and after its row. Categories named by a row are matched
+// case-insensitively among non-archived categories and created as root
+// categories when missing. An update locks the product and writes only the
+// fields its row has a value for, so concurrent changes to the others, such
+// as stock taken by a checkout, are kept.
+func (r *productImportRepository) ApplyBatch(rows []models.ProductImportRow) ([]models.ProductImportChange, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	categoryIDs := make(map[string]int)
+	changes := make([]models.ProductImportChange, 0, len(rows))
+	for _, row := range rows {
+		var categoryID *int
+		if row.CategoryName != "" {
+			key := strings.ToLower(row.CategoryName)
+			id, ok := categoryIDs[key]
+			if !ok {
+				if id, err = findOrCreateCategory(tx, row.CategoryName); err != nil {
+					return nil, fmt.Errorf("row %d: %w", row.Row, err)
+				}
+				categoryIDs[key] = id
+			}
+			categoryID = &id
+		}
+
+		change := models.ProductImportChange{Row: row.Row}
+		var id int
+		if row.ProductID == 0 {
+			prod := row.Product
+			prod.CategoryID = categoryID
+			err = tx.QueryRow(`
+				INSERT INTO products (name, price, stock, sku, unit, is_active, category_id)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id
+			`, prod.Name, prod.Price, prod.Stock, prod.SKU, prod.Unit, prod.IsActive, prod.CategoryID).Scan(&id)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row.Row, err)
+			}
+		} else {
+			before, err := getImportProduct(tx, row.ProductID, true)
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("row %d: product %d was archived or removed during the import", row.Row, row.ProductID)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row.Row, err)
+			}
+			change.Before = before
+
+			prod := mergeImportFields(*before, row.Product, row.Fields)
+			if categoryID != nil {
+				prod.CategoryID = categoryID
+			}
+			_, err = tx.Exec(`
+				UPDATE products
+				SET name = $1, price = $2, stock = $3,
+				    unit = $4, is_active = $5, category_id = $6, updated_at = $7
+				WHERE id = $8
+			`, prod.Name, prod.Price, prod.Stock, prod.Unit, prod.IsActive, prod.CategoryID, now, row.ProductID)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", row.Row, err)
+			}
+			id = row.ProductID
+		}
+
+		if change.After, err = getImportProduct(tx, id, false); err != nil {
+			return nil, fmt.Errorf("row %d: %w", row.Row, err)
+		}
+		changes = append(changes, change)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// getImportProduct reads a non-archived product inside the import
+// transaction, locking its row when lock is set
+func getImportProduct(tx *sql.Tx, id int, lock bool) (*models.Product, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		WHERE p.id = $1 AND p.deleted_at IS NULL
+	`, productColumns)
+	if lock {
+		query += " FOR UPDATE OF p"
+	}
+	return scanProduct(tx.QueryRow(query, id))
+}
+
+// mergeImportFields returns current with the fields listed in fields taken
+// from row
+func mergeImportFields(current, row models.Product, fields []string) models.Product {
+	for _, field := range fields {
+		switch field {
+		case models.ImportFieldName:
+			current.Name = row.Name
+		case models.ImportFieldPrice:
+			current.Price = row.Price
+		case models.ImportFieldStock:
+			current.Stock = row.Stock
+		case models.ImportFieldUnit:
+			current.Unit = row.Unit
+		case models.ImportFieldIsActive:
+			current.IsActive = row.IsActive
+		}
+	}
+	return current
+}
+
+// findOrCreateCategory returns the ID of the non-archived category called
+// name, creating it as a root category if there is none
+func findOrCreateCategory(tx *sql.Tx, name string) (int, error) {
+	var id int
+	err := tx.QueryRow(`
+		SELECT id FROM categories
+		WHERE lower(name) = lower($1) AND archived_at IS NULL
+		ORDER BY id
+		LIMIT 1
+	`, name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	err = tx.QueryRow(`INSERT INTO categories (name, description) VALUES ($1, '') RETURNING id`, name).Scan(&id)
+	return id, err
+}
@@ -5,17 +5,23 @@ import (
 	"fmt"
 	"math"
 	"retail-core-api/models"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
 )
 
 // ProductRepository defines the interface for product data access
 type ProductRepository interface {
 	GetAll(params models.ProductListParams) (*models.PaginatedProducts, error)
+	Each(params models.ProductListParams, fn func(models.Product) error) error
+	Autocomplete(term string, limit int) ([]models.ProductSuggestion, error)
 	GetByID(id int) (*models.Product, error)
-	GetByCategoryID(categoryID int) ([]models.Product, error)
+	GetByCategoryID(categoryID int, includeInactive bool) ([]models.Product, error)
 	Create(product models.Product) (*models.Product, error)
 	Update(id int, product models.Product) (*models.Product, error)
 	Delete(id int) error
+	Restore(id int) error
 }
 
 // productRepository implements ProductRepository interface with PostgreSQL
@@ -34,7 +40,7 @@ const productColumns = `
 	p.sku, p.image_url, p.unit, p.is_active,
 	p.category_id,
 	COALESCE(c.name, '') as category_name,
-	p.created_at, p.updated_at
+	p.deleted_at, p.created_at, p.updated_at
 `
 
 // scanProduct scans a row into a Product struct
@@ -51,6 +57,7 @@ func scanProduct(scanner interface{ Scan(dest ...interface{}) error }) (*models.
 		&prod.IsActive,
 		&prod.CategoryID,
 		&prod.CategoryName,
+		&prod.DeletedAt,
 		&prod.CreatedAt,
 		&prod.UpdatedAt,
 	)
@@ -60,24 +67,207 @@ func scanProduct(scanner interface{ Scan(dest ...interface{}) error }) (*models.
 	return &prod, nil
 }
 
-// GetAll returns paginated products with optional search and category filter
+// GetAll returns paginated products with optional search and category
+// filter, ordered by relevance when searching. Inactive and archived
+// products are left out unless IncludeInactive or IncludeDeleted is set.
+// With a cursor the page is found by keyset instead of OFFSET; either way
+// the result carries cursors for the neighbouring pages.
 func (r *productRepository) GetAll(params models.ProductListParams) (*models.PaginatedProducts, error) {
 	// Defaults
-	if params.Page <= 0 {
+	if params.Page <= 0 || params.Cursor != nil {
 		params.Page = 1
 	}
 	if params.Limit <= 0 {
 		params.Limit = 20
 	}
 
-	// Build WHERE clause
+	where, keys, args := productFilter(params)
+	signature := sortSignature(keys, params.Search)
+	result := &models.PaginatedProducts{Limit: params.Limit}
+
+	// Count total
+	if !params.SkipTotal {
+		countQuery := "SELECT COUNT(*) FROM products p LEFT JOIN categories c ON p.category_id = c.id" + where
+		var total int
+		if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		totalPages := int(math.Ceil(float64(total) / float64(params.Limit)))
+		result.Total, result.TotalPages = &total, &totalPages
+	}
+
+	// Fetch page, plus one row to tell whether another follows
+	readKeys := keys
+	offset := (params.Page - 1) * params.Limit
+	if cur := params.Cursor; cur != nil {
+		if err := checkCursor(cur, keys, signature); err != nil {
+			return nil, err
+		}
+		if cur.Before {
+			readKeys = reverseKeys(keys)
+		}
+		cond, keyArgs := keysetFilter(readKeys, cur, len(args)+1)
+		where += " AND " + cond
+		args = append(args, keyArgs...)
+	} else {
+		result.Page = params.Page
+	}
+	argIdx := len(args) + 1
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		%s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d
+	`, productColumns, cursorColumns(keys), where, orderClause(readKeys), argIdx, argIdx+1)
+	args = append(args, params.Limit+1, offset)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]models.Product, 0, params.Limit+1)
+	rowKeys := make([][]string, 0, params.Limit+1)
+	for rows.Next() {
+		scanner := newKeyScanner(rows, len(keys))
+		prod, err := scanProduct(scanner)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *prod)
+		rowKeys = append(rowKeys, scanner.keys)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasMore := len(products) > params.Limit
+	if hasMore {
+		products, rowKeys = products[:params.Limit], rowKeys[:params.Limit]
+	}
+	before := params.Cursor != nil && params.Cursor.Before
+	if before {
+		slices.Reverse(products)
+		slices.Reverse(rowKeys)
+	}
+
+	result.Data = products
+	result.NextCursor, result.PrevCursor = pageCursors(rowKeys, signature, before, hasMore, params.Cursor != nil || params.Page > 1)
+	return result, nil
+}
+
+// Each calls fn for every product matching the filters of params, ignoring
+// pagination. Rows are read from the database as fn consumes them.
+func (r *productRepository) Each(params models.ProductListParams, fn func(models.Product) error) error {
+	where, keys, args := productFilter(params)
+	order := orderClause(keys)
+	if params.Search == "" && len(params.Sort) == 0 {
+		order = "p.id"
+	}
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		%s
+		ORDER BY %s
+	`, productColumns, where, order)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		prod, err := scanProduct(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(*prod); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// productSortColumns maps the sortable product fields to SQL expressions.
+// units_sold counts quantities of non-voided sales.
+var productSortColumns = map[string]sortColumn{
+	"id":         {"p.id", "integer"},
+	"name":       {"p.name", "varchar"},
+	"price":      {"p.price", "integer"},
+	"stock":      {"p.stock", "integer"},
+	"created_at": {"p.created_at", "timestamp"},
+	"updated_at": {"p.updated_at", "timestamp"},
+	"units_sold": {`(SELECT COALESCE(SUM(td.quantity), 0)
+		FROM transaction_details td JOIN transactions t ON t.id = td.transaction_id
+		WHERE td.product_id = p.id AND t.status = 'active')`, "bigint"},
+}
+
+// productTieBreaker ends every product ordering so it is total
+var productTieBreaker = sortKey{sortColumn{"p.id", "integer"}, true}
+
+// productFilter builds the WHERE clause, ORDER BY keys and arguments for the
+// filters of a product listing. An explicit sort wins; otherwise searches
+// are ranked by relevance and plain listings show newest first.
+func productFilter(params models.ProductListParams) (string, []sortKey, []interface{}) {
 	where := " WHERE 1=1"
+	keys := []sortKey{productTieBreaker}
 	args := []interface{}{}
 	argIdx := 1
 
 	if params.Search != "" {
-		where += fmt.Sprintf(" AND p.name ILIKE $%d", argIdx)
-		args = append(args, "%"+params.Search+"%")
+		cond, rank, searchArgs := productSearch(params.Search, argIdx)
+		where += " AND " + cond
+		keys = []sortKey{{sortColumn{rank + "::float8", "float8"}, true}, productTieBreaker}
+		args = append(args, searchArgs...)
+		argIdx += len(searchArgs)
+	}
+	if len(params.Sort) > 0 {
+		keys = sortKeys(params.Sort, productSortColumns, productTieBreaker)
+	}
+
+	ranges := []struct {
+		cond  string
+		value *int
+	}{
+		{"p.price >= $%d", params.MinPrice},
+		{"p.price <= $%d", params.MaxPrice},
+		{"p.stock >= $%d", params.MinStock},
+		{"p.stock <= $%d", params.MaxStock},
+	}
+	for _, r := range ranges {
+		if r.value != nil {
+			where += " AND " + fmt.Sprintf(r.cond, argIdx)
+			args = append(args, *r.value)
+			argIdx++
+		}
+	}
+
+	if params.LowStock {
+		where += fmt.Sprintf(" AND p.stock < $%d", argIdx)
+		args = append(args, models.LowStockThreshold)
+		argIdx++
+	}
+
+	if params.IsActive != nil {
+		where += fmt.Sprintf(" AND p.is_active = $%d", argIdx)
+		args = append(args, *params.IsActive)
+		argIdx++
+	}
+
+	if params.Unit != "" {
+		where += fmt.Sprintf(" AND lower(p.unit) = lower($%d)", argIdx)
+		args = append(args, params.Unit)
+		argIdx++
+	}
+
+	if params.UpdatedSince != nil {
+		where += fmt.Sprintf(" AND p.updated_at >= $%d", argIdx)
+		args = append(args, *params.UpdatedSince)
 		argIdx++
 	}
 
@@ -87,55 +277,107 @@ func (r *productRepository) GetAll(params models.ProductListParams) (*models.Pag
 		argIdx++
 	}
 
-	// Count total
-	countQuery := "SELECT COUNT(*) FROM products p" + where
-	var total int
-	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
-		return nil, err
+	if !params.IncludeInactive && params.IsActive == nil {
+		where += " AND p.is_active"
 	}
 
-	// Fetch page
-	offset := (params.Page - 1) * params.Limit
+	if !params.IncludeDeleted {
+		where += " AND p.deleted_at IS NULL"
+	}
+
+	return where, keys, args
+}
+
+// productSearchDocument is the full-text document of a product. It must match
+// the expression of the idx_products_search index.
+const productSearchDocument = `to_tsvector('simple', p.name || ' ' || COALESCE(p.sku, ''))`
+
+// productSearch returns the condition and relevance expression matching term
+// against products p and their categories c, with placeholders numbered
+// from argIdx. Whole and partial words match through full-text search
+// (prefix queries), typos through trigram word similarity on the name, and
+// SKUs by prefix. Exact SKU matches rank first.
+func productSearch(term string, argIdx int) (cond string, rank string, args []interface{}) {
+	q := fmt.Sprintf("$%d", argIdx)
+	prefix := fmt.Sprintf("$%d", argIdx+1)
+	args = append(args, term, escapeLike(term)+"%")
+
+	conds := []string{
+		fmt.Sprintf("%s <%% p.name", q),
+		fmt.Sprintf("p.sku ILIKE %s", prefix),
+	}
+	ranks := []string{
+		fmt.Sprintf("word_similarity(%s, p.name)", q),
+		fmt.Sprintf("CASE WHEN lower(p.sku) = lower(%s) THEN 2 ELSE 0 END", q),
+	}
+
+	if tsq := prefixTSQuery(term); tsq != "" {
+		query := fmt.Sprintf("to_tsquery('simple', $%d)", argIdx+2)
+		args = append(args, tsq)
+		conds = append(conds,
+			fmt.Sprintf("%s @@ %s", productSearchDocument, query),
+			fmt.Sprintf("to_tsvector('simple', COALESCE(c.name, '')) @@ %s", query),
+		)
+		ranks = append(ranks, fmt.Sprintf("ts_rank(%s, %s)", productSearchDocument, query))
+	}
+
+	return "(" + strings.Join(conds, " OR ") + ")", "(" + strings.Join(ranks, " + ") + ")", args
+}
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike returns term with LIKE metacharacters escaped, so that it only
+// matches itself in a LIKE pattern
+func escapeLike(term string) string {
+	return likeEscaper.Replace(term)
+}
+
+// prefixTSQuery turns free text into a tsquery that requires every word, each
+// matched as a prefix so results appear while the user is still typing.
+// Punctuation is dropped so user input cannot break the query syntax.
+func prefixTSQuery(term string) string {
+	words := strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// Autocomplete returns up to limit active products best matching term, for
+// search-as-you-type
+func (r *productRepository) Autocomplete(term string, limit int) ([]models.ProductSuggestion, error) {
+	cond, rank, args := productSearch(term, 1)
 	query := fmt.Sprintf(`
-		SELECT %s
+		SELECT p.id, p.name, p.sku, p.price, p.stock
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
-		%s
-		ORDER BY p.id DESC
-		LIMIT $%d OFFSET $%d
-	`, productColumns, where, argIdx, argIdx+1)
-	args = append(args, params.Limit, offset)
+		WHERE %s AND p.is_active AND p.deleted_at IS NULL
+		ORDER BY %s DESC, p.name
+		LIMIT $%d
+	`, cond, rank, len(args)+1)
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.Query(query, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var products []models.Product
+	suggestions := make([]models.ProductSuggestion, 0, limit)
 	for rows.Next() {
-		prod, err := scanProduct(rows)
-		if err != nil {
+		var s models.ProductSuggestion
+		if err := rows.Scan(&s.ID, &s.Name, &s.SKU, &s.Price, &s.Stock); err != nil {
 			return nil, err
 		}
-		products = append(products, *prod)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, err
+		suggestions = append(suggestions, s)
 	}
-
-	totalPages := int(math.Ceil(float64(total) / float64(params.Limit)))
-
-	return &models.PaginatedProducts{
-		Data:       products,
-		Total:      total,
-		Page:       params.Page,
-		Limit:      params.Limit,
-		TotalPages: totalPages,
-	}, nil
+	return suggestions, rows.Err()
 }
 
-// GetByID returns a product by its ID with category name (LEFT JOIN)
+// GetByID returns a product by its ID with category name (LEFT JOIN),
+// including archived products
 func (r *productRepository) GetByID(id int) (*models.Product, error) {
 	query := fmt.Sprintf(`
 		SELECT %s
@@ -154,18 +396,19 @@ func (r *productRepository) GetByID(id int) (*models.Product, error) {
 	return prod, nil
 }
 
-// Create adds a new product and returns it
+// Create adds a new product and returns it. Its image_url stays empty until
+// an image is uploaded.
 func (r *productRepository) Create(product models.Product) (*models.Product, error) {
 	query := `
-		INSERT INTO products (name, price, stock, sku, image_url, unit, is_active, category_id) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+		INSERT INTO products (name, price, stock, sku, unit, is_active, category_id) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7) 
 		RETURNING id, name, price, stock, sku, image_url, unit, is_active, category_id, created_at, updated_at
 	`
 	var prod models.Product
 	err := r.db.QueryRow(
 		query,
 		product.Name, product.Price, product.Stock,
-		product.SKU, product.ImageURL, product.Unit, product.IsActive,
+		product.SKU, product.Unit, product.IsActive,
 		product.CategoryID,
 	).Scan(
 		&prod.ID, &prod.Name, &prod.Price, &prod.Stock,
@@ -188,20 +431,21 @@ func (r *productRepository) Create(product models.Product) (*models.Product, err
 	return &prod, nil
 }
 
-// Update modifies an existing product
+// Update modifies an existing, non-archived product. image_url is left
+// alone; it mirrors the primary image.
 func (r *productRepository) Update(id int, product models.Product) (*models.Product, error) {
 	query := `
 		UPDATE products 
-		SET name = $1, price = $2, stock = $3, sku = $4, image_url = $5, 
-		    unit = $6, is_active = $7, category_id = $8, updated_at = $9
-		WHERE id = $10 
+		SET name = $1, price = $2, stock = $3, sku = $4,
+		    unit = $5, is_active = $6, category_id = $7, updated_at = $8
+		WHERE id = $9 AND deleted_at IS NULL
 		RETURNING id, name, price, stock, sku, image_url, unit, is_active, category_id, created_at, updated_at
 	`
 	var prod models.Product
 	err := r.db.QueryRow(
 		query,
 		product.Name, product.Price, product.Stock,
-		product.SKU, product.ImageURL, product.Unit, product.IsActive,
+		product.SKU, product.Unit, product.IsActive,
 		product.CategoryID, time.Now(), id,
 	).Scan(
 		&prod.ID, &prod.Name, &prod.Price, &prod.Stock,
@@ -227,10 +471,11 @@ func (r *productRepository) Update(id int, product models.Product) (*models.Prod
 	return &prod, nil
 }
 
-// Delete removes a product by its ID
+// Delete archives a product by its ID. The row is kept so transactions that
+// reference it stay intact.
 func (r *productRepository) Delete(id int) error {
-	query := `DELETE FROM products WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+	query := `UPDATE products SET deleted_at = $1, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+	result, err := r.db.Exec(query, time.Now(), id)
 	if err != nil {
 		return err
 	}
@@ -247,17 +492,25 @@ func (r *productRepository) Delete(id int) error {
 	return nil
 }
 
-// GetByCategoryID returns all products belonging to a specific category
-func (r *productRepository) GetByCategoryID(categoryID int) ([]models.Product, error) {
+// GetByCategoryID returns all non-archived products belonging to a category
+// or to any category below it in the tree. Inactive products are only
+// included when includeInactive is set.
+func (r *productRepository) GetByCategoryID(categoryID int, includeInactive bool) ([]models.Product, error) {
 	query := fmt.Sprintf(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION
+			SELECT ch.id FROM categories ch JOIN subtree s ON ch.parent_id = s.id
+		)
 		SELECT %s
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
-		WHERE p.category_id = $1
+		WHERE p.category_id IN (SELECT id FROM subtree) AND p.deleted_at IS NULL
+		  AND ($2::boolean OR p.is_active)
 		ORDER BY p.id
 	`, productColumns)
 
-	rows, err := r.db.Query(query, categoryID)
+	rows, err := r.db.Query(query, categoryID, includeInactive)
 	if err != nil {
 		return nil, err
 	}
@@ -278,3 +531,23 @@ func (r *productRepository) GetByCategoryID(categoryID int) ([]models.Product, e
 
 	return products, nil
 }
+
+// Restore brings back an archived product
+func (r *productRepository) Restore(id int) error {
+	query := `UPDATE products SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND deleted_at IS NOT NULL`
+	result, err := r.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
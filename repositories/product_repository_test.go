@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct{ term, want string }{
+		{"IDM-GRG", "IDM-GRG"},
+		{"%", `\%`},
+		{"_", `\_`},
+		{`A\B`, `A\\B`},
+		{"50%_off", `50\%\_off`},
+	}
+	for _, tt := range tests {
+		if got := escapeLike(tt.term); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestProductSearchBindsEscapedSKUPrefix(t *testing.T) {
+	cond, _, args := productSearch("%", 3)
+	if !strings.Contains(cond, "p.sku ILIKE $4") {
+		t.Errorf("condition %q does not match the SKU against the prefix argument", cond)
+	}
+	if len(args) < 2 || args[0] != "%" || args[1] != `\%%` {
+		t.Errorf("args = %q, want the raw term then the escaped prefix", args)
+	}
+}
@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"database/sql"
+	"retail-core-api/models"
+	"time"
+)
+
+// RoleRepository defines the interface for role data access
+type RoleRepository interface {
+	GetAll() ([]models.Role, error)
+	GetByID(id int) (*models.Role, error)
+	GetByName(name string) (*models.Role, error)
+	Create(role models.Role) (*models.Role, error)
+	Update(id int, role models.Role) (*models.Role, error)
+	Delete(id int) error
+	CountUsers(name string) (int, error)
+}
+
+// roleRepository implements RoleRepository interface with PostgreSQL
+type roleRepository struct {
+	db *sql.DB
+}
+
+// NewRoleRepository creates a new role repository instance
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// roleColumns is the standard set of columns selected for role queries
+const roleColumns = `id, name, description, is_system, created_at, updated_at`
+
+// scanRole scans a row into a Role struct (without permissions)
+func scanRole(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*models.Role, error) {
+	var role models.Role
+	err := scanner.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// loadPermissions fills in the permissions of the given role
+func (r *roleRepository) loadPermissions(role *models.Role) error {
+	rows, err := r.db.Query(`SELECT permission FROM role_permissions WHERE role_id = $1 ORDER BY permission`, role.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	role.Permissions = make([]string, 0)
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return err
+		}
+		role.Permissions = append(role.Permissions, p)
+	}
+	return rows.Err()
+}
+
+// GetAll returns all roles with their permissions
+func (r *roleRepository) GetAll() ([]models.Role, error) {
+	rows, err := r.db.Query(`SELECT ` + roleColumns + ` FROM roles ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]models.Role, 0)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range roles {
+		if err := r.loadPermissions(&roles[i]); err != nil {
+			return nil, err
+		}
+	}
+	return roles, nil
+}
+
+// GetByID returns a role by its ID
+func (r *roleRepository) GetByID(id int) (*models.Role, error) {
+	role, err := scanRole(r.db.QueryRow(`SELECT `+roleColumns+` FROM roles WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadPermissions(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// GetByName returns a role by its name
+func (r *roleRepository) GetByName(name string) (*models.Role, error) {
+	role, err := scanRole(r.db.QueryRow(`SELECT `+roleColumns+` FROM roles WHERE name = $1`, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadPermissions(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// replacePermissions overwrites the permission set of a role inside tx
+func replacePermissions(tx *sql.Tx, roleID int, permissions []string) error {
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		_, err := tx.Exec(
+			`INSERT INTO role_permissions (role_id, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			roleID, p,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create adds a new custom role with its permissions
+func (r *roleRepository) Create(role models.Role) (*models.Role, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created, err := scanRole(tx.QueryRow(
+		`INSERT INTO roles (name, description, is_system) VALUES ($1, $2, false) RETURNING `+roleColumns,
+		role.Name, role.Description,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := replacePermissions(tx, created.ID, role.Permissions); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	created.Permissions = role.Permissions
+	return created, nil
+}
+
+// Update modifies an existing role and replaces its permissions. Renaming a
+// role also renames it on every user holding it.
+func (r *roleRepository) Update(id int, role models.Role) (*models.Role, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var oldName string
+	err = tx.QueryRow(`SELECT name FROM roles WHERE id = $1 FOR UPDATE`, id).Scan(&oldName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := scanRole(tx.QueryRow(
+		`UPDATE roles SET name = $1, description = $2, updated_at = $3 WHERE id = $4 RETURNING `+roleColumns,
+		role.Name, role.Description, time.Now(), id,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if oldName != role.Name {
+		if _, err := tx.Exec(`UPDATE users SET role = $1 WHERE role = $2`, role.Name, oldName); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := replacePermissions(tx, id, role.Permissions); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	updated.Permissions = role.Permissions
+	return updated, nil
+}
+
+// Delete removes a role by its ID
+func (r *roleRepository) Delete(id int) error {
+	result, err := r.db.Exec(`DELETE FROM roles WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CountUsers returns the number of users assigned to the named role
+func (r *roleRepository) CountUsers(name string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = $1`, name).Scan(&count)
+	return count, err
+}
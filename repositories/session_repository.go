@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"database/sql"
+	"retail-core-api/models"
+)
+
+// SessionRepository defines the interface for login session data access
+type SessionRepository interface {
+	Create(session models.Session) (*models.Session, error)
+	GetActiveByUser(userID int) ([]models.Session, error)
+	IsActive(id, userID int) (bool, error)
+	RevokeByUser(userID int) error
+}
+
+// sessionRepository implements SessionRepository interface with PostgreSQL
+type sessionRepository struct {
+	db *sql.DB
+}
+
+// NewSessionRepository creates a new session repository instance
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Create records a new session
+func (r *sessionRepository) Create(session models.Session) (*models.Session, error) {
+	query := `
+		INSERT INTO user_sessions (user_id, ip, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, ip, user_agent, created_at, expires_at
+	`
+	var created models.Session
+	err := r.db.QueryRow(query, session.UserID, session.IP, session.UserAgent, session.ExpiresAt.UTC()).Scan(
+		&created.ID, &created.UserID, &created.IP, &created.UserAgent, &created.CreatedAt, &created.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetActiveByUser returns the unexpired, unrevoked sessions of a user,
+// newest first
+func (r *sessionRepository) GetActiveByUser(userID int) ([]models.Session, error) {
+	query := `
+		SELECT id, user_id, ip, user_agent, created_at, expires_at
+		FROM user_sessions
+		WHERE user_id = $1 AND expires_at > CURRENT_TIMESTAMP AND revoked_at IS NULL
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.Session, 0)
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.IP, &s.UserAgent, &s.CreatedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// IsActive reports whether session id belongs to userID and is neither
+// expired nor revoked
+func (r *sessionRepository) IsActive(id, userID int) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM user_sessions
+			WHERE id = $1 AND user_id = $2 AND expires_at > CURRENT_TIMESTAMP AND revoked_at IS NULL
+		)
+	`, id, userID).Scan(&active)
+	return active, err
+}
+
+// RevokeByUser revokes every unrevoked session of a user, so the access
+// tokens issued for them stop working
+func (r *sessionRepository) RevokeByUser(userID int) error {
+	_, err := r.db.Exec(`
+		UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
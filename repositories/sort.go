@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// sortColumn is the SQL expression of a sortable field and the type its
+// cursor values are cast back to. Sort columns must never be NULL.
+type sortColumn struct {
+	expr string
+	typ  string
+}
+
+// sortKey is one key of an ORDER BY
+type sortKey struct {
+	sortColumn
+	desc bool
+}
+
+// sortKeys turns parsed sort terms into ORDER BY keys. columns maps each
+// sortable field to its column; fields without one are skipped. tieBreaker,
+// a unique column, is appended so the order is total and pagination stays
+// stable between pages.
+func sortKeys(terms []models.SortTerm, columns map[string]sortColumn, tieBreaker sortKey) []sortKey {
+	keys := make([]sortKey, 0, len(terms)+1)
+	for _, term := range terms {
+		if column, ok := columns[term.Field]; ok {
+			keys = append(keys, sortKey{column, term.Desc})
+		}
+	}
+	return append(keys, tieBreaker)
+}
+
+// orderClause renders keys as an ORDER BY expression
+func orderClause(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		if key.desc {
+			parts[i] = key.expr + " DESC"
+		} else {
+			parts[i] = key.expr + " ASC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// reverseKeys flips the direction of every key, for reading a list backwards
+func reverseKeys(keys []sortKey) []sortKey {
+	reversed := make([]sortKey, len(keys))
+	for i, key := range keys {
+		reversed[i] = sortKey{key.sortColumn, !key.desc}
+	}
+	return reversed
+}
+
+// cursorColumns selects the key values of each row as text, to build cursors
+func cursorColumns(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = "(" + key.expr + ")::text"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortSignature fingerprints an ordering. extra holds anything else the key
+// values depend on, such as the search term behind a relevance rank.
+func sortSignature(keys []sortKey, extra ...string) string {
+	h := fnv.New64a()
+	h.Write([]byte(orderClause(keys)))
+	for _, e := range extra {
+		h.Write([]byte{0})
+		h.Write([]byte(e))
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// keysetFilter returns the condition selecting the rows that come after the
+// cursor in the order of keys, with placeholders numbered from argIdx. For
+// keys (a, b) that is a > $1 OR (a = $1 AND b > $2), with < for descending
+// keys.
+func keysetFilter(keys []sortKey, cur *models.Cursor, argIdx int) (string, []interface{}) {
+	values := make([]string, len(keys))
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = fmt.Sprintf("$%d::text::%s", argIdx+i, key.typ)
+		args[i] = cur.Keys[i]
+	}
+
+	ors := make([]string, len(keys))
+	for i, key := range keys {
+		ands := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j].expr+" = "+values[j])
+		}
+		op := " > "
+		if key.desc {
+			op = " < "
+		}
+		ands = append(ands, key.expr+op+values[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// checkCursor rejects a cursor that was issued for another ordering, or
+// whose key values do not fit their columns' types, so a tampered cursor is
+// a validation error rather than a failed cast in the database
+func checkCursor(cur *models.Cursor, keys []sortKey, signature string) error {
+	if cur.Sort != signature || len(cur.Keys) != len(keys) {
+		return helpers.NewValidationError("cursor does not match this sort order or search; start again without a cursor")
+	}
+	for i, key := range keys {
+		if !validKeyValue(key.typ, cur.Keys[i]) {
+			return helpers.NewValidationError("invalid cursor; start again without a cursor")
+		}
+	}
+	return nil
+}
+
+// pgTimestampLayout parses PostgreSQL's text output of a timestamp, which
+// has up to six fractional digits
+const pgTimestampLayout = "2006-01-02 15:04:05.999999999"
+
+// validKeyValue reports whether v, a key value from a cursor, can be cast to
+// the sort column type typ
+func validKeyValue(typ, v string) bool {
+	var err error
+	switch typ {
+	case "integer":
+		_, err = strconv.ParseInt(v, 10, 32)
+	case "bigint":
+		_, err = strconv.ParseInt(v, 10, 64)
+	case "float8":
+		_, err = strconv.ParseFloat(v, 64)
+	case "timestamp":
+		_, err = time.Parse(pgTimestampLayout, v)
+	case "varchar":
+		return utf8.ValidString(v) && !strings.ContainsRune(v, 0)
+	default:
+		return false
+	}
+	return err == nil
+}
+
+// pageCursors builds the next and previous cursors of a page. rowKeys holds
+// the key values of the page's rows in list order, hasMore whether rows
+// exist beyond the page in the direction it was read, and resumed whether
+// rows exist before it in that direction (a cursor or page > 1 was given).
+func pageCursors(rowKeys [][]string, signature string, before, hasMore, resumed bool) (next, prev string) {
+	if len(rowKeys) == 0 {
+		return "", ""
+	}
+	first := helpers.EncodeCursor(models.Cursor{Keys: rowKeys[0], Before: true, Sort: signature})
+	last := helpers.EncodeCursor(models.Cursor{Keys: rowKeys[len(rowKeys)-1], Sort: signature})
+	if before {
+		hasMore, resumed = resumed, hasMore
+	}
+	if hasMore {
+		next = last
+	}
+	if resumed {
+		prev = first
+	}
+	return next, prev
+}
+
+// rowScanner is satisfied by *sql.Row, *sql.Rows and keyScanner
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// keyScanner scans a row whose own columns are followed by the cursor
+// columns of its sort keys, collecting those into keys
+type keyScanner struct {
+	rows *sql.Rows
+	keys []string
+}
+
+// newKeyScanner prepares a keyScanner for a row with n sort keys
+func newKeyScanner(rows *sql.Rows, n int) *keyScanner {
+	return &keyScanner{rows: rows, keys: make([]string, n)}
+}
+
+// Scan scans the row's own columns into dest and its key columns into keys
+func (s *keyScanner) Scan(dest ...interface{}) error {
+	for i := range s.keys {
+		dest = append(dest, &s.keys[i])
+	}
+	return s.rows.Scan(dest...)
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"testing"
+)
+
+func TestValidKeyValue(t *testing.T) {
+	tests := []struct {
+		typ, value string
+		want       bool
+	}{
+		{"integer", "42", true},
+		{"integer", "-7", true},
+		{"integer", "3000000000", false},
+		{"integer", "4x", false},
+		{"integer", "", false},
+		{"bigint", "3000000000", true},
+		{"bigint", "1e3", false},
+		{"float8", "0.0607927", true},
+		{"float8", "1e-05", true},
+		{"float8", "high", false},
+		{"timestamp", "2026-01-30 12:00:00", true},
+		{"timestamp", "2026-01-30 12:00:00.123456", true},
+		{"timestamp", "2026-01-30T12:00:00Z", false},
+		{"timestamp", "yesterday", false},
+		{"varchar", "Kopi Susu", true},
+		{"varchar", "", true},
+		{"varchar", "a\x00b", false},
+		{"varchar", "\xff", false},
+		{"jsonb", "{}", false},
+	}
+	for _, tt := range tests {
+		if got := validKeyValue(tt.typ, tt.value); got != tt.want {
+			t.Errorf("validKeyValue(%q, %q) = %v, want %v", tt.typ, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCursor(t *testing.T) {
+	keys := []sortKey{{sortColumn{"p.price", "integer"}, false}, productTieBreaker}
+	signature := sortSignature(keys)
+
+	valid := &models.Cursor{Keys: []string{"15000", "12"}, Sort: signature}
+	if err := checkCursor(valid, keys, signature); err != nil {
+		t.Errorf("valid cursor: %v", err)
+	}
+
+	for name, cur := range map[string]*models.Cursor{
+		"other ordering":  {Keys: []string{"15000", "12"}, Sort: "0"},
+		"missing key":     {Keys: []string{"12"}, Sort: signature},
+		"tampered value":  {Keys: []string{"15000'; --", "12"}, Sort: signature},
+		"out of range id": {Keys: []string{"15000", "99999999999"}, Sort: signature},
+	} {
+		if err := checkCursor(cur, keys, signature); !helpers.IsValidation(err) {
+			t.Errorf("%s: error = %v, want validation", name, err)
+		}
+	}
+}
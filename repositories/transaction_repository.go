@@ -3,20 +3,24 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
+	"retail-core-api/helpers"
 	"retail-core-api/models"
+	"slices"
 	"time"
 )
 
 // TransactionRepository defines the interface for transaction data access
 type TransactionRepository interface {
+	CalculateGrossAmount(items []models.CheckoutItem) (int, error)
 	CreateTransaction(req models.CheckoutRequest) (*models.Transaction, error)
-	GetAllTransactions(page, limit int, startDate, endDate string) (*models.PaginatedTransactions, error)
+	GetAllTransactions(params models.TransactionListParams) (*models.PaginatedTransactions, error)
+	EachTransaction(params models.TransactionListParams, fn func(models.TransactionListItem) error) error
 	GetTransactionByID(id int) (*models.Transaction, error)
-	VoidTransaction(id int) error
+	VoidTransaction(id int, approvedBy *int) error
 	GetDashboardStats() (*models.DashboardStats, error)
 	GetDailySalesReport() (*models.SalesReport, error)
 	GetSalesReportByDateRange(startDate, endDate string) (*models.SalesReport, error)
-	GetReportSummary(startDate, endDate string) (*models.ReportSummary, error)
+	GetReportSummary(startDate, endDate string, categoryLevel int) (*models.ReportSummary, error)
 }
 
 // transactionRepository implements TransactionRepository interface
@@ -29,8 +33,32 @@ func NewTransactionRepository(db *sql.DB) TransactionRepository {
 	return &transactionRepository{db: db}
 }
 
+// CalculateGrossAmount returns the amount of the given items at current
+// prices, before discount. Unknown and archived products contribute nothing.
+func (repo *transactionRepository) CalculateGrossAmount(items []models.CheckoutItem) (int, error) {
+	ids := make([]int, len(items))
+	quantities := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+		quantities[i] = item.Quantity
+	}
+
+	var gross int
+	err := repo.db.QueryRow(`
+		SELECT COALESCE(SUM(p.price * x.quantity), 0)
+		FROM unnest($1::int[], $2::int[]) AS x(product_id, quantity)
+		JOIN products p ON p.id = x.product_id AND p.deleted_at IS NULL
+	`, ids, quantities).Scan(&gross)
+	if err != nil {
+		return 0, err
+	}
+	return gross, nil
+}
+
 // CreateTransaction processes a checkout: validates products, deducts stock,
 // creates transaction record and detail rows inside a single DB transaction.
+// Archived products are treated as not found and inactive ones are refused.
+// Each detail row keeps a copy of the product name and SKU at sale time.
 func (repo *transactionRepository) CreateTransaction(req models.CheckoutRequest) (*models.Transaction, error) {
 	tx, err := repo.db.Begin()
 	if err != nil {
@@ -43,22 +71,29 @@ func (repo *transactionRepository) CreateTransaction(req models.CheckoutRequest)
 
 	for _, item := range req.Items {
 		var productPrice, stock int
-		var productName string
+		var productName, productSKU string
+		var isActive bool
 
 		err := tx.QueryRow(
-			"SELECT name, price, stock FROM products WHERE id = $1",
+			"SELECT name, sku, price, stock, is_active FROM products WHERE id = $1 AND deleted_at IS NULL",
 			item.ProductID,
-		).Scan(&productName, &productPrice, &stock)
+		).Scan(&productName, &productSKU, &productPrice, &stock, &isActive)
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("product id %d not found", item.ProductID)
+			return nil, helpers.NewNotFoundError(fmt.Sprintf("product id %d not found", item.ProductID))
 		}
 		if err != nil {
 			return nil, err
 		}
 
+		if !isActive {
+			return nil, helpers.NewProductInactiveError(fmt.Sprintf(
+				"product '%s' (id %d) is inactive and cannot be sold", productName, item.ProductID))
+		}
+
 		if stock < item.Quantity {
-			return nil, fmt.Errorf("insufficient stock for product '%s' (available: %d, requested: %d)",
-				productName, stock, item.Quantity)
+			return nil, helpers.NewInsufficientStockError(fmt.Sprintf(
+				"insufficient stock for product '%s' (available: %d, requested: %d)",
+				productName, stock, item.Quantity))
 		}
 
 		subtotal := productPrice * item.Quantity
@@ -75,6 +110,7 @@ func (repo *transactionRepository) CreateTransaction(req models.CheckoutRequest)
 		details = append(details, models.TransactionDetail{
 			ProductID:   item.ProductID,
 			ProductName: productName,
+			ProductSKU:  productSKU,
 			Quantity:    item.Quantity,
 			UnitPrice:   productPrice,
 			Subtotal:    subtotal,
@@ -98,9 +134,9 @@ func (repo *transactionRepository) CreateTransaction(req models.CheckoutRequest)
 	var transactionID int
 	var createdAt time.Time
 	err = tx.QueryRow(
-		`INSERT INTO transactions (total_amount, payment_method, discount, notes, status) 
-		 VALUES ($1, $2, $3, $4, 'active') RETURNING id, created_at`,
-		finalAmount, paymentMethod, discount, req.Notes,
+		`INSERT INTO transactions (total_amount, payment_method, discount, notes, status, approved_by) 
+		 VALUES ($1, $2, $3, $4, 'active', $5) RETURNING id, created_at`,
+		finalAmount, paymentMethod, discount, req.Notes, req.ApprovedBy,
 	).Scan(&transactionID, &createdAt)
 	if err != nil {
 		return nil, err
@@ -112,9 +148,10 @@ func (repo *transactionRepository) CreateTransaction(req models.CheckoutRequest)
 
 		var detailID int
 		err = tx.QueryRow(
-			`INSERT INTO transaction_details (transaction_id, product_id, quantity, unit_price, subtotal) 
-			 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
-			transactionID, details[i].ProductID, details[i].Quantity, details[i].UnitPrice, details[i].Subtotal,
+			`INSERT INTO transaction_details (transaction_id, product_id, product_name, product_sku, quantity, unit_price, subtotal) 
+			 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			transactionID, details[i].ProductID, details[i].ProductName, details[i].ProductSKU,
+			details[i].Quantity, details[i].UnitPrice, details[i].Subtotal,
 		).Scan(&detailID)
 		if err != nil {
 			return nil, err
@@ -133,13 +170,15 @@ func (repo *transactionRepository) CreateTransaction(req models.CheckoutRequest)
 		Discount:      discount,
 		Notes:         req.Notes,
 		Status:        "active",
+		ApprovedBy:    req.ApprovedBy,
 		CreatedAt:     createdAt,
 		Details:       details,
 	}, nil
 }
 
-// VoidTransaction marks a transaction as void and restores product stock
-func (repo *transactionRepository) VoidTransaction(id int) error {
+// VoidTransaction marks a transaction as void, records who approved the void
+// and restores product stock
+func (repo *transactionRepository) VoidTransaction(id int, approvedBy *int) error {
 	tx, err := repo.db.Begin()
 	if err != nil {
 		return err
@@ -190,7 +229,7 @@ func (repo *transactionRepository) VoidTransaction(id int) error {
 	}
 
 	// Mark as void
-	_, err = tx.Exec("UPDATE transactions SET status = 'void' WHERE id = $1", id)
+	_, err = tx.Exec("UPDATE transactions SET status = 'void', void_approved_by = $1 WHERE id = $2", approvedBy, id)
 	if err != nil {
 		return err
 	}
@@ -268,9 +307,49 @@ func (repo *transactionRepository) GetSalesReportByDateRange(startDate, endDate
 	return report, nil
 }
 
-// GetAllTransactions returns a paginated list of transactions with optional date filtering
-func (repo *transactionRepository) GetAllTransactions(page, limit int, startDate, endDate string) (*models.PaginatedTransactions, error) {
-	if page < 1 {
+// transactionItemCount counts the detail lines of transaction t. A
+// correlated subquery rather than a GROUP BY keeps it usable in keyset
+// conditions and only evaluates it for the rows a page needs.
+const transactionItemCount = `(SELECT COUNT(*) FROM transaction_details td WHERE td.transaction_id = t.id)`
+
+// transactionListColumns are the columns scanned into a TransactionListItem
+const transactionListColumns = `t.id, t.total_amount, t.payment_method, t.discount, t.status,
+	` + transactionItemCount + ` AS item_count, t.created_at`
+
+// transactionSortColumns maps the sortable transaction fields to SQL
+var transactionSortColumns = map[string]sortColumn{
+	"id":           {"t.id", "integer"},
+	"created_at":   {"t.created_at", "timestamp"},
+	"total_amount": {"t.total_amount", "integer"},
+	"discount":     {"t.discount", "integer"},
+	"item_count":   {transactionItemCount, "bigint"},
+}
+
+// transactionTieBreaker ends every transaction ordering so it is total
+var transactionTieBreaker = sortKey{sortColumn{"t.id", "integer"}, true}
+
+// transactionKeys returns the ORDER BY keys of a transaction list, newest
+// first unless params asks otherwise
+func transactionKeys(params models.TransactionListParams) []sortKey {
+	if len(params.Sort) == 0 {
+		return []sortKey{{transactionSortColumns["created_at"], true}, transactionTieBreaker}
+	}
+	return sortKeys(params.Sort, transactionSortColumns, transactionTieBreaker)
+}
+
+// scanTransactionListItem scans a row of transactionListColumns
+func scanTransactionListItem(scanner rowScanner) (models.TransactionListItem, error) {
+	var item models.TransactionListItem
+	err := scanner.Scan(&item.ID, &item.TotalAmount, &item.PaymentMethod, &item.Discount, &item.Status, &item.ItemCount, &item.CreatedAt)
+	return item, err
+}
+
+// GetAllTransactions returns a paginated list of transactions with optional
+// date filtering. With a cursor the page is found by keyset instead of
+// OFFSET; either way the result carries cursors for the neighbouring pages.
+func (repo *transactionRepository) GetAllTransactions(params models.TransactionListParams) (*models.PaginatedTransactions, error) {
+	page, limit := params.Page, params.Limit
+	if page < 1 || params.Cursor != nil {
 		page = 1
 	}
 	if limit < 1 || limit > 100 {
@@ -278,42 +357,45 @@ func (repo *transactionRepository) GetAllTransactions(page, limit int, startDate
 	}
 	offset := (page - 1) * limit
 
-	// Build WHERE clause for date filters
-	where := " WHERE 1=1"
-	args := []interface{}{}
-	argIdx := 1
-
-	if startDate != "" {
-		where += fmt.Sprintf(" AND t.created_at::date >= $%d::date", argIdx)
-		args = append(args, startDate)
-		argIdx++
-	}
-	if endDate != "" {
-		where += fmt.Sprintf(" AND t.created_at::date <= $%d::date", argIdx)
-		args = append(args, endDate)
-		argIdx++
-	}
+	where, args := transactionDateFilter(params.StartDate, params.EndDate)
+	keys := transactionKeys(params)
+	signature := sortSignature(keys)
+	result := &models.PaginatedTransactions{Limit: limit}
 
 	// Count total
-	countQuery := "SELECT COUNT(*) FROM transactions t" + where
-	var total int
-	err := repo.db.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, err
+	if !params.SkipTotal {
+		var total int
+		if err := repo.db.QueryRow("SELECT COUNT(*) FROM transactions t"+where, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		totalPages := (total + limit - 1) / limit
+		result.Total, result.TotalPages = &total, &totalPages
 	}
 
-	// Fetch page
+	// Fetch page, plus one row to tell whether another follows
+	readKeys := keys
+	if cur := params.Cursor; cur != nil {
+		if err := checkCursor(cur, keys, signature); err != nil {
+			return nil, err
+		}
+		if cur.Before {
+			readKeys = reverseKeys(keys)
+		}
+		cond, keyArgs := keysetFilter(readKeys, cur, len(args)+1)
+		where += " AND " + cond
+		args = append(args, keyArgs...)
+	} else {
+		result.Page = page
+	}
+	argIdx := len(args) + 1
 	query := fmt.Sprintf(`
-		SELECT t.id, t.total_amount, t.payment_method, t.discount, t.status,
-		       COUNT(td.id) AS item_count, t.created_at
+		SELECT %s, %s
 		FROM transactions t
-		LEFT JOIN transaction_details td ON td.transaction_id = t.id
 		%s
-		GROUP BY t.id, t.total_amount, t.payment_method, t.discount, t.status, t.created_at
-		ORDER BY t.created_at DESC
+		ORDER BY %s
 		LIMIT $%d OFFSET $%d
-	`, where, argIdx, argIdx+1)
-	args = append(args, limit, offset)
+	`, transactionListColumns, cursorColumns(keys), where, orderClause(readKeys), argIdx, argIdx+1)
+	args = append(args, limit+1, offset)
 
 	rows, err := repo.db.Query(query, args...)
 	if err != nil {
@@ -321,33 +403,90 @@ func (repo *transactionRepository) GetAllTransactions(page, limit int, startDate
 	}
 	defer rows.Close()
 
-	items := make([]models.TransactionListItem, 0)
+	items := make([]models.TransactionListItem, 0, limit+1)
+	rowKeys := make([][]string, 0, limit+1)
 	for rows.Next() {
-		var item models.TransactionListItem
-		if err := rows.Scan(&item.ID, &item.TotalAmount, &item.PaymentMethod, &item.Discount, &item.Status, &item.ItemCount, &item.CreatedAt); err != nil {
+		scanner := newKeyScanner(rows, len(keys))
+		item, err := scanTransactionListItem(scanner)
+		if err != nil {
 			return nil, err
 		}
 		items = append(items, item)
+		rowKeys = append(rowKeys, scanner.keys)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items, rowKeys = items[:limit], rowKeys[:limit]
+	}
+	before := params.Cursor != nil && params.Cursor.Before
+	if before {
+		slices.Reverse(items)
+		slices.Reverse(rowKeys)
 	}
 
-	totalPages := (total + limit - 1) / limit
+	result.Data = items
+	result.NextCursor, result.PrevCursor = pageCursors(rowKeys, signature, before, hasMore, params.Cursor != nil || page > 1)
+	return result, nil
+}
 
-	return &models.PaginatedTransactions{
-		Data:       items,
-		Total:      total,
-		Page:       page,
-		Limit:      limit,
-		TotalPages: totalPages,
-	}, nil
+// EachTransaction calls fn for every transaction in the date range, in the
+// list order, without pagination. Rows are read as fn consumes them.
+func (repo *transactionRepository) EachTransaction(params models.TransactionListParams, fn func(models.TransactionListItem) error) error {
+	where, args := transactionDateFilter(params.StartDate, params.EndDate)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM transactions t
+		%s
+		ORDER BY %s
+	`, transactionListColumns, where, orderClause(transactionKeys(params)))
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanTransactionListItem(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// transactionDateFilter builds the WHERE clause and arguments for a
+// transaction listing limited to a date range
+func transactionDateFilter(startDate, endDate string) (string, []interface{}) {
+	where := " WHERE 1=1"
+	args := []interface{}{}
+	if startDate != "" {
+		args = append(args, startDate)
+		where += fmt.Sprintf(" AND t.created_at::date >= $%d::date", len(args))
+	}
+	if endDate != "" {
+		args = append(args, endDate)
+		where += fmt.Sprintf(" AND t.created_at::date <= $%d::date", len(args))
+	}
+	return where, args
 }
 
 // GetTransactionByID returns a single transaction with all its details
 func (repo *transactionRepository) GetTransactionByID(id int) (*models.Transaction, error) {
 	var t models.Transaction
 	err := repo.db.QueryRow(`
-		SELECT id, total_amount, payment_method, discount, notes, status, created_at 
+		SELECT id, total_amount, payment_method, discount, notes, status,
+		       approved_by, void_approved_by, created_at 
 		FROM transactions WHERE id = $1
-	`, id).Scan(&t.ID, &t.TotalAmount, &t.PaymentMethod, &t.Discount, &t.Notes, &t.Status, &t.CreatedAt)
+	`, id).Scan(&t.ID, &t.TotalAmount, &t.PaymentMethod, &t.Discount, &t.Notes, &t.Status,
+		&t.ApprovedBy, &t.VoidApprovedBy, &t.CreatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("transaction id %d not found", id)
 	}
@@ -357,7 +496,8 @@ func (repo *transactionRepository) GetTransactionByID(id int) (*models.Transacti
 
 	rows, err := repo.db.Query(`
 		SELECT td.id, td.transaction_id, td.product_id,
-		       COALESCE(p.name, 'Deleted Product') AS product_name,
+		       COALESCE(td.product_name, p.name, 'Deleted Product') AS product_name,
+		       COALESCE(td.product_sku, p.sku, '') AS product_sku,
 		       td.quantity, td.unit_price, td.subtotal
 		FROM transaction_details td
 		LEFT JOIN products p ON p.id = td.product_id
@@ -372,7 +512,7 @@ func (repo *transactionRepository) GetTransactionByID(id int) (*models.Transacti
 	details := make([]models.TransactionDetail, 0)
 	for rows.Next() {
 		var d models.TransactionDetail
-		if err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.ProductName, &d.Quantity, &d.UnitPrice, &d.Subtotal); err != nil {
+		if err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.ProductName, &d.ProductSKU, &d.Quantity, &d.UnitPrice, &d.Subtotal); err != nil {
 			return nil, err
 		}
 		details = append(details, d)
@@ -394,7 +534,7 @@ func (repo *transactionRepository) GetDashboardStats() (*models.DashboardStats,
 		return nil, err
 	}
 
-	err = repo.db.QueryRow(`SELECT COUNT(*) FROM products`).Scan(&stats.TotalProducts)
+	err = repo.db.QueryRow(`SELECT COUNT(*) FROM products WHERE is_active AND deleted_at IS NULL`).Scan(&stats.TotalProducts)
 	if err != nil {
 		return nil, err
 	}
@@ -404,7 +544,7 @@ func (repo *transactionRepository) GetDashboardStats() (*models.DashboardStats,
 		return nil, err
 	}
 
-	err = repo.db.QueryRow(`SELECT COUNT(*) FROM products WHERE stock < 10`).Scan(&stats.LowStockCount)
+	err = repo.db.QueryRow(`SELECT COUNT(*) FROM products WHERE stock < $1 AND is_active AND deleted_at IS NULL`, models.LowStockThreshold).Scan(&stats.LowStockCount)
 	if err != nil {
 		return nil, err
 	}
@@ -431,9 +571,11 @@ func (repo *transactionRepository) GetDashboardStats() (*models.DashboardStats,
 	return stats, nil
 }
 
-// GetReportSummary returns an aggregated report with category breakdown
-func (repo *transactionRepository) GetReportSummary(startDate, endDate string) (*models.ReportSummary, error) {
-	summary := &models.ReportSummary{}
+// GetReportSummary returns an aggregated report with category breakdown.
+// With a categoryLevel above 0, revenue of deeper categories is rolled up
+// into their ancestor at that depth (1 = root categories).
+func (repo *transactionRepository) GetReportSummary(startDate, endDate string, categoryLevel int) (*models.ReportSummary, error) {
+	summary := &models.ReportSummary{CategoryLevel: categoryLevel}
 
 	// Build date filter
 	where := " WHERE t.status = 'active'"
@@ -478,19 +620,30 @@ func (repo *transactionRepository) GetReportSummary(startDate, endDate string) (
 		summary.BestSellingProduct = &best
 	}
 
-	// Category breakdown
+	// Category breakdown. Every category's path from its root is walked so
+	// that sales can be attributed to the ancestor at the requested level.
 	catQuery := fmt.Sprintf(`
-		SELECT COALESCE(p.category_id, 0), COALESCE(c.name, 'Uncategorized'),
+		WITH RECURSIVE tree AS (
+			SELECT id, ARRAY[id] AS path FROM categories WHERE parent_id IS NULL
+			UNION ALL
+			SELECT ch.id, tree.path || ch.id FROM categories ch JOIN tree ON ch.parent_id = tree.id
+		),
+		rollup AS (
+			SELECT id, CASE WHEN $%d::int > 0 THEN path[LEAST(array_length(path, 1), $%d::int)] ELSE id END AS category_id
+			FROM tree
+		)
+		SELECT COALESCE(r.category_id, 0), COALESCE(c.name, 'Uncategorized'),
 		       COALESCE(SUM(td.subtotal), 0), COUNT(DISTINCT t.id)
 		FROM transaction_details td
 		JOIN transactions t ON td.transaction_id = t.id
 		JOIN products p ON td.product_id = p.id
-		LEFT JOIN categories c ON p.category_id = c.id
+		LEFT JOIN rollup r ON p.category_id = r.id
+		LEFT JOIN categories c ON r.category_id = c.id
 		%s
-		GROUP BY p.category_id, c.name
+		GROUP BY r.category_id, c.name
 		ORDER BY SUM(td.subtotal) DESC
-	`, where)
-	rows, err := repo.db.Query(catQuery, args...)
+	`, argIdx, argIdx, where)
+	rows, err := repo.db.Query(catQuery, append(args, categoryLevel)...)
 	if err != nil {
 		return nil, err
 	}
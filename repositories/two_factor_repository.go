@@ -0,0 +1,131 @@
+package repositories
+
+import "database/sql"
+
+// TwoFactorRepository defines the interface for TOTP secret and recovery code data access
+type TwoFactorRepository interface {
+	GetSecret(userID int) (secret string, enabled bool, err error)
+	SetPendingSecret(userID int, secret string) error
+	Enable(userID int, recoveryCodeHashes []string) error
+	Disable(userID int) error
+	MarkStepUsed(userID int, step int64) (bool, error)
+	ConsumeRecoveryCode(userID int, codeHash string) (bool, error)
+}
+
+// twoFactorRepository implements TwoFactorRepository interface with PostgreSQL
+type twoFactorRepository struct {
+	db *sql.DB
+}
+
+// NewTwoFactorRepository creates a new two-factor repository instance
+func NewTwoFactorRepository(db *sql.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+// GetSecret returns the TOTP secret of a user and whether it is enabled. The
+// secret is empty when the user never started enrollment.
+func (r *twoFactorRepository) GetSecret(userID int) (string, bool, error) {
+	var secret string
+	var enabled bool
+	err := r.db.QueryRow(
+		`SELECT COALESCE(totp_secret, ''), totp_enabled FROM users WHERE id = $1`,
+		userID,
+	).Scan(&secret, &enabled)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return secret, enabled, err
+}
+
+// SetPendingSecret stores a new secret awaiting confirmation. It is ignored
+// for users who already have two-factor authentication enabled.
+func (r *twoFactorRepository) SetPendingSecret(userID int, secret string) error {
+	result, err := r.db.Exec(
+		`UPDATE users SET totp_secret = $1, totp_last_step = 0 WHERE id = $2 AND totp_enabled = false`,
+		secret, userID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Enable turns on two-factor authentication and replaces the user's
+// recovery codes in a single transaction
+func (r *twoFactorRepository) Enable(userID int, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE users SET totp_enabled = true WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Disable turns off two-factor authentication, removing the secret and
+// recovery codes
+func (r *twoFactorRepository) Disable(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`UPDATE users SET totp_enabled = false, totp_secret = NULL, totp_last_step = 0 WHERE id = $1`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkStepUsed records the time step of an accepted TOTP code. It returns
+// false when that step (or a later one) was already used, which blocks
+// replaying a code within its validity window.
+func (r *twoFactorRepository) MarkStepUsed(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`,
+		step, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used. It returns
+// false when the code does not match.
+func (r *twoFactorRepository) ConsumeRecoveryCode(userID int, codeHash string) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
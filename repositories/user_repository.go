@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"database/sql"
+	"retail-core-api/helpers"
 	"retail-core-api/models"
+	"time"
 )
 
 // UserRepository defines the interface for user data access
@@ -12,7 +14,14 @@ type UserRepository interface {
 	GetAll() ([]models.User, error)
 	Create(user models.User) (*models.User, error)
 	Update(id int, user models.User) (*models.User, error)
-	Delete(id int) error
+	SetActive(id int, active bool) error
+	GetPINHash(id int) (string, error)
+	SetPINHash(id int, pinHash string) error
+	IncrementFailedLogins(id int) (int, error)
+	LockUntil(id int, until time.Time) error
+	ResetFailedLogins(id int) error
+	SetPassword(id int, passwordHash string, mustChange bool) error
+	SetName(id int, name string) error
 }
 
 // userRepository implements UserRepository interface
@@ -27,11 +36,11 @@ func NewUserRepository(db *sql.DB) UserRepository {
 
 // GetByID returns a user by their ID
 func (r *userRepository) GetByID(id int) (*models.User, error) {
-	query := `SELECT id, name, email, password, role, is_active, created_at FROM users WHERE id = $1`
+	query := `SELECT id, name, email, password, role, is_active, must_change_password, totp_enabled, locked_until, created_at FROM users WHERE id = $1`
 	var user models.User
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID, &user.Name, &user.Email, &user.Password,
-		&user.Role, &user.IsActive, &user.CreatedAt,
+		&user.Role, &user.IsActive, &user.MustChangePassword, &user.TwoFactorEnabled, &user.LockedUntil, &user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -44,11 +53,11 @@ func (r *userRepository) GetByID(id int) (*models.User, error) {
 
 // GetByEmail returns a user by their email
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
-	query := `SELECT id, name, email, password, role, is_active, created_at FROM users WHERE email = $1`
+	query := `SELECT id, name, email, password, role, is_active, must_change_password, totp_enabled, locked_until, created_at FROM users WHERE email = $1`
 	var user models.User
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID, &user.Name, &user.Email, &user.Password,
-		&user.Role, &user.IsActive, &user.CreatedAt,
+		&user.Role, &user.IsActive, &user.MustChangePassword, &user.TwoFactorEnabled, &user.LockedUntil, &user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -61,7 +70,7 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 
 // GetAll returns all users
 func (r *userRepository) GetAll() ([]models.User, error) {
-	query := `SELECT id, name, email, password, role, is_active, created_at FROM users ORDER BY id`
+	query := `SELECT id, name, email, password, role, is_active, must_change_password, totp_enabled, locked_until, created_at FROM users ORDER BY id`
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -73,7 +82,7 @@ func (r *userRepository) GetAll() ([]models.User, error) {
 		var user models.User
 		err := rows.Scan(
 			&user.ID, &user.Name, &user.Email, &user.Password,
-			&user.Role, &user.IsActive, &user.CreatedAt,
+			&user.Role, &user.IsActive, &user.MustChangePassword, &user.TwoFactorEnabled, &user.LockedUntil, &user.CreatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -87,14 +96,14 @@ func (r *userRepository) GetAll() ([]models.User, error) {
 // Create adds a new user
 func (r *userRepository) Create(user models.User) (*models.User, error) {
 	query := `
-		INSERT INTO users (name, email, password, role, is_active)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, name, email, role, is_active, created_at
+		INSERT INTO users (name, email, password, role, is_active, must_change_password)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, name, email, role, is_active, must_change_password, totp_enabled, locked_until, created_at
 	`
 	var created models.User
-	err := r.db.QueryRow(query, user.Name, user.Email, user.Password, user.Role, true).Scan(
+	err := r.db.QueryRow(query, user.Name, user.Email, user.Password, user.Role, true, user.MustChangePassword).Scan(
 		&created.ID, &created.Name, &created.Email,
-		&created.Role, &created.IsActive, &created.CreatedAt,
+		&created.Role, &created.IsActive, &created.MustChangePassword, &created.TwoFactorEnabled, &created.LockedUntil, &created.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -102,17 +111,31 @@ func (r *userRepository) Create(user models.User) (*models.User, error) {
 	return &created, nil
 }
 
-// Update modifies an existing user
+// Update modifies the name, email and role of an existing user. The active
+// flag and password have their own methods. Demoting the last active owner
+// fails with a conflict error.
 func (r *userRepository) Update(id int, user models.User) (*models.User, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if user.Role != models.RoleOwner {
+		if err := ensureOwnerRemains(tx, id); err != nil {
+			return nil, err
+		}
+	}
+
 	query := `
-		UPDATE users SET name = $1, email = $2, role = $3, is_active = $4
-		WHERE id = $5
-		RETURNING id, name, email, role, is_active, created_at
+		UPDATE users SET name = $1, email = $2, role = $3
+		WHERE id = $4
+		RETURNING id, name, email, role, is_active, must_change_password, totp_enabled, locked_until, created_at
 	`
 	var updated models.User
-	err := r.db.QueryRow(query, user.Name, user.Email, user.Role, user.IsActive, id).Scan(
+	err = tx.QueryRow(query, user.Name, user.Email, user.Role, id).Scan(
 		&updated.ID, &updated.Name, &updated.Email,
-		&updated.Role, &updated.IsActive, &updated.CreatedAt,
+		&updated.Role, &updated.IsActive, &updated.MustChangePassword, &updated.TwoFactorEnabled, &updated.LockedUntil, &updated.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -120,13 +143,141 @@ func (r *userRepository) Update(id int, user models.User) (*models.User, error)
 	if err != nil {
 		return nil, err
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return &updated, nil
 }
 
-// Delete deactivates a user by ID
-func (r *userRepository) Delete(id int) error {
-	query := `UPDATE users SET is_active = false WHERE id = $1`
-	result, err := r.db.Exec(query, id)
+// SetActive activates or deactivates a user by ID. Deactivating the last
+// active owner fails with a conflict error.
+func (r *userRepository) SetActive(id int, active bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !active {
+		if err := ensureOwnerRemains(tx, id); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`UPDATE users SET is_active = $1 WHERE id = $2`, active, id)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// ensureOwnerRemains rejects taking user id out of the active owners when it
+// is the last one. The active owner rows stay locked until tx ends, so
+// concurrent demotions or deactivations are serialized and the second one
+// sees the first.
+func ensureOwnerRemains(tx *sql.Tx, id int) error {
+	rows, err := tx.Query(`SELECT id FROM users WHERE role = $1 AND is_active = true ORDER BY id FOR UPDATE`, models.RoleOwner)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	count, isOwner := 0, false
+	for rows.Next() {
+		var ownerID int
+		if err := rows.Scan(&ownerID); err != nil {
+			return err
+		}
+		count++
+		isOwner = isOwner || ownerID == id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if isOwner && count <= 1 {
+		return helpers.NewConflictError("cannot deactivate or demote the last active owner")
+	}
+	return nil
+}
+
+// GetPINHash returns the approval PIN hash of a user, empty if none is set
+func (r *userRepository) GetPINHash(id int) (string, error) {
+	var hash string
+	err := r.db.QueryRow(`SELECT COALESCE(pin_hash, '') FROM users WHERE id = $1`, id).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+// SetPINHash stores the approval PIN hash of a user
+func (r *userRepository) SetPINHash(id int, pinHash string) error {
+	result, err := r.db.Exec(`UPDATE users SET pin_hash = $1 WHERE id = $2`, pinHash, id)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// IncrementFailedLogins bumps the consecutive failed login counter of a user
+// and returns the new count
+func (r *userRepository) IncrementFailedLogins(id int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`UPDATE users SET failed_login_count = failed_login_count + 1 WHERE id = $1 RETURNING failed_login_count`,
+		id,
+	).Scan(&count)
+	return count, err
+}
+
+// LockUntil blocks logins for a user until the given time. Timestamps are
+// stored in UTC to match CURRENT_TIMESTAMP on a UTC database.
+func (r *userRepository) LockUntil(id int, until time.Time) error {
+	_, err := r.db.Exec(`UPDATE users SET locked_until = $1 WHERE id = $2`, until.UTC(), id)
+	return err
+}
+
+// ResetFailedLogins clears the failed login counter and any lockout of a user
+func (r *userRepository) ResetFailedLogins(id int) error {
+	result, err := r.db.Exec(`UPDATE users SET failed_login_count = 0, locked_until = NULL WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetPassword replaces the password hash of a user and sets whether they
+// must change it at their next login
+func (r *userRepository) SetPassword(id int, passwordHash string, mustChange bool) error {
+	result, err := r.db.Exec(
+		`UPDATE users SET password = $1, must_change_password = $2 WHERE id = $3`,
+		passwordHash, mustChange, id,
+	)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetName changes the display name of a user
+func (r *userRepository) SetName(id int, name string) error {
+	result, err := r.db.Exec(`UPDATE users SET name = $1 WHERE id = $2`, name, id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+	"strings"
+	"time"
+)
+
+// apiKeyScheme starts every API key, so leaked keys are easy to recognize
+const apiKeyScheme = "rk"
+
+// APIKeyService defines the interface for API key management and authentication
+type APIKeyService interface {
+	Create(actor models.Actor, input models.APIKeyInput) (*models.APIKeyCreated, error)
+	GetAll() ([]models.APIKey, error)
+	Revoke(actor models.Actor, id int) error
+	Authenticate(rawKey, ip string) (*models.APIKey, error)
+}
+
+// apiKeyService implements APIKeyService interface
+type apiKeyService struct {
+	repo  repositories.APIKeyRepository
+	audit AuditService
+}
+
+// NewAPIKeyService creates a new API key service instance
+func NewAPIKeyService(repo repositories.APIKeyRepository, audit AuditService) APIKeyService {
+	return &apiKeyService{repo: repo, audit: audit}
+}
+
+// Create issues a new key scoped to a subset of the creator's own
+// permissions. The plaintext key is only returned here.
+func (s *apiKeyService) Create(actor models.Actor, input models.APIKeyInput) (*models.APIKeyCreated, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return nil, helpers.NewValidationError("name is required")
+	}
+	if len(input.Permissions) == 0 {
+		return nil, helpers.NewValidationError("an API key must grant at least one permission")
+	}
+	seen := make(map[string]bool, len(input.Permissions))
+	permissions := make([]string, 0, len(input.Permissions))
+	for _, p := range input.Permissions {
+		if !models.IsValidPermission(p) {
+			return nil, helpers.NewValidationError(fmt.Sprintf("unknown permission '%s'", p))
+		}
+		if p == models.PermAPIKeyManage {
+			return nil, helpers.NewValidationError("API keys cannot manage API keys")
+		}
+		if !actor.Can(p) {
+			return nil, helpers.NewValidationError(fmt.Sprintf("cannot grant permission '%s' you do not hold", p))
+		}
+		if !seen[p] {
+			seen[p] = true
+			permissions = append(permissions, p)
+		}
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, helpers.NewValidationError("expires_at must be in the future")
+	}
+
+	prefix, secret, err := newAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	rawKey := prefix + "_" + secret
+
+	key := &models.APIKey{
+		Name:        input.Name,
+		Prefix:      prefix,
+		Permissions: permissions,
+		CreatedBy:   actor.UserID,
+		ExpiresAt:   input.ExpiresAt,
+		KeyHash:     hashToken(rawKey),
+	}
+	if err := s.repo.Create(key); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, models.AuditActionCreate, models.AuditEntityAPIKey, key.ID, nil, key)
+	return &models.APIKeyCreated{APIKey: *key, Key: rawKey}, nil
+}
+
+// GetAll returns every API key without its secret
+func (s *apiKeyService) GetAll() ([]models.APIKey, error) {
+	return s.repo.GetAll()
+}
+
+// Revoke permanently disables a key
+func (s *apiKeyService) Revoke(actor models.Actor, id int) error {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return helpers.NewNotFoundError("API key not found")
+	}
+	if existing.RevokedAt != nil {
+		return helpers.NewConflictError("API key is already revoked")
+	}
+
+	revoked, err := s.repo.Revoke(id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return helpers.NewConflictError("API key is already revoked")
+	}
+
+	s.audit.Record(actor, models.AuditActionRevoke, models.AuditEntityAPIKey, id, nil, nil)
+	return nil
+}
+
+// Authenticate resolves a presented key. It returns nil for unknown,
+// revoked or expired keys, and records when and from where a valid key
+// was last used.
+func (s *apiKeyService) Authenticate(rawKey, ip string) (*models.APIKey, error) {
+	parts := strings.SplitN(rawKey, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyScheme {
+		return nil, nil
+	}
+
+	key, err := s.repo.GetByPrefix(parts[0] + "_" + parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, nil
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return nil, nil
+	}
+
+	if err := s.repo.TouchLastUsed(key.ID, ip, now); err != nil {
+		slog.Warn("Failed to record API key use", "error", err, "api_key_id", key.ID)
+	}
+	return key, nil
+}
+
+// newAPIKey returns a random public prefix (rk_ followed by 48 bits in hex)
+// and a 256-bit secret
+func newAPIKey() (prefix, secret string, err error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	return apiKeyScheme + "_" + hex.EncodeToString(id), base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"regexp"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// pinPattern restricts approval PINs to 4-8 digits
+var pinPattern = regexp.MustCompile(`^[0-9]{4,8}$`)
+
+// ApprovalPolicy configures which cashier actions need a manager approval
+type ApprovalPolicy struct {
+	// DiscountThresholdPercent is the discount, as a percentage of the
+	// gross amount, above which a checkout needs approval. 0 disables it.
+	DiscountThresholdPercent int
+	// RequireVoidApproval makes every void need approval
+	RequireVoidApproval bool
+}
+
+// Approval is an authorization to perform a sensitive action
+type Approval struct {
+	ApproverID int
+	grantID    int
+}
+
+// ApprovalService defines the interface for manager approvals
+type ApprovalService interface {
+	SetPIN(actor models.Actor, currentPassword, pin string) error
+	Grant(actor models.Actor, req models.ApprovalRequest) (*models.ApprovalResponse, error)
+	Authorize(actor models.Actor, action, token string, binding models.ApprovalBinding) (*Approval, error)
+	Release(approval *Approval)
+}
+
+// approvalService implements ApprovalService interface
+type approvalService struct {
+	repo        repositories.ApprovalRepository
+	userRepo    repositories.UserRepository
+	permissions PermissionResolver
+	audit       AuditService
+	ttl         time.Duration
+	lockout     LoginPolicy
+}
+
+// PermissionResolver resolves the permissions granted to a role
+type PermissionResolver interface {
+	Permissions(role string) ([]string, error)
+}
+
+// NewApprovalService creates a new approval service instance
+func NewApprovalService(
+	repo repositories.ApprovalRepository,
+	userRepo repositories.UserRepository,
+	permissions PermissionResolver,
+	audit AuditService,
+	ttl time.Duration,
+	lockout LoginPolicy,
+) ApprovalService {
+	return &approvalService{
+		repo:        repo,
+		userRepo:    userRepo,
+		permissions: permissions,
+		audit:       audit,
+		ttl:         ttl,
+		lockout:     lockout,
+	}
+}
+
+// hashToken returns the hex-encoded SHA-256 of a random token. Tokens carry
+// enough entropy that a fast hash is sufficient for storage.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken returns a random 256-bit hex token
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hasPermission reports whether the named role grants permission
+func hasPermission(resolver PermissionResolver, role, permission string) (bool, error) {
+	permissions, err := resolver.Permissions(role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetPIN sets the approval PIN of the calling user after confirming their password
+func (s *approvalService) SetPIN(actor models.Actor, currentPassword, pin string) error {
+	if actor.UserID == nil {
+		return helpers.NewValidationError("a user account is required to set a PIN")
+	}
+	if !pinPattern.MatchString(pin) {
+		return helpers.NewValidationError("PIN must be 4 to 8 digits")
+	}
+
+	user, err := s.userRepo.GetByID(*actor.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return helpers.NewNotFoundError("user not found")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)) != nil {
+		return helpers.NewValidationError("current password is incorrect")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash PIN")
+	}
+	if err := s.userRepo.SetPINHash(user.ID, string(hash)); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, models.AuditActionSetPIN, models.AuditEntityUser, user.ID, nil, nil)
+	return nil
+}
+
+// Grant verifies a manager's PIN and issues a single-use approval token for
+// the requested action. Failed PIN checks are audited and counted per
+// approver and per caller; after too many in a row both are locked out with
+// the same escalating lockout as logins.
+func (s *approvalService) Grant(actor models.Actor, req models.ApprovalRequest) (*models.ApprovalResponse, error) {
+	if err := validateApprovalBinding(req); err != nil {
+		return nil, err
+	}
+
+	callerStreak, err := s.repo.CallerFailures(actor.UserID, actor.APIKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLockout(callerStreak); err != nil {
+		return nil, err
+	}
+
+	approver, err := s.userRepo.GetByEmail(strings.TrimSpace(req.Email))
+	if err != nil {
+		return nil, err
+	}
+	if approver == nil || !approver.IsActive {
+		return nil, s.registerFailure(actor, req, nil, callerStreak)
+	}
+
+	approverStreak, err := s.repo.ApproverFailures(approver.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLockout(approverStreak); err != nil {
+		return nil, err
+	}
+
+	pinHash, err := s.userRepo.GetPINHash(approver.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pinHash == "" || bcrypt.CompareHashAndPassword([]byte(pinHash), []byte(req.PIN)) != nil {
+		return nil, s.registerFailure(actor, req, approver, callerStreak, approverStreak)
+	}
+
+	// Only a PIN that grants the approval counts as a success; otherwise a
+	// caller could clear their failure streak with their own PIN
+	allowed, err := hasPermission(s.permissions, approver.Role, models.PermApprovalGrant)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		s.recordAttempt(actor, &approver.ID, false)
+		return nil, helpers.NewValidationError("approver is not allowed to grant approvals")
+	}
+	s.recordAttempt(actor, &approver.ID, true)
+
+	token, err := newToken()
+	if err != nil {
+		return nil, errors.New("failed to generate approval token")
+	}
+
+	grant := models.ApprovalGrant{
+		ApproverID: approver.ID,
+		Action:     req.Action,
+		ApprovalBinding: models.ApprovalBinding{
+			TransactionID: req.TransactionID,
+			Discount:      req.Discount,
+			GrossAmount:   req.GrossAmount,
+		},
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	grantID, err := s.repo.Create(grant, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, models.AuditActionApprove, models.AuditEntityApproval, grantID, nil, map[string]interface{}{
+		"approver_id":    approver.ID,
+		"approver_name":  approver.Name,
+		"action":         req.Action,
+		"transaction_id": req.TransactionID,
+		"discount":       req.Discount,
+		"gross_amount":   req.GrossAmount,
+		"expires_at":     grant.ExpiresAt,
+	})
+
+	return &models.ApprovalResponse{
+		ApprovalToken: token,
+		Action:        req.Action,
+		ApprovedBy:    approver.ID,
+		ApproverName:  approver.Name,
+		ExpiresAt:     grant.ExpiresAt,
+	}, nil
+}
+
+// validateApprovalBinding checks that a request names what it approves: the
+// transaction of a void, the discount and gross amount of a discount
+func validateApprovalBinding(req models.ApprovalRequest) error {
+	switch req.Action {
+	case models.ApprovalActionVoid:
+		if req.TransactionID == nil {
+			return helpers.NewValidationError("transaction_id is required to approve a void")
+		}
+	case models.ApprovalActionDiscount:
+		if req.Discount == nil || req.GrossAmount == nil {
+			return helpers.NewValidationError("discount and gross_amount are required to approve a discount")
+		}
+		if *req.Discount <= 0 || *req.GrossAmount <= 0 || *req.Discount > *req.GrossAmount {
+			return helpers.NewValidationError("discount must be positive and at most gross_amount")
+		}
+	}
+	return nil
+}
+
+// checkLockout returns a too-many-requests error while a failure streak
+// keeps its approver or caller locked out
+func (s *approvalService) checkLockout(streak *models.FailureStreak) error {
+	if s.lockout.MaxFailedAttempts <= 0 || streak.Count < s.lockout.MaxFailedAttempts {
+		return nil
+	}
+	remaining := time.Until(streak.LastFailure.Add(s.lockout.lockoutFor(streak.Count)))
+	if remaining <= 0 {
+		return nil
+	}
+	return helpers.NewTooManyRequestsError("too many failed approval attempts, try again later", remaining)
+}
+
+// registerFailure records and audits a failed PIN check. It returns the
+// error for the caller: a lockout once this failure completes a streak of
+// the given ones, otherwise invalid credentials. approver is nil when the
+// email did not match an active user.
+func (s *approvalService) registerFailure(actor models.Actor, req models.ApprovalRequest, approver *models.User, streaks ...*models.FailureStreak) error {
+	var approverID *int
+	if approver != nil {
+		approverID = &approver.ID
+	}
+	s.recordAttempt(actor, approverID, false)
+
+	count := 0
+	for _, streak := range streaks {
+		count = max(count, streak.Count+1)
+	}
+	details := map[string]interface{}{
+		"approver_email":  strings.TrimSpace(req.Email),
+		"approver_id":     approverID,
+		"action":          req.Action,
+		"transaction_id":  req.TransactionID,
+		"failed_attempts": count,
+	}
+
+	var err error = helpers.NewValidationError("invalid approver credentials")
+	if s.lockout.MaxFailedAttempts > 0 && count >= s.lockout.MaxFailedAttempts {
+		lockout := s.lockout.lockoutFor(count)
+		details["locked_until"] = time.Now().Add(lockout)
+		slog.Warn("Approvals locked after failed PIN checks", "approver_id", approverID, "caller_user_id", actor.UserID,
+			"caller_api_key_id", actor.APIKeyID, "failed_attempts", count)
+		err = helpers.NewTooManyRequestsError("too many failed approval attempts, try again later", lockout)
+	}
+
+	entityID := 0
+	if approverID != nil {
+		entityID = *approverID
+	}
+	s.audit.Record(actor, models.AuditActionApprovalFailed, models.AuditEntityUser, entityID, nil, details)
+	return err
+}
+
+// recordAttempt stores a PIN check. Failures are logged, not returned, so
+// that bookkeeping never blocks an approval.
+func (s *approvalService) recordAttempt(actor models.Actor, approverID *int, success bool) {
+	err := s.repo.RecordAttempt(models.ApprovalAttempt{
+		ApproverID:     approverID,
+		CallerUserID:   actor.UserID,
+		CallerAPIKeyID: actor.APIKeyID,
+		Success:        success,
+		IP:             actor.IP,
+	})
+	if err != nil {
+		slog.Error("Failed to record approval attempt", "error", err, "approver_id", approverID)
+	}
+}
+
+// Authorize returns the approval for an action. Actors who may grant
+// approvals themselves are self-approved; everyone else must present a valid
+// approval token, which is consumed.
+func (s *approvalService) Authorize(actor models.Actor, action, token string, binding models.ApprovalBinding) (*Approval, error) {
+	if actor.UserID != nil && actor.Can(models.PermApprovalGrant) {
+		return &Approval{ApproverID: *actor.UserID}, nil
+	}
+
+	if token == "" {
+		return nil, helpers.NewApprovalRequiredError("manager approval required for " + action)
+	}
+
+	grant, err := s.repo.Consume(hashToken(token), action, binding, actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, helpers.NewApprovalRequiredError("invalid or expired approval token")
+	}
+	return &Approval{ApproverID: grant.ApproverID, grantID: grant.ID}, nil
+}
+
+// Release returns a consumed approval token when the approved operation
+// failed, so the cashier can retry without a new PIN entry
+func (s *approvalService) Release(approval *Approval) {
+	if approval == nil || approval.grantID == 0 {
+		return
+	}
+	if err := s.repo.Release(approval.grantID); err != nil {
+		slog.Error("Failed to release approval grant", "error", err, "grant_id", approval.grantID)
+	}
+}
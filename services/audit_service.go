@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+)
+
+// AuditService defines the interface for recording and querying the audit log
+type AuditService interface {
+	Record(actor models.Actor, action, entityType string, entityID int, before, after interface{})
+	GetAll(params models.AuditLogListParams) (*models.PaginatedAuditLogs, error)
+}
+
+// auditService implements AuditService interface
+type auditService struct {
+	repo repositories.AuditRepository
+}
+
+// NewAuditService creates a new audit service instance
+func NewAuditService(repo repositories.AuditRepository) AuditService {
+	return &auditService{repo: repo}
+}
+
+// fieldChange describes how a single field changed between two states
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Record writes an audit log entry for a mutating action. before and after
+// are the entity states (nil when not applicable) and are stored as JSON
+// together with a per-field diff. Failures are logged rather than returned
+// so that auditing never fails the action that was already performed.
+func (s *auditService) Record(actor models.Actor, action, entityType string, entityID int, before, after interface{}) {
+	entry := models.AuditLog{
+		ActorID:    actor.UserID,
+		ActorName:  actor.Name,
+		ActorRole:  actor.Role,
+		APIKeyID:   actor.APIKeyID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IP:         actor.IP,
+		RequestID:  actor.RequestID,
+	}
+
+	var err error
+	if entry.Before, err = marshalState(before); err != nil {
+		slog.Error("Failed to encode audit state", "error", err, "entity_type", entityType, "entity_id", entityID)
+	}
+	if entry.After, err = marshalState(after); err != nil {
+		slog.Error("Failed to encode audit state", "error", err, "entity_type", entityType, "entity_id", entityID)
+	}
+	entry.Changes = diffStates(entry.Before, entry.After)
+
+	if err := s.repo.Create(entry); err != nil {
+		slog.Error("Failed to write audit log",
+			"error", err,
+			"action", action,
+			"entity_type", entityType,
+			"entity_id", entityID,
+			"request_id", actor.RequestID,
+		)
+	}
+}
+
+// GetAll returns paginated audit log entries matching the given filters
+func (s *auditService) GetAll(params models.AuditLogListParams) (*models.PaginatedAuditLogs, error) {
+	return s.repo.GetAll(params)
+}
+
+// marshalState encodes an entity state as JSON, treating nil as absent
+func marshalState(state interface{}) (json.RawMessage, error) {
+	if state == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(state)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+	return json.Marshal(state)
+}
+
+// diffStates returns the top-level fields that differ between two JSON
+// object states as {"field": {"from": ..., "to": ...}}. It returns nil when
+// either state is missing or nothing changed.
+func diffStates(before, after json.RawMessage) json.RawMessage {
+	if len(before) == 0 || len(after) == 0 {
+		return nil
+	}
+
+	var b, a map[string]interface{}
+	if json.Unmarshal(before, &b) != nil || json.Unmarshal(after, &a) != nil {
+		return nil
+	}
+
+	changes := map[string]fieldChange{}
+	for key, from := range b {
+		if to, ok := a[key]; !ok || !reflect.DeepEqual(from, to) {
+			changes[key] = fieldChange{From: from, To: a[key]}
+		}
+	}
+	for key, to := range a {
+		if _, ok := b[key]; !ok {
+			changes[key] = fieldChange{From: nil, To: to}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
@@ -2,65 +2,320 @@ package services
 
 import (
 	"errors"
+	"log/slog"
+	"retail-core-api/helpers"
+	"retail-core-api/metrics"
 	"retail-core-api/models"
 	"retail-core-api/repositories"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// dummyPasswordHash is compared against when the email is unknown, so that
+// unknown accounts take as long to reject as wrong passwords
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+// LoginPolicy configures brute-force protection for logins
+type LoginPolicy struct {
+	// MaxFailedAttempts is the number of consecutive failures after which an
+	// account is locked. 0 disables account lockout.
+	MaxFailedAttempts int
+	// LockoutDuration is the first lockout; every further failure doubles it
+	LockoutDuration time.Duration
+	// MaxLockoutDuration caps the exponential backoff
+	MaxLockoutDuration time.Duration
+	// IPMaxAttempts is the number of failed logins allowed from one IP
+	// within IPWindow. 0 disables IP throttling.
+	IPMaxAttempts int
+	IPWindow      time.Duration
+}
+
+// lockoutFor returns how long to lock an account after count consecutive failures
+func (p LoginPolicy) lockoutFor(count int) time.Duration {
+	lockout := p.LockoutDuration
+	for i := p.MaxFailedAttempts; i < count && lockout < p.MaxLockoutDuration; i++ {
+		lockout *= 2
+	}
+	if p.MaxLockoutDuration > 0 && lockout > p.MaxLockoutDuration {
+		lockout = p.MaxLockoutDuration
+	}
+	return lockout
+}
+
 // AuthService defines the interface for authentication business logic
 type AuthService interface {
-	Login(email, password string) (*models.LoginResponse, error)
-	Register(name, email, password, role string) (*models.User, error)
+	Login(email, password string, client models.ClientInfo) (*models.LoginResponse, error)
+	Register(actor models.Actor, name, email, password, role string) (*models.User, error)
+	VerifyTwoFactor(challengeToken, code string, client models.ClientInfo) (*models.LoginResponse, error)
+	ChangePassword(actor models.Actor, currentPassword, newPassword string) (*models.LoginResponse, error)
+	GetLoginAttempts(params models.LoginAttemptListParams) (*models.PaginatedLoginAttempts, error)
+	AuthenticateSession(userID, sessionID int) (*models.User, error)
 }
 
 // authService implements AuthService interface
 type authService struct {
-	userRepo  repositories.UserRepository
-	jwtSecret string
+	userRepo    repositories.UserRepository
+	roleRepo    repositories.RoleRepository
+	attemptRepo repositories.LoginAttemptRepository
+	sessions    repositories.SessionRepository
+	twoFactor   TwoFactorService
+	audit       AuditService
+	policy      LoginPolicy
+	passwords   helpers.PasswordPolicy
+	keys        *helpers.JWTKeys
 }
 
 // NewAuthService creates a new auth service instance
-func NewAuthService(userRepo repositories.UserRepository, jwtSecret string) AuthService {
+func NewAuthService(
+	userRepo repositories.UserRepository,
+	roleRepo repositories.RoleRepository,
+	attemptRepo repositories.LoginAttemptRepository,
+	sessions repositories.SessionRepository,
+	twoFactor TwoFactorService,
+	audit AuditService,
+	policy LoginPolicy,
+	passwords helpers.PasswordPolicy,
+	keys *helpers.JWTKeys,
+) AuthService {
 	return &authService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		attemptRepo: attemptRepo,
+		sessions:    sessions,
+		twoFactor:   twoFactor,
+		audit:       audit,
+		policy:      policy,
+		passwords:   passwords,
+		keys:        keys,
 	}
 }
 
-// Login authenticates a user and returns a JWT token
-func (s *authService) Login(email, password string) (*models.LoginResponse, error) {
+// Token lifetimes
+const (
+	// accessTokenTTL is how long an access token, and its session, stays valid
+	accessTokenTTL = 24 * time.Hour
+	// challengeTTL is how long a two-factor login challenge stays valid
+	challengeTTL = 5 * time.Minute
+)
+
+// Login authenticates a user and returns a JWT token. Failed attempts are
+// throttled per client IP and lock the account with exponential backoff.
+// Users with two-factor authentication get a challenge token instead, to be
+// completed with VerifyTwoFactor.
+func (s *authService) Login(email, password string, client models.ClientInfo) (*models.LoginResponse, error) {
+	email = strings.TrimSpace(email)
+
+	retryAfter, err := s.ipRetryAfter(client.IP)
+	if err != nil {
+		return nil, errors.New("failed to check login attempts")
+	}
+	if retryAfter > 0 {
+		s.recordAttempt(email, nil, models.LoginResultThrottled, client)
+		return nil, helpers.NewTooManyRequestsError("too many failed login attempts, try again later", retryAfter)
+	}
+
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
 		return nil, errors.New("failed to find user")
 	}
 	if user == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		s.recordAttempt(email, nil, models.LoginResultInvalidCredentials, client)
 		return nil, errors.New("invalid email or password")
 	}
 
-	if !user.IsActive {
-		return nil, errors.New("account is deactivated")
+	if err := s.checkAccount(user, client); err != nil {
+		return nil, err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
+		s.recordAttempt(email, &user.ID, models.LoginResultInvalidCredentials, client)
+		if lockErr := s.registerFailure(user, client); lockErr != nil {
+			return nil, lockErr
+		}
 		return nil, errors.New("invalid email or password")
 	}
 
-	// Generate JWT token
+	if user.TwoFactorEnabled {
+		return s.issueChallenge(user)
+	}
+	return s.completeLogin(user, client)
+}
+
+// VerifyTwoFactor completes a two-factor login with a TOTP or recovery code
+// and returns the JWT. Wrong codes count towards the account lockout.
+func (s *authService) VerifyTwoFactor(challengeToken, code string, client models.ClientInfo) (*models.LoginResponse, error) {
+	userID, err := s.parseChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	retryAfter, err := s.ipRetryAfter(client.IP)
+	if err != nil {
+		return nil, errors.New("failed to check login attempts")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("failed to find user")
+	}
+	if user == nil {
+		return nil, errors.New("invalid or expired challenge")
+	}
+	if retryAfter > 0 {
+		s.recordAttempt(user.Email, &user.ID, models.LoginResultThrottled, client)
+		return nil, helpers.NewTooManyRequestsError("too many failed login attempts, try again later", retryAfter)
+	}
+	if err := s.checkAccount(user, client); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.twoFactor.Verify(user.ID, code)
+	if err != nil {
+		return nil, errors.New("failed to verify code")
+	}
+	if !ok {
+		s.recordAttempt(user.Email, &user.ID, models.LoginResultInvalidSecondFactor, client)
+		if lockErr := s.registerFailure(user, client); lockErr != nil {
+			return nil, lockErr
+		}
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	return s.completeLogin(user, client)
+}
+
+// AuthenticateSession returns the current account behind an access token,
+// or nil when the user no longer exists, is deactivated, or the token's
+// session has been revoked or has expired. Tokens issued without a recorded
+// session are only checked against the account.
+func (s *authService) AuthenticateSession(userID, sessionID int) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, nil
+	}
+	if sessionID != 0 {
+		active, err := s.sessions.IsActive(sessionID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, nil
+		}
+	}
+	user.Password = ""
+	return user, nil
+}
+
+// checkAccount rejects locked and deactivated accounts
+func (s *authService) checkAccount(user *models.User, client models.ClientInfo) error {
+	now := time.Now()
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		s.recordAttempt(user.Email, &user.ID, models.LoginResultLocked, client)
+		return helpers.NewTooManyRequestsError(
+			"account is temporarily locked after too many failed login attempts",
+			user.LockedUntil.Sub(now),
+		)
+	}
+
+	if !user.IsActive {
+		s.recordAttempt(user.Email, &user.ID, models.LoginResultInactive, client)
+		return errors.New("account is deactivated")
+	}
+	return nil
+}
+
+// completeLogin records a successful login, clears the failure counter and
+// issues the JWT
+func (s *authService) completeLogin(user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	s.recordAttempt(user.Email, &user.ID, models.LoginResultSuccess, client)
+	if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+		slog.Error("Failed to reset failed login counter", "error", err, "user_id", user.ID)
+	}
+	user.LockedUntil = nil
+
+	return s.issueToken(user, client)
+}
+
+// issueChallenge signs a short-lived token that can only be exchanged for a
+// JWT at /auth/login/2fa
+func (s *authService) issueChallenge(user *models.User) (*models.LoginResponse, error) {
 	claims := jwt.MapClaims{
+		"typ":     models.TokenTypeTwoFactorChallenge,
+		"user_id": user.ID,
+		"exp":     time.Now().Add(challengeTTL).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+
+	tokenString, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	return &models.LoginResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    tokenString,
+	}, nil
+}
+
+// parseChallenge validates a challenge token and returns its user ID
+func (s *authService) parseChallenge(challengeToken string) (int, error) {
+	invalid := errors.New("invalid or expired challenge")
+
+	claims, err := s.keys.Parse(challengeToken)
+	if err != nil || claims["typ"] != models.TokenTypeTwoFactorChallenge {
+		return 0, invalid
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, invalid
+	}
+	return int(userID), nil
+}
+
+// issueToken records a session and signs a JWT for the user carrying its
+// ID. Users who must change their password get a token that only allows
+// /auth/change-password, and users who must enroll in two-factor
+// authentication one that only allows enrollment.
+func (s *authService) issueToken(user *models.User, client models.ClientInfo) (*models.LoginResponse, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"typ":     models.TokenTypeAccess,
 		"user_id": user.ID,
 		"email":   user.Email,
 		"role":    user.Role,
 		"name":    user.Name,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
+		"exp":     now.Add(accessTokenTTL).Unix(),
+		"iat":     now.Unix(),
+	}
+
+	// Sessions are informational, so a failure to record one does not block the login
+	session, err := s.sessions.Create(models.Session{
+		UserID:    user.ID,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		ExpiresAt: now.Add(accessTokenTTL),
+	})
+	if err != nil {
+		slog.Error("Failed to record session", "error", err, "user_id", user.ID)
+	} else {
+		claims["sid"] = session.ID
+	}
+	if user.MustChangePassword {
+		claims["must_change_password"] = true
+	}
+	if !user.TwoFactorEnabled && s.twoFactor.Required(user.Role) {
+		claims["must_enroll_2fa"] = true
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	tokenString, err := s.keys.Sign(claims)
 	if err != nil {
 		return nil, errors.New("failed to generate token")
 	}
@@ -70,12 +325,52 @@ func (s *authService) Login(email, password string) (*models.LoginResponse, erro
 
 	return &models.LoginResponse{
 		Token: tokenString,
-		User:  *user,
+		User:  user,
 	}, nil
 }
 
+// ChangePassword replaces the caller's password after verifying the current
+// one, clears any forced change and returns a fresh token
+func (s *authService) ChangePassword(actor models.Actor, currentPassword, newPassword string) (*models.LoginResponse, error) {
+	if actor.UserID == nil {
+		return nil, helpers.NewValidationError("a user account is required to change the password")
+	}
+
+	user, err := s.userRepo.GetByID(*actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, helpers.NewNotFoundError("user not found")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)) != nil {
+		return nil, helpers.NewValidationError("current password is incorrect")
+	}
+	if currentPassword == newPassword {
+		return nil, helpers.NewValidationError("new password must differ from the current password")
+	}
+	if err := s.passwords.Validate(newPassword); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+	if err := s.userRepo.SetPassword(user.ID, string(hash), false); err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	before := *user
+	user.MustChangePassword = false
+	s.audit.Record(actor, models.AuditActionChangePassword, models.AuditEntityUser, user.ID, &before, user)
+
+	return s.issueToken(user, models.ClientInfo{IP: actor.IP, UserAgent: actor.UserAgent, RequestID: actor.RequestID})
+}
+
 // Register creates a new user account
-func (s *authService) Register(name, email, password, role string) (*models.User, error) {
+func (s *authService) Register(actor models.Actor, name, email, password, role string) (*models.User, error) {
 	// Check if email already exists
 	existing, err := s.userRepo.GetByEmail(email)
 	if err != nil {
@@ -85,9 +380,13 @@ func (s *authService) Register(name, email, password, role string) (*models.User
 		return nil, errors.New("email already registered")
 	}
 
-	// Validate role
-	if role != "owner" && role != "cashier" {
-		return nil, errors.New("role must be 'owner' or 'cashier'")
+	// The role must exist and grant nothing the actor does not hold
+	if err := checkAssignRole(s.roleRepo, actor, role); err != nil {
+		return nil, err
+	}
+
+	if err := s.passwords.Validate(password); err != nil {
+		return nil, err
 	}
 
 	// Hash password
@@ -103,5 +402,82 @@ func (s *authService) Register(name, email, password, role string) (*models.User
 		Role:     role,
 	}
 
-	return s.userRepo.Create(user)
+	created, err := s.userRepo.Create(user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, models.AuditActionCreate, models.AuditEntityUser, created.ID, nil, created)
+	return created, nil
+}
+
+// GetLoginAttempts returns paginated login attempts
+func (s *authService) GetLoginAttempts(params models.LoginAttemptListParams) (*models.PaginatedLoginAttempts, error) {
+	return s.attemptRepo.GetAll(params)
+}
+
+// ipRetryAfter returns how long a client IP must wait before its next login
+// attempt, or 0 when it is not throttled
+func (s *authService) ipRetryAfter(ip string) (time.Duration, error) {
+	if s.policy.IPMaxAttempts <= 0 || ip == "" {
+		return 0, nil
+	}
+
+	now := time.Now()
+	oldest, err := s.attemptRepo.RecentFailureByIP(ip, now.Add(-s.policy.IPWindow), s.policy.IPMaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+	if oldest == nil {
+		return 0, nil
+	}
+	return oldest.Add(s.policy.IPWindow).Sub(now), nil
+}
+
+// registerFailure counts a failed password for the user and locks the
+// account once the policy threshold is reached. It returns a throttle error
+// when the account has just been locked.
+func (s *authService) registerFailure(user *models.User, client models.ClientInfo) error {
+	count, err := s.userRepo.IncrementFailedLogins(user.ID)
+	if err != nil {
+		slog.Error("Failed to count failed login", "error", err, "user_id", user.ID)
+		return nil
+	}
+	if s.policy.MaxFailedAttempts <= 0 || count < s.policy.MaxFailedAttempts {
+		return nil
+	}
+
+	lockout := s.policy.lockoutFor(count)
+	until := time.Now().Add(lockout)
+	if err := s.userRepo.LockUntil(user.ID, until); err != nil {
+		slog.Error("Failed to lock account", "error", err, "user_id", user.ID)
+		return nil
+	}
+
+	actor := models.Actor{Name: user.Email, IP: client.IP, RequestID: client.RequestID}
+	s.audit.Record(actor, models.AuditActionLock, models.AuditEntityUser, user.ID, nil, map[string]interface{}{
+		"failed_attempts": count,
+		"locked_until":    until,
+	})
+	slog.Warn("Account locked after failed logins", "user_id", user.ID, "failed_attempts", count, "locked_until", until)
+
+	return helpers.NewTooManyRequestsError("too many failed login attempts, account is temporarily locked", lockout)
+}
+
+// recordAttempt stores a login attempt. Failures are logged, not returned,
+// so that bookkeeping never blocks a login.
+func (s *authService) recordAttempt(email string, userID *int, result string, client models.ClientInfo) {
+	metrics.LoginAttemptsTotal.WithLabelValues(result).Inc()
+
+	err := s.attemptRepo.Create(models.LoginAttempt{
+		Email:     email,
+		UserID:    userID,
+		Result:    result,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		RequestID: client.RequestID,
+	})
+	if err != nil {
+		slog.Error("Failed to record login attempt", "error", err, "email", email, "result", result)
+	}
 }
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"retail-core-api/helpers"
 	"retail-core-api/models"
 	"retail-core-api/repositories"
 )
@@ -9,20 +12,22 @@ import (
 // CategoryService defines the interface for category business logic
 type CategoryService interface {
 	GetAllCategories() ([]models.Category, error)
+	GetCategoryTree() ([]models.Category, error)
 	GetCategoryByID(id int) (*models.Category, error)
-	CreateCategory(category models.Category) (*models.Category, error)
-	UpdateCategory(id int, category models.Category) (*models.Category, error)
-	DeleteCategory(id int) error
+	CreateCategory(actor models.Actor, category models.Category) (*models.Category, error)
+	UpdateCategory(actor models.Actor, id int, category models.Category) (*models.Category, error)
+	DeleteCategory(actor models.Actor, id int, opts models.CategoryDeleteOptions) (*models.CategoryDeleteResult, error)
 }
 
 // categoryService implements CategoryService interface
 type categoryService struct {
-	repo repositories.CategoryRepository
+	repo  repositories.CategoryRepository
+	audit AuditService
 }
 
 // NewCategoryService creates a new category service instance
-func NewCategoryService(repo repositories.CategoryRepository) CategoryService {
-	return &categoryService{repo: repo}
+func NewCategoryService(repo repositories.CategoryRepository, audit AuditService) CategoryService {
+	return &categoryService{repo: repo, audit: audit}
 }
 
 // GetAllCategories returns all categories
@@ -30,41 +35,202 @@ func (s *categoryService) GetAllCategories() ([]models.Category, error) {
 	return s.repo.GetAll()
 }
 
+// GetCategoryTree returns the root categories with their descendants nested
+// under Children
+func (s *categoryService) GetCategoryTree() ([]models.Category, error) {
+	categories, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]bool, len(categories))
+	for _, cat := range categories {
+		known[cat.ID] = true
+	}
+	children := make(map[int][]models.Category)
+	roots := make([]models.Category, 0)
+	for _, cat := range categories {
+		if cat.ParentID != nil && known[*cat.ParentID] {
+			children[*cat.ParentID] = append(children[*cat.ParentID], cat)
+		} else {
+			roots = append(roots, cat)
+		}
+	}
+
+	// Each category has one parent, so walking down from the roots visits
+	// every node at most once
+	var attach func(nodes []models.Category)
+	attach = func(nodes []models.Category) {
+		for i := range nodes {
+			nodes[i].Children = children[nodes[i].ID]
+			attach(nodes[i].Children)
+		}
+	}
+	attach(roots)
+	return roots, nil
+}
+
+// validateParent checks that parentID names an existing, non-archived
+// category other than the category itself. id is 0 for a new category. The
+// repository checks that a move does not put a category under one of its
+// descendants, under lock.
+func (s *categoryService) validateParent(id int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == id {
+		return helpers.NewValidationError("a category cannot be its own parent")
+	}
+	parent, err := s.repo.GetByID(*parentID)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return helpers.NewValidationError("parent category not found")
+	}
+	if parent.ArchivedAt != nil {
+		return helpers.NewValidationError("parent category is archived")
+	}
+	return nil
+}
+
 // GetCategoryByID returns a category by its ID
 func (s *categoryService) GetCategoryByID(id int) (*models.Category, error) {
 	return s.repo.GetByID(id)
 }
 
 // CreateCategory validates and creates a new category
-func (s *categoryService) CreateCategory(category models.Category) (*models.Category, error) {
+func (s *categoryService) CreateCategory(actor models.Actor, category models.Category) (*models.Category, error) {
 	// Business logic validation
 	if category.Name == "" {
 		return nil, errors.New("category name is required")
 	}
+	if err := s.validateParent(0, category.ParentID); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Create(category)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.repo.Create(category)
+	s.audit.Record(actor, models.AuditActionCreate, models.AuditEntityCategory, created.ID, nil, created)
+	return created, nil
 }
 
 // UpdateCategory validates and updates an existing category
-func (s *categoryService) UpdateCategory(id int, category models.Category) (*models.Category, error) {
+func (s *categoryService) UpdateCategory(actor models.Actor, id int, category models.Category) (*models.Category, error) {
 	// Business logic validation
 	if category.Name == "" {
 		return nil, errors.New("category name is required")
 	}
 
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("category not found")
+	}
+	if err := s.validateParent(id, category.ParentID); err != nil {
+		return nil, err
+	}
+
 	updated, err := s.repo.Update(id, category)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	if updated == nil {
 		return nil, errors.New("category not found")
 	}
 
+	s.audit.Record(actor, models.AuditActionUpdate, models.AuditEntityCategory, id, existing, updated)
 	return updated, nil
 }
 
-// DeleteCategory removes a category by its ID
-func (s *categoryService) DeleteCategory(id int) error {
-	return s.repo.Delete(id)
+// DeleteCategory removes a category. A category that still has products or
+// subcategories is only removed when opts says what to do with them:
+// reassign them to another category, or archive the whole subtree.
+func (s *categoryService) DeleteCategory(actor models.Actor, id int, opts models.CategoryDeleteOptions) (*models.CategoryDeleteResult, error) {
+	if opts.ReassignTo != nil && opts.Archive {
+		return nil, helpers.NewValidationError("reassign_to and archive cannot be combined")
+	}
+
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, helpers.NewNotFoundError("category not found")
+	}
+
+	switch {
+	case opts.Archive:
+		if existing.ArchivedAt != nil {
+			return nil, helpers.NewConflictError("category is already archived")
+		}
+		categories, products, err := s.repo.ArchiveSubtree(id)
+		if err != nil {
+			return nil, err
+		}
+		result := &models.CategoryDeleteResult{
+			Action:             models.CategoryArchived,
+			AffectedProducts:   products,
+			AffectedCategories: categories,
+		}
+		s.audit.Record(actor, models.AuditActionArchive, models.AuditEntityCategory, id, existing, result)
+		return result, nil
+
+	case opts.ReassignTo != nil:
+		if err := s.validateReassignTarget(id, *opts.ReassignTo); err != nil {
+			return nil, err
+		}
+		products, children, err := s.repo.DeleteReassigning(id, *opts.ReassignTo)
+		if err != nil {
+			return nil, err
+		}
+		result := &models.CategoryDeleteResult{
+			Action:             models.CategoryReassigned,
+			AffectedProducts:   products,
+			AffectedCategories: children,
+			ReassignedTo:       opts.ReassignTo,
+		}
+		s.audit.Record(actor, models.AuditActionDelete, models.AuditEntityCategory, id, existing, result)
+		return result, nil
+	}
+
+	products, children, err := s.repo.Delete(id)
+	if err == sql.ErrNoRows {
+		return nil, helpers.NewNotFoundError("category not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if products > 0 || children > 0 {
+		return nil, helpers.NewConflictError(fmt.Sprintf(
+			"category has %d product(s) and %d subcategory(ies); pass reassign_to or archive=true",
+			products, children,
+		))
+	}
+
+	s.audit.Record(actor, models.AuditActionDelete, models.AuditEntityCategory, id, existing, nil)
+	return &models.CategoryDeleteResult{Action: models.CategoryDeleted}, nil
+}
+
+// validateReassignTarget checks that products of category id can be moved
+// to targetID: it must exist and not be archived. The repository checks
+// that it lies outside id's subtree.
+func (s *categoryService) validateReassignTarget(id, targetID int) error {
+	target, err := s.repo.GetByID(targetID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return helpers.NewValidationError("reassign_to category not found")
+	}
+	if target.ArchivedAt != nil {
+		return helpers.NewValidationError("reassign_to category is archived")
+	}
+	return nil
 }
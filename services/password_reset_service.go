@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"retail-core-api/helpers"
+	"retail-core-api/mailer"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// mailTimeout bounds how long a reset email may take to send
+const mailTimeout = 30 * time.Second
+
+// PasswordResetPolicy configures the password reset flow
+type PasswordResetPolicy struct {
+	// TokenTTL is how long a reset link stays valid
+	TokenTTL time.Duration
+	// ResetURL is the frontend page the emailed link points to. The token is
+	// appended as a query parameter; when empty the email carries the bare token.
+	ResetURL string
+	// EmailMaxRequests and IPMaxRequests cap reset requests per email and
+	// per client IP within RateWindow. 0 disables a cap.
+	EmailMaxRequests int
+	IPMaxRequests    int
+	RateWindow       time.Duration
+}
+
+// PasswordResetService defines the interface for forgotten password recovery
+type PasswordResetService interface {
+	RequestReset(email string, client models.ClientInfo) error
+	Reset(token, newPassword string, client models.ClientInfo) error
+}
+
+// passwordResetService implements PasswordResetService interface
+type passwordResetService struct {
+	userRepo  repositories.UserRepository
+	resetRepo repositories.PasswordResetRepository
+	sessions  repositories.SessionRepository
+	mailer    mailer.Mailer
+	audit     AuditService
+	passwords helpers.PasswordPolicy
+	policy    PasswordResetPolicy
+}
+
+// NewPasswordResetService creates a new password reset service instance
+func NewPasswordResetService(
+	userRepo repositories.UserRepository,
+	resetRepo repositories.PasswordResetRepository,
+	sessions repositories.SessionRepository,
+	mail mailer.Mailer,
+	audit AuditService,
+	passwords helpers.PasswordPolicy,
+	policy PasswordResetPolicy,
+) PasswordResetService {
+	return &passwordResetService{
+		userRepo:  userRepo,
+		resetRepo: resetRepo,
+		sessions:  sessions,
+		mailer:    mail,
+		audit:     audit,
+		passwords: passwords,
+		policy:    policy,
+	}
+}
+
+// RequestReset emails a single-use reset link to an active account. It
+// returns nil for unknown or inactive emails so callers cannot tell whether
+// an account exists, and sends the email in the background so response
+// times do not reveal it either. Requests are rate limited per email, known
+// or not, and per client IP.
+func (s *passwordResetService) RequestReset(email string, client models.ClientInfo) error {
+	email = strings.TrimSpace(email)
+	retryAfter, err := s.requestRetryAfter(email, client.IP)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		return helpers.NewTooManyRequestsError("too many password reset requests, try again later", retryAfter)
+	}
+	if err := s.resetRepo.RecordRequest(email, client.IP); err != nil {
+		slog.Error("Failed to record password reset request", "error", err, "ip", client.IP)
+	}
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.IsActive {
+		slog.Info("Password reset requested for unknown or inactive account", "ip", client.IP)
+		return nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return errors.New("failed to generate reset token")
+	}
+
+	// Only the newest link is valid
+	if err := s.resetRepo.InvalidateForUser(user.ID); err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.policy.TokenTTL)
+	if err := s.resetRepo.Create(user.ID, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    s.resetEmailBody(user.Name, token, expiresAt),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			slog.Error("Failed to send password reset email", "error", err, "user_id", user.ID)
+		}
+	}()
+	return nil
+}
+
+// requestRetryAfter returns how long a client must wait before requesting
+// another reset link for email, or 0 when it is within both limits
+func (s *passwordResetService) requestRetryAfter(email, ip string) (time.Duration, error) {
+	now := time.Now()
+	since := now.Add(-s.policy.RateWindow)
+	var wait time.Duration
+
+	if s.policy.EmailMaxRequests > 0 {
+		oldest, err := s.resetRepo.RecentRequestByEmail(email, since, s.policy.EmailMaxRequests)
+		if err != nil {
+			return 0, err
+		}
+		if oldest != nil {
+			wait = max(wait, oldest.Add(s.policy.RateWindow).Sub(now))
+		}
+	}
+	if s.policy.IPMaxRequests > 0 && ip != "" {
+		oldest, err := s.resetRepo.RecentRequestByIP(ip, since, s.policy.IPMaxRequests)
+		if err != nil {
+			return 0, err
+		}
+		if oldest != nil {
+			wait = max(wait, oldest.Add(s.policy.RateWindow).Sub(now))
+		}
+	}
+	return wait, nil
+}
+
+// Reset sets a new password using a reset token. The token is only consumed
+// once the new password passes the policy. Any lockout and pending forced
+// change are cleared, and the user's sessions are ended.
+func (s *passwordResetService) Reset(token, newPassword string, client models.ClientInfo) error {
+	if err := s.passwords.Validate(newPassword); err != nil {
+		return err
+	}
+
+	userID, err := s.resetRepo.Consume(hashToken(strings.TrimSpace(token)))
+	if err != nil {
+		return err
+	}
+	if userID == nil {
+		return helpers.NewValidationError("invalid or expired reset token")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	if err := s.userRepo.SetPassword(*userID, string(hash), false); err != nil {
+		return err
+	}
+	if err := s.userRepo.ResetFailedLogins(*userID); err != nil {
+		slog.Error("Failed to clear lockout after password reset", "error", err, "user_id", *userID)
+	}
+	if err := s.resetRepo.InvalidateForUser(*userID); err != nil {
+		slog.Error("Failed to invalidate reset tokens", "error", err, "user_id", *userID)
+	}
+	if err := s.sessions.RevokeByUser(*userID); err != nil {
+		return err
+	}
+
+	actor := models.Actor{UserID: userID, IP: client.IP, RequestID: client.RequestID}
+	s.audit.Record(actor, models.AuditActionResetPassword, models.AuditEntityUser, *userID, nil, map[string]interface{}{
+		"method": "reset_token",
+	})
+	return nil
+}
+
+// resetEmailBody renders the plain-text reset email
+func (s *passwordResetService) resetEmailBody(name, token string, expiresAt time.Time) string {
+	link := token
+	if s.policy.ResetURL != "" {
+		sep := "?"
+		if strings.Contains(s.policy.ResetURL, "?") {
+			sep = "&"
+		}
+		link = s.policy.ResetURL + sep + "token=" + token
+	}
+
+	return fmt.Sprintf(`Hello %s,
+
+We received a request to reset your Retail Core password. Use the link
+below to choose a new password:
+
+%s
+
+The link can be used once and expires at %s.
+If you did not request a reset, you can ignore this email.
+`, name, link, expiresAt.Format(time.RFC1123))
+}
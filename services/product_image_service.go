@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log/slog"
+	"net/http"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+	"retail-core-api/storage"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // registers the WebP decoder
+)
+
+// maxImagePixels bounds the decoded size of an upload, so a small file that
+// decompresses into a huge bitmap is refused before it is decoded
+const maxImagePixels = 40_000_000
+
+// ImagePolicy configures product image uploads
+type ImagePolicy struct {
+	// MaxFileSize is the largest accepted upload in bytes
+	MaxFileSize int64
+	// ThumbnailSize is the longest side of generated thumbnails in pixels
+	ThumbnailSize int
+}
+
+// ProductImageService defines the interface for product image management
+type ProductImageService interface {
+	List(productID int) ([]models.ProductImage, error)
+	Upload(ctx context.Context, actor models.Actor, productID int, data []byte, primary bool) (*models.ProductImage, error)
+	SetPrimary(actor models.Actor, productID, imageID int) (*models.ProductImage, error)
+	Delete(ctx context.Context, actor models.Actor, productID, imageID int) error
+}
+
+// productImageService implements ProductImageService interface
+type productImageService struct {
+	repo        repositories.ProductImageRepository
+	productRepo repositories.ProductRepository
+	store       storage.Storage
+	audit       AuditService
+	policy      ImagePolicy
+}
+
+// NewProductImageService creates a new product image service instance
+func NewProductImageService(repo repositories.ProductImageRepository, productRepo repositories.ProductRepository, store storage.Storage, audit AuditService, policy ImagePolicy) ProductImageService {
+	return &productImageService{
+		repo:        repo,
+		productRepo: productRepo,
+		store:       store,
+		audit:       audit,
+		policy:      policy,
+	}
+}
+
+// List returns the images of a product, primary first
+func (s *productImageService) List(productID int) ([]models.ProductImage, error) {
+	if _, err := s.liveProduct(productID); err != nil {
+		return nil, err
+	}
+	images, err := s.repo.GetByProductID(productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range images {
+		s.setURLs(&images[i])
+	}
+	return images, nil
+}
+
+// Upload validates an image, stores it with a thumbnail and attaches it to
+// the product. The first image of a product, or one uploaded with primary
+// set, becomes the primary image and the product's image_url.
+func (s *productImageService) Upload(ctx context.Context, actor models.Actor, productID int, data []byte, primary bool) (*models.ProductImage, error) {
+	if _, err := s.liveProduct(productID); err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.policy.MaxFileSize {
+		return nil, helpers.NewValidationError(fmt.Sprintf("image is larger than %d bytes", s.policy.MaxFileSize))
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, helpers.NewValidationError("unsupported image type " + contentType + "; upload a JPEG, PNG or WebP image")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, helpers.NewValidationError("image could not be read: " + err.Error())
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, helpers.NewValidationError(fmt.Sprintf("image is %dx%d pixels; the limit is %d megapixels", config.Width, config.Height, maxImagePixels/1_000_000))
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, helpers.NewValidationError("image could not be read: " + err.Error())
+	}
+
+	thumb, thumbType, err := encodeThumbnail(decoded, contentType, s.policy.ThumbnailSize)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := newImageName()
+	if err != nil {
+		return nil, err
+	}
+	img := models.ProductImage{
+		ProductID:    productID,
+		StorageKey:   fmt.Sprintf("products/%d/%s%s", productID, name, ext),
+		ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb%s", productID, name, imageExtensions[thumbType]),
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        config.Width,
+		Height:       config.Height,
+		IsPrimary:    primary,
+	}
+
+	if err := s.store.Put(ctx, img.StorageKey, bytes.NewReader(data), img.Size, contentType); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, img.ThumbnailKey, bytes.NewReader(thumb), int64(len(thumb)), thumbType); err != nil {
+		s.removeFiles(ctx, img.StorageKey)
+		return nil, err
+	}
+
+	created, err := s.repo.Create(img, s.store.URL(img.StorageKey))
+	if err != nil {
+		s.removeFiles(ctx, img.StorageKey, img.ThumbnailKey)
+		return nil, err
+	}
+	s.setURLs(created)
+
+	s.audit.Record(actor, models.AuditActionCreate, models.AuditEntityImage, created.ID, nil, created)
+	return created, nil
+}
+
+// SetPrimary makes an image the product's primary image
+func (s *productImageService) SetPrimary(actor models.Actor, productID, imageID int) (*models.ProductImage, error) {
+	img, err := s.repo.GetByID(productID, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if img == nil {
+		return nil, helpers.NewNotFoundError("image not found")
+	}
+	if img.IsPrimary {
+		s.setURLs(img)
+		return img, nil
+	}
+
+	before := *img
+	if err := s.repo.SetPrimary(productID, imageID, s.store.URL(img.StorageKey)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, helpers.NewNotFoundError("image not found")
+		}
+		return nil, err
+	}
+	img.IsPrimary = true
+	s.setURLs(img)
+
+	s.audit.Record(actor, models.AuditActionUpdate, models.AuditEntityImage, img.ID, before, img)
+	return img, nil
+}
+
+// Delete removes an image and its files. When it was the primary image the
+// oldest remaining one takes its place; without images left the product's
+// image_url is cleared. Files are only removed once the record is gone, so
+// a failed delete never leaves a record pointing at missing files.
+func (s *productImageService) Delete(ctx context.Context, actor models.Actor, productID, imageID int) error {
+	deleted, err := s.repo.Delete(productID, imageID, s.store.URL)
+	if err == sql.ErrNoRows {
+		return helpers.NewNotFoundError("image not found")
+	}
+	if err != nil {
+		return err
+	}
+	// The record is gone even if the client has hung up
+	s.removeFiles(context.WithoutCancel(ctx), deleted.StorageKey, deleted.ThumbnailKey)
+
+	s.setURLs(deleted)
+	s.audit.Record(actor, models.AuditActionDelete, models.AuditEntityImage, deleted.ID, deleted, nil)
+	return nil
+}
+
+// liveProduct returns the product if it exists and is not archived
+func (s *productImageService) liveProduct(productID int) (*models.Product, error) {
+	product, err := s.productRepo.GetByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil || product.DeletedAt != nil {
+		return nil, helpers.NewNotFoundError("product not found")
+	}
+	return product, nil
+}
+
+// setURLs fills in the public URLs of an image from its storage keys
+func (s *productImageService) setURLs(img *models.ProductImage) {
+	img.URL = s.store.URL(img.StorageKey)
+	img.ThumbnailURL = s.store.URL(img.ThumbnailKey)
+}
+
+// removeFiles deletes stored files whose records are gone or were never
+// written. Failures only leave orphaned files behind, so they are logged.
+func (s *productImageService) removeFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			slog.Warn("Failed to delete stored image", "error", err, "key", key)
+		}
+	}
+}
+
+// imageExtensions maps accepted image types to file extensions
+var imageExtensions = map[string]string{
+	models.ImageTypeJPEG: ".jpg",
+	models.ImageTypePNG:  ".png",
+	models.ImageTypeWebP: ".webp",
+}
+
+// encodeThumbnail scales img so its longest side is at most size pixels.
+// PNG sources keep PNG thumbnails to preserve transparency; everything else
+// becomes JPEG, as there is no WebP encoder in the standard library.
+func encodeThumbnail(img image.Image, contentType string, size int) ([]byte, string, error) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(thumb, thumb.Bounds(), img, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if contentType == models.ImageTypePNG {
+		if err := png.Encode(&buf, thumb); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), models.ImageTypePNG, nil
+	}
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), models.ImageTypeJPEG, nil
+}
+
+// newImageName returns a random file name for an upload, so stored files
+// never collide and can be cached forever
+func newImageName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
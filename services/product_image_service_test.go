@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+	"strings"
+	"testing"
+)
+
+// fakeProductRepo serves products by ID; other methods are not used by the
+// image service
+type fakeProductRepo struct {
+	repositories.ProductRepository
+	products map[int]*models.Product
+}
+
+func (r *fakeProductRepo) GetByID(id int) (*models.Product, error) {
+	return r.products[id], nil
+}
+
+// fakeImageRepo keeps images in memory. deleteErr makes Delete fail as a
+// rolled back transaction would.
+type fakeImageRepo struct {
+	images    map[int]*models.ProductImage
+	imageURLs map[int]string
+	nextID    int
+	deleteErr error
+}
+
+func newFakeImageRepo() *fakeImageRepo {
+	return &fakeImageRepo{images: make(map[int]*models.ProductImage), imageURLs: make(map[int]string)}
+}
+
+func (r *fakeImageRepo) GetByProductID(productID int) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	for _, img := range r.images {
+		if img.ProductID == productID {
+			images = append(images, *img)
+		}
+	}
+	return images, nil
+}
+
+func (r *fakeImageRepo) GetByID(productID, imageID int) (*models.ProductImage, error) {
+	img, ok := r.images[imageID]
+	if !ok || img.ProductID != productID {
+		return nil, nil
+	}
+	c := *img
+	return &c, nil
+}
+
+func (r *fakeImageRepo) Create(image models.ProductImage, imageURL string) (*models.ProductImage, error) {
+	images, _ := r.GetByProductID(image.ProductID)
+	image.IsPrimary = image.IsPrimary || len(images) == 0
+	r.nextID++
+	image.ID = r.nextID
+	r.images[image.ID] = &image
+	if image.IsPrimary {
+		r.imageURLs[image.ProductID] = imageURL
+	}
+	c := image
+	return &c, nil
+}
+
+func (r *fakeImageRepo) SetPrimary(productID, imageID int, imageURL string) error {
+	if _, ok := r.images[imageID]; !ok {
+		return sql.ErrNoRows
+	}
+	r.images[imageID].IsPrimary = true
+	r.imageURLs[productID] = imageURL
+	return nil
+}
+
+func (r *fakeImageRepo) Delete(productID, imageID int, imageURL func(string) string) (*models.ProductImage, error) {
+	if r.deleteErr != nil {
+		return nil, r.deleteErr
+	}
+	img, ok := r.images[imageID]
+	if !ok || img.ProductID != productID {
+		return nil, sql.ErrNoRows
+	}
+	delete(r.images, imageID)
+	if img.IsPrimary {
+		r.imageURLs[productID] = ""
+		for _, other := range r.images {
+			if other.ProductID == productID {
+				other.IsPrimary = true
+				r.imageURLs[productID] = imageURL(other.StorageKey)
+				break
+			}
+		}
+	}
+	return img, nil
+}
+
+// memoryStorage is an in-memory storage.Storage
+type memoryStorage struct {
+	files map[string][]byte
+	types map[string]string
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{files: make(map[string][]byte), types: make(map[string]string)}
+}
+
+func (s *memoryStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.files[key], s.types[key] = data, contentType
+	return nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context, key string) error {
+	delete(s.files, key)
+	return nil
+}
+
+func (s *memoryStorage) URL(key string) string { return "/uploads/" + key }
+
+func newTestImageService() (ProductImageService, *fakeImageRepo, *memoryStorage) {
+	products := &fakeProductRepo{products: map[int]*models.Product{7: {ID: 7, Name: "Coffee"}}}
+	repo := newFakeImageRepo()
+	store := newMemoryStorage()
+	svc := NewProductImageService(repo, products, store, &fakeAudit{}, ImagePolicy{MaxFileSize: 1 << 20, ThumbnailSize: 64})
+	return svc, repo, store
+}
+
+// testImage returns a w x h image encoded as JPEG or PNG
+func testImage(t *testing.T, w, h int, contentType string) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 200, 255})
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	if contentType == models.ImageTypePNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeThumbnail(t *testing.T) {
+	tests := []struct {
+		name          string
+		w, h          int
+		contentType   string
+		wantW, wantH  int
+		wantThumbType string
+	}{
+		{"landscape jpeg", 400, 200, models.ImageTypeJPEG, 64, 32, models.ImageTypeJPEG},
+		{"portrait png", 100, 300, models.ImageTypePNG, 21, 64, models.ImageTypePNG},
+		{"webp becomes jpeg", 128, 128, models.ImageTypeWebP, 64, 64, models.ImageTypeJPEG},
+		{"small image is not enlarged", 40, 30, models.ImageTypeJPEG, 40, 30, models.ImageTypeJPEG},
+		{"thin image keeps a pixel", 1000, 1, models.ImageTypePNG, 64, 1, models.ImageTypePNG},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.w, tt.h))
+			data, thumbType, err := encodeThumbnail(src, tt.contentType, 64)
+			if err != nil {
+				t.Fatalf("encodeThumbnail: %v", err)
+			}
+			if thumbType != tt.wantThumbType {
+				t.Errorf("thumbnail type = %s, want %s", thumbType, tt.wantThumbType)
+			}
+			config, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("thumbnail does not decode: %v", err)
+			}
+			if "image/"+format != tt.wantThumbType {
+				t.Errorf("thumbnail encoded as %s, want %s", format, tt.wantThumbType)
+			}
+			if config.Width != tt.wantW || config.Height != tt.wantH {
+				t.Errorf("thumbnail is %dx%d, want %dx%d", config.Width, config.Height, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestImageUploadStoresImageAndThumbnail(t *testing.T) {
+	svc, repo, store := newTestImageService()
+
+	img, err := svc.Upload(context.Background(), models.Actor{}, 7, testImage(t, 300, 150, models.ImageTypePNG), false)
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if !img.IsPrimary || repo.imageURLs[7] != img.URL {
+		t.Errorf("first image is not primary with its URL on the product: %+v, image_url %q", img, repo.imageURLs[7])
+	}
+	if img.Width != 300 || img.Height != 150 || img.ContentType != models.ImageTypePNG {
+		t.Errorf("image metadata = %dx%d %s", img.Width, img.Height, img.ContentType)
+	}
+	if !strings.HasPrefix(img.StorageKey, "products/7/") || !strings.HasSuffix(img.StorageKey, ".png") {
+		t.Errorf("storage key = %q", img.StorageKey)
+	}
+	if img.URL != "/uploads/"+img.StorageKey || img.ThumbnailURL != "/uploads/"+img.ThumbnailKey {
+		t.Errorf("URLs = %q, %q", img.URL, img.ThumbnailURL)
+	}
+
+	thumb, ok := store.files[img.ThumbnailKey]
+	if !ok {
+		t.Fatalf("thumbnail %q not stored", img.ThumbnailKey)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil {
+		t.Fatalf("stored thumbnail does not decode: %v", err)
+	}
+	if config.Width != 64 || config.Height != 32 {
+		t.Errorf("stored thumbnail is %dx%d, want 64x32", config.Width, config.Height)
+	}
+	if store.types[img.ThumbnailKey] != models.ImageTypePNG {
+		t.Errorf("thumbnail stored as %s", store.types[img.ThumbnailKey])
+	}
+	if _, ok := store.files[img.StorageKey]; !ok {
+		t.Errorf("original %q not stored", img.StorageKey)
+	}
+}
+
+func TestImageUploadRejects(t *testing.T) {
+	svc, _, store := newTestImageService()
+	ctx := context.Background()
+
+	if _, err := svc.Upload(ctx, models.Actor{}, 7, []byte("GIF89a not really"), false); !helpers.IsValidation(err) {
+		t.Errorf("unsupported type error = %v, want validation", err)
+	}
+	truncated := testImage(t, 50, 50, models.ImageTypeJPEG)[:40]
+	if _, err := svc.Upload(ctx, models.Actor{}, 7, truncated, false); !helpers.IsValidation(err) {
+		t.Errorf("corrupt image error = %v, want validation", err)
+	}
+	if _, err := svc.Upload(ctx, models.Actor{}, 99, testImage(t, 10, 10, models.ImageTypeJPEG), false); !helpers.IsNotFound(err) {
+		t.Errorf("unknown product error = %v, want not found", err)
+	}
+	if len(store.files) != 0 {
+		t.Errorf("rejected uploads left %d files", len(store.files))
+	}
+}
+
+func TestImageDelete(t *testing.T) {
+	svc, repo, store := newTestImageService()
+	ctx := context.Background()
+
+	first, err := svc.Upload(ctx, models.Actor{}, 7, testImage(t, 100, 100, models.ImageTypeJPEG), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Upload(ctx, models.Actor{}, 7, testImage(t, 100, 100, models.ImageTypeJPEG), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed delete keeps the files its record points at
+	repo.deleteErr = errors.New("connection reset")
+	if err := svc.Delete(ctx, models.Actor{}, 7, first.ID); err == nil {
+		t.Fatalf("Delete succeeded despite a repository error")
+	}
+	if _, ok := store.files[first.StorageKey]; !ok {
+		t.Errorf("files were removed although the record was kept")
+	}
+	repo.deleteErr = nil
+
+	if err := svc.Delete(ctx, models.Actor{}, 7, first.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := store.files[first.StorageKey]; ok {
+		t.Errorf("original still stored after Delete")
+	}
+	if _, ok := store.files[first.ThumbnailKey]; ok {
+		t.Errorf("thumbnail still stored after Delete")
+	}
+	if got, want := repo.imageURLs[7], "/uploads/"+second.StorageKey; got != want {
+		t.Errorf("product image_url = %q, want the promoted image %q", got, want)
+	}
+
+	if err := svc.Delete(ctx, models.Actor{}, 7, first.ID); !helpers.IsNotFound(err) {
+		t.Errorf("second Delete error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ProductImportService defines the interface for bulk product imports
+type ProductImportService interface {
+	Import(actor models.Actor, file io.Reader, opts models.ProductImportOptions) (*models.ProductImportResult, error)
+}
+
+// productImportService implements ProductImportService interface
+type productImportService struct {
+	repo         repositories.ProductImportRepository
+	categoryRepo repositories.CategoryRepository
+	audit        AuditService
+}
+
+// NewProductImportService creates a new product import service instance
+func NewProductImportService(repo repositories.ProductImportRepository, categoryRepo repositories.CategoryRepository, audit AuditService) ProductImportService {
+	return &productImportService{
+		repo:         repo,
+		categoryRepo: categoryRepo,
+		audit:        audit,
+	}
+}
+
+// importRecord is one row of an import file with its 1-based row number
+type importRecord struct {
+	row   int
+	cells []string
+}
+
+// Import reads a CSV or XLSX file of products and upserts them by SKU.
+// Every row is validated before anything is written; if any row fails, the
+// returned result lists the errors and nothing is applied. A dry run stops
+// after validation. When a batch fails to apply, the result says which row
+// to resume from and the error is returned alongside it.
+func (s *productImportService) Import(actor models.Actor, file io.Reader, opts models.ProductImportOptions) (*models.ProductImportResult, error) {
+	if opts.BatchSize < 0 {
+		return nil, helpers.NewValidationError("batch_size cannot be negative")
+	}
+
+	records, err := readImportRecords(file, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, helpers.NewValidationError("file has no header row")
+	}
+
+	columns, err := mapImportColumns(records[0].cells, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ProductImportResult{
+		DryRun:            opts.DryRun,
+		CategoriesCreated: []string{},
+		Errors:            []models.ProductImportError{},
+	}
+
+	data := make([]importRecord, 0, len(records)-1)
+	skus := make([]string, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		if isBlankRecord(rec.cells) {
+			continue
+		}
+		result.TotalRows++
+		if rec.row < opts.StartRow {
+			result.Skipped++
+			continue
+		}
+		data = append(data, rec)
+		if sku := importCell(rec, columns, models.ImportFieldSKU); sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+	if result.TotalRows == 0 {
+		return nil, helpers.NewValidationError("file has no data rows")
+	}
+
+	existing, err := s.repo.GetBySKUs(skus)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categoryRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	knownCategories := make(map[string]bool, len(categories))
+	for _, cat := range categories {
+		knownCategories[strings.ToLower(cat.Name)] = true
+	}
+
+	rows := make([]models.ProductImportRow, 0, len(data))
+	seen := make(map[string]int, len(data))
+	for _, rec := range data {
+		row, rowErrors := buildImportRow(rec, columns, existing, seen)
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			result.Failed++
+			continue
+		}
+
+		if name := row.CategoryName; name != "" && !knownCategories[strings.ToLower(name)] {
+			knownCategories[strings.ToLower(name)] = true
+			result.CategoriesCreated = append(result.CategoriesCreated, name)
+		}
+		if row.ProductID == 0 {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+		rows = append(rows, row)
+	}
+
+	if result.Failed > 0 && !opts.DryRun {
+		return result, helpers.NewValidationError(fmt.Sprintf("%d row(s) failed validation; nothing was imported", result.Failed))
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	size := opts.BatchSize
+	if size == 0 || size > len(rows) {
+		size = len(rows)
+	}
+	result.Created, result.Updated = 0, 0
+	for start := 0; start < len(rows); start += size {
+		end := min(start+size, len(rows))
+		changes, err := s.repo.ApplyBatch(rows[start:end])
+		if err != nil {
+			resume := rows[start].Row
+			result.ResumeFromRow = &resume
+			if result.BatchesCommitted > 0 {
+				result.Applied = true
+				s.audit.Record(actor, models.AuditActionImport, models.AuditEntityProduct, 0, nil, result)
+			}
+			return result, err
+		}
+		for _, change := range changes {
+			if change.Before == nil {
+				result.Created++
+				s.audit.Record(actor, models.AuditActionCreate, models.AuditEntityProduct, change.After.ID, nil, change.After)
+			} else {
+				result.Updated++
+				s.audit.Record(actor, models.AuditActionUpdate, models.AuditEntityProduct, change.After.ID, change.Before, change.After)
+			}
+		}
+		result.BatchesCommitted++
+		result.LastCommittedRow = rows[end-1].Row
+	}
+
+	result.Applied = true
+	s.audit.Record(actor, models.AuditActionImport, models.AuditEntityProduct, 0, nil, result)
+	return result, nil
+}
+
+// buildImportRow turns a file row into a product write. A row whose SKU
+// matches an existing product updates it, and empty cells keep that
+// product's current values: only the fields with a value are listed in
+// row.Fields, and the repository merges those into the product as it is
+// when the row is written. Otherwise a new product is created. seen tracks
+// the SKUs of earlier rows so a file cannot write the same product twice.
+func buildImportRow(rec importRecord, columns map[string]int, existing map[string][]models.Product, seen map[string]int) (models.ProductImportRow, []models.ProductImportError) {
+	row := models.ProductImportRow{Row: rec.row}
+	var rowErrors []models.ProductImportError
+	fail := func(field, message string) {
+		rowErrors = append(rowErrors, models.ProductImportError{Row: rec.row, Field: field, Message: message})
+	}
+
+	sku := importCell(rec, columns, models.ImportFieldSKU)
+	if sku == "" {
+		fail(models.ImportFieldSKU, "sku is required")
+		return row, rowErrors
+	}
+	if first, dup := seen[sku]; dup {
+		fail(models.ImportFieldSKU, fmt.Sprintf("sku %q already appears on row %d", sku, first))
+		return row, rowErrors
+	}
+	seen[sku] = rec.row
+
+	var live []models.Product
+	for _, p := range existing[sku] {
+		if p.DeletedAt == nil {
+			live = append(live, p)
+		}
+	}
+	switch {
+	case len(live) > 1:
+		fail(models.ImportFieldSKU, fmt.Sprintf("sku %q matches %d products; make it unique before importing", sku, len(live)))
+		return row, rowErrors
+	case len(live) == 0 && len(existing[sku]) > 0:
+		fail(models.ImportFieldSKU, fmt.Sprintf("sku %q belongs to an archived product; restore it first", sku))
+		return row, rowErrors
+	}
+
+	product := models.Product{SKU: sku, Unit: "pcs", IsActive: true}
+	if len(live) == 1 {
+		product = live[0]
+		row.ProductID = product.ID
+	}
+
+	set := func(field string) string {
+		v := importCell(rec, columns, field)
+		if v != "" {
+			row.Fields = append(row.Fields, field)
+		}
+		return v
+	}
+
+	if v := set(models.ImportFieldName); v != "" {
+		product.Name = v
+	}
+	if v := set(models.ImportFieldPrice); v != "" {
+		price, err := strconv.Atoi(v)
+		if err != nil {
+			fail(models.ImportFieldPrice, "price must be a whole number")
+		}
+		product.Price = price
+	} else if row.ProductID == 0 {
+		fail(models.ImportFieldPrice, "price is required for new products")
+	}
+	if v := set(models.ImportFieldStock); v != "" {
+		stock, err := strconv.Atoi(v)
+		if err != nil {
+			fail(models.ImportFieldStock, "stock must be a whole number")
+		}
+		product.Stock = stock
+	}
+	if v := set(models.ImportFieldUnit); v != "" {
+		product.Unit = v
+	}
+	if v := set(models.ImportFieldIsActive); v != "" {
+		active, ok := parseImportBool(v)
+		if !ok {
+			fail(models.ImportFieldIsActive, "is_active must be true/false or yes/no")
+		}
+		product.IsActive = active
+	}
+	if v := importCell(rec, columns, models.ImportFieldCategory); v != "" {
+		row.CategoryName = v
+	}
+
+	// Same rules as ProductService
+	if err := validateProduct(product); err != nil {
+		fail("", err.Error())
+	}
+
+	row.Product = product
+	return row, rowErrors
+}
+
+// readImportRecords reads every row of a CSV file or of the first sheet of
+// an XLSX workbook. Files with more than models.MaxImportRows data rows are
+// rejected as soon as the limit is passed.
+func readImportRecords(file io.Reader, format string) ([]importRecord, error) {
+	tooMany := helpers.NewValidationError(fmt.Sprintf("import files are limited to %d rows besides the header; split the file", models.MaxImportRows))
+
+	var records []importRecord
+	switch format {
+	case models.ImportFormatCSV:
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		for {
+			cells, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, helpers.NewValidationError("invalid CSV: " + err.Error())
+			}
+			if len(records) > models.MaxImportRows {
+				return nil, tooMany
+			}
+			line, _ := reader.FieldPos(0)
+			records = append(records, importRecord{row: line, cells: cells})
+		}
+
+	case models.ImportFormatXLSX:
+		workbook, err := excelize.OpenReader(file)
+		if err != nil {
+			return nil, helpers.NewValidationError("invalid XLSX: " + err.Error())
+		}
+		defer workbook.Close()
+
+		sheets := workbook.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, helpers.NewValidationError("XLSX workbook has no sheets")
+		}
+		rows, err := workbook.Rows(sheets[0])
+		if err != nil {
+			return nil, helpers.NewValidationError("invalid XLSX: " + err.Error())
+		}
+		defer rows.Close()
+		for i := 1; rows.Next(); i++ {
+			if len(records) > models.MaxImportRows {
+				return nil, tooMany
+			}
+			cells, err := rows.Columns()
+			if err != nil {
+				return nil, helpers.NewValidationError("invalid XLSX: " + err.Error())
+			}
+			records = append(records, importRecord{row: i, cells: cells})
+		}
+		if err := rows.Error(); err != nil {
+			return nil, helpers.NewValidationError("invalid XLSX: " + err.Error())
+		}
+
+	default:
+		return nil, helpers.NewValidationError("unsupported import format; use csv or xlsx")
+	}
+
+	// Spreadsheet tools often prefix UTF-8 CSV exports with a byte order mark
+	if len(records) > 0 && len(records[0].cells) > 0 {
+		records[0].cells[0] = strings.TrimPrefix(records[0].cells[0], "\ufeff")
+	}
+	return records, nil
+}
+
+// mapImportColumns resolves each import field to its column index. mapping
+// names the header of a field's column; unmapped fields are matched by
+// their own name. Headers are compared case-insensitively.
+func mapImportColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	headerIndex := make(map[string]int, len(header))
+	for i, h := range header {
+		key := strings.ToLower(strings.TrimSpace(h))
+		if _, dup := headerIndex[key]; !dup && key != "" {
+			headerIndex[key] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for field, column := range mapping {
+		if !isImportField(field) {
+			return nil, helpers.NewValidationError(fmt.Sprintf("unknown import field %q in mapping", field))
+		}
+		idx, ok := headerIndex[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, helpers.NewValidationError(fmt.Sprintf("column %q mapped to %s is not in the file header", column, field))
+		}
+		columns[field] = idx
+	}
+	for _, field := range models.ImportFields {
+		if _, mapped := columns[field]; mapped {
+			continue
+		}
+		if idx, ok := headerIndex[field]; ok {
+			columns[field] = idx
+		}
+	}
+
+	if _, ok := columns[models.ImportFieldSKU]; !ok {
+		return nil, helpers.NewValidationError("file needs a sku column; rows are matched to products by SKU")
+	}
+	return columns, nil
+}
+
+// isImportField reports whether field is a known import field
+func isImportField(field string) bool {
+	for _, known := range models.ImportFields {
+		if known == field {
+			return true
+		}
+	}
+	return false
+}
+
+// importCell returns the trimmed value of field in rec, or "" when the file
+// has no column for it
+func importCell(rec importRecord, columns map[string]int, field string) string {
+	idx, ok := columns[field]
+	if !ok || idx >= len(rec.cells) {
+		return ""
+	}
+	return strings.TrimSpace(rec.cells[idx])
+}
+
+// isBlankRecord reports whether every cell of a row is empty
+func isBlankRecord(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseImportBool accepts the boolean spellings spreadsheets commonly use
+func parseImportBool(v string) (bool, bool) {
+	switch strings.ToLower(v) {
+	case "yes", "y":
+		return true, true
+	case "no", "n":
+		return false, true
+	}
+	b, err := strconv.ParseBool(v)
+	return b, err == nil
+}
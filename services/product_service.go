@@ -2,31 +2,38 @@ package services
 
 import (
 	"errors"
+	"retail-core-api/helpers"
 	"retail-core-api/models"
 	"retail-core-api/repositories"
+	"strings"
 )
 
 // ProductService defines the interface for product business logic
 type ProductService interface {
 	GetAllProducts(params models.ProductListParams) (*models.PaginatedProducts, error)
+	ExportProducts(params models.ProductListParams, fn func(models.Product) error) error
+	Autocomplete(term string, limit int) ([]models.ProductSuggestion, error)
 	GetProductByID(id int) (*models.Product, error)
-	GetProductsByCategoryID(categoryID int) ([]models.Product, error)
-	CreateProduct(product models.Product) (*models.Product, error)
-	UpdateProduct(id int, product models.Product) (*models.Product, error)
-	DeleteProduct(id int) error
+	GetProductsByCategoryID(categoryID int, includeInactive bool) ([]models.Product, error)
+	CreateProduct(actor models.Actor, product models.Product) (*models.Product, error)
+	UpdateProduct(actor models.Actor, id int, product models.Product) (*models.Product, error)
+	DeleteProduct(actor models.Actor, id int) error
+	RestoreProduct(actor models.Actor, id int) (*models.Product, error)
 }
 
 // productService implements ProductService interface
 type productService struct {
 	repo         repositories.ProductRepository
 	categoryRepo repositories.CategoryRepository
+	audit        AuditService
 }
 
 // NewProductService creates a new product service instance
-func NewProductService(repo repositories.ProductRepository, categoryRepo repositories.CategoryRepository) ProductService {
+func NewProductService(repo repositories.ProductRepository, categoryRepo repositories.CategoryRepository, audit AuditService) ProductService {
 	return &productService{
 		repo:         repo,
 		categoryRepo: categoryRepo,
+		audit:        audit,
 	}
 }
 
@@ -35,24 +42,60 @@ func (s *productService) GetAllProducts(params models.ProductListParams) (*model
 	return s.repo.GetAll(params)
 }
 
+// ExportProducts calls fn for every product matching the list filters,
+// without pagination
+func (s *productService) ExportProducts(params models.ProductListParams, fn func(models.Product) error) error {
+	return s.repo.Each(params, fn)
+}
+
+// Autocomplete limits
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 20
+)
+
+// Autocomplete returns the active products best matching term for the POS
+// search box. An empty term matches nothing.
+func (s *productService) Autocomplete(term string, limit int) ([]models.ProductSuggestion, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return []models.ProductSuggestion{}, nil
+	}
+	if limit <= 0 {
+		limit = defaultAutocompleteLimit
+	}
+	if limit > maxAutocompleteLimit {
+		limit = maxAutocompleteLimit
+	}
+	return s.repo.Autocomplete(term, limit)
+}
+
 // GetProductByID returns a product by its ID
 func (s *productService) GetProductByID(id int) (*models.Product, error) {
 	return s.repo.GetByID(id)
 }
 
-// CreateProduct validates and creates a new product
-func (s *productService) CreateProduct(product models.Product) (*models.Product, error) {
-	// Business logic validation
+// validateProduct applies the business rules every product write must pass
+func validateProduct(product models.Product) error {
 	if product.Name == "" {
-		return nil, errors.New("product name is required")
+		return errors.New("product name is required")
 	}
 
 	if product.Price < 0 {
-		return nil, errors.New("product price cannot be negative")
+		return errors.New("product price cannot be negative")
 	}
 
 	if product.Stock < 0 {
-		return nil, errors.New("product stock cannot be negative")
+		return errors.New("product stock cannot be negative")
+	}
+	return nil
+}
+
+// CreateProduct validates and creates a new product
+func (s *productService) CreateProduct(actor models.Actor, product models.Product) (*models.Product, error) {
+	// Business logic validation
+	if err := validateProduct(product); err != nil {
+		return nil, err
 	}
 
 	// Validate category exists if category_id is provided
@@ -66,22 +109,20 @@ func (s *productService) CreateProduct(product models.Product) (*models.Product,
 		}
 	}
 
-	return s.repo.Create(product)
+	created, err := s.repo.Create(product)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, models.AuditActionCreate, models.AuditEntityProduct, created.ID, nil, created)
+	return created, nil
 }
 
 // UpdateProduct validates and updates an existing product
-func (s *productService) UpdateProduct(id int, product models.Product) (*models.Product, error) {
+func (s *productService) UpdateProduct(actor models.Actor, id int, product models.Product) (*models.Product, error) {
 	// Business logic validation
-	if product.Name == "" {
-		return nil, errors.New("product name is required")
-	}
-
-	if product.Price < 0 {
-		return nil, errors.New("product price cannot be negative")
-	}
-
-	if product.Stock < 0 {
-		return nil, errors.New("product stock cannot be negative")
+	if err := validateProduct(product); err != nil {
+		return nil, err
 	}
 
 	// Validate category exists if category_id is provided
@@ -95,6 +136,17 @@ func (s *productService) UpdateProduct(id int, product models.Product) (*models.
 		}
 	}
 
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.New("product not found")
+	}
+	if existing.DeletedAt != nil {
+		return nil, helpers.NewConflictError("product is archived; restore it before editing")
+	}
+
 	updated, err := s.repo.Update(id, product)
 	if err != nil {
 		return nil, err
@@ -104,18 +156,63 @@ func (s *productService) UpdateProduct(id int, product models.Product) (*models.
 		return nil, errors.New("product not found")
 	}
 
+	s.audit.Record(actor, models.AuditActionUpdate, models.AuditEntityProduct, id, existing, updated)
 	return updated, nil
 }
 
-// DeleteProduct removes a product by its ID
-func (s *productService) DeleteProduct(id int) error {
-	return s.repo.Delete(id)
+// DeleteProduct archives a product by its ID. Archived products drop out of
+// listings and checkout but stay referenced by past transactions.
+func (s *productService) DeleteProduct(actor models.Actor, id int) error {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return helpers.NewNotFoundError("product not found")
+	}
+	if existing.DeletedAt != nil {
+		return helpers.NewConflictError("product is already archived")
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, models.AuditActionArchive, models.AuditEntityProduct, id, existing, nil)
+	return nil
+}
+
+// RestoreProduct brings an archived product back into listings and checkout
+func (s *productService) RestoreProduct(actor models.Actor, id int) (*models.Product, error) {
+	existing, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, helpers.NewNotFoundError("product not found")
+	}
+	if existing.DeletedAt == nil {
+		return nil, helpers.NewConflictError("product is not archived")
+	}
+
+	if err := s.repo.Restore(id); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, models.AuditActionRestore, models.AuditEntityProduct, id, existing, restored)
+	return restored, nil
 }
 
-// GetProductsByCategoryID returns all products belonging to a category
-func (s *productService) GetProductsByCategoryID(categoryID int) ([]models.Product, error) {
+// GetProductsByCategoryID returns all products belonging to a category,
+// including those in its descendant categories
+func (s *productService) GetProductsByCategoryID(categoryID int, includeInactive bool) ([]models.Product, error) {
 	if categoryID <= 0 {
 		return nil, errors.New("invalid category ID")
 	}
-	return s.repo.GetByCategoryID(categoryID)
+	return s.repo.GetByCategoryID(categoryID, includeInactive)
 }
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+	"sync"
+	"time"
+)
+
+// permissionCacheTTL bounds how long a role's permissions are cached, so
+// changes made through another instance are picked up without a restart.
+const permissionCacheTTL = 30 * time.Second
+
+// roleNamePattern restricts custom role names to lowercase identifiers
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// RoleService defines the interface for role and permission business logic
+type RoleService interface {
+	GetAllRoles() ([]models.Role, error)
+	GetRoleByID(id int) (*models.Role, error)
+	CreateRole(actor models.Actor, input models.RoleInput) (*models.Role, error)
+	UpdateRole(actor models.Actor, id int, input models.RoleInput) (*models.Role, error)
+	DeleteRole(actor models.Actor, id int) error
+	Permissions(role string) ([]string, error)
+}
+
+// cachedPermissions holds a role's permissions and when they were loaded
+type cachedPermissions struct {
+	permissions []string
+	loadedAt    time.Time
+}
+
+// roleService implements RoleService interface
+type roleService struct {
+	repo  repositories.RoleRepository
+	audit AuditService
+
+	mu    sync.RWMutex
+	cache map[string]cachedPermissions
+}
+
+// NewRoleService creates a new role service instance
+func NewRoleService(repo repositories.RoleRepository, audit AuditService) RoleService {
+	return &roleService{
+		repo:  repo,
+		audit: audit,
+		cache: make(map[string]cachedPermissions),
+	}
+}
+
+// GetAllRoles returns all roles with their permissions
+func (s *roleService) GetAllRoles() ([]models.Role, error) {
+	return s.repo.GetAll()
+}
+
+// GetRoleByID returns a role by its ID
+func (s *roleService) GetRoleByID(id int) (*models.Role, error) {
+	role, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, helpers.NewNotFoundError("role not found")
+	}
+	return role, nil
+}
+
+// validateRoleInput checks the role name and that every permission is known
+func validateRoleInput(input models.RoleInput) error {
+	if !roleNamePattern.MatchString(input.Name) {
+		return helpers.NewValidationError("role name must be 2-50 lowercase letters, digits or underscores, starting with a letter")
+	}
+	if len(input.Permissions) == 0 {
+		return helpers.NewValidationError("a role must grant at least one permission")
+	}
+	for _, p := range input.Permissions {
+		if !models.IsValidPermission(p) {
+			return helpers.NewValidationError(fmt.Sprintf("unknown permission '%s'", p))
+		}
+	}
+	return nil
+}
+
+// CreateRole validates and creates a new custom role
+func (s *roleService) CreateRole(actor models.Actor, input models.RoleInput) (*models.Role, error) {
+	if err := validateRoleInput(input); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, helpers.NewConflictError("role name already exists")
+	}
+
+	created, err := s.repo.Create(models.Role{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, models.AuditActionCreate, models.AuditEntityRole, created.ID, nil, created)
+	return created, nil
+}
+
+// UpdateRole validates and updates a custom role. System roles are read-only.
+func (s *roleService) UpdateRole(actor models.Actor, id int, input models.RoleInput) (*models.Role, error) {
+	if err := validateRoleInput(input); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.GetRoleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.IsSystem {
+		return nil, helpers.NewValidationError("system roles cannot be modified")
+	}
+
+	if input.Name != existing.Name {
+		clash, err := s.repo.GetByName(input.Name)
+		if err != nil {
+			return nil, err
+		}
+		if clash != nil {
+			return nil, helpers.NewConflictError("role name already exists")
+		}
+	}
+
+	updated, err := s.repo.Update(id, models.Role{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, helpers.NewNotFoundError("role not found")
+	}
+
+	s.invalidate(existing.Name)
+	s.audit.Record(actor, models.AuditActionUpdate, models.AuditEntityRole, id, existing, updated)
+	return updated, nil
+}
+
+// DeleteRole removes a custom role that is not assigned to any user
+func (s *roleService) DeleteRole(actor models.Actor, id int) error {
+	existing, err := s.GetRoleByID(id)
+	if err != nil {
+		return err
+	}
+	if existing.IsSystem {
+		return helpers.NewValidationError("system roles cannot be deleted")
+	}
+
+	count, err := s.repo.CountUsers(existing.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return helpers.NewConflictError(fmt.Sprintf("role is assigned to %d user(s)", count))
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		return err
+	}
+
+	s.invalidate(existing.Name)
+	s.audit.Record(actor, models.AuditActionDelete, models.AuditEntityRole, id, existing, nil)
+	return nil
+}
+
+// Permissions returns the permissions granted to the named role. Unknown
+// roles have no permissions. Results are cached for permissionCacheTTL.
+func (s *roleService) Permissions(role string) ([]string, error) {
+	s.mu.RLock()
+	cached, ok := s.cache[role]
+	s.mu.RUnlock()
+	if ok && time.Since(cached.loadedAt) < permissionCacheTTL {
+		return cached.permissions, nil
+	}
+
+	r, err := s.repo.GetByName(role)
+	if err != nil {
+		return nil, err
+	}
+	permissions := []string{}
+	if r != nil {
+		permissions = r.Permissions
+	}
+
+	s.mu.Lock()
+	s.cache[role] = cachedPermissions{permissions: permissions, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return permissions, nil
+}
+
+// invalidate drops the cached permissions of a role
+func (s *roleService) invalidate(role string) {
+	s.mu.Lock()
+	delete(s.cache, role)
+	s.mu.Unlock()
+}
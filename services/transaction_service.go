@@ -2,56 +2,158 @@ package services
 
 import (
 	"errors"
+	"retail-core-api/helpers"
+	"retail-core-api/metrics"
 	"retail-core-api/models"
 	"retail-core-api/repositories"
 )
 
 // TransactionService defines the interface for transaction business logic
 type TransactionService interface {
-	Checkout(req models.CheckoutRequest) (*models.Transaction, error)
-	GetAllTransactions(page, limit int, startDate, endDate string) (*models.PaginatedTransactions, error)
+	Checkout(actor models.Actor, req models.CheckoutRequest, approvalToken string) (*models.Transaction, error)
+	GetAllTransactions(params models.TransactionListParams) (*models.PaginatedTransactions, error)
+	ExportTransactions(params models.TransactionListParams, fn func(models.TransactionListItem) error) error
 	GetTransactionByID(id int) (*models.Transaction, error)
-	VoidTransaction(id int) error
+	VoidTransaction(actor models.Actor, id int, approvalToken string) error
 	GetDashboardStats() (*models.DashboardStats, error)
 	GetDailySalesReport() (*models.SalesReport, error)
 	GetSalesReportByDateRange(startDate, endDate string) (*models.SalesReport, error)
-	GetReportSummary(startDate, endDate string) (*models.ReportSummary, error)
+	GetReportSummary(startDate, endDate string, categoryLevel int) (*models.ReportSummary, error)
 }
 
 // transactionService implements TransactionService interface
 type transactionService struct {
-	repo repositories.TransactionRepository
+	repo      repositories.TransactionRepository
+	audit     AuditService
+	approvals ApprovalService
+	policy    ApprovalPolicy
 }
 
 // NewTransactionService creates a new transaction service instance
-func NewTransactionService(repo repositories.TransactionRepository) TransactionService {
-	return &transactionService{repo: repo}
+func NewTransactionService(
+	repo repositories.TransactionRepository,
+	audit AuditService,
+	approvals ApprovalService,
+	policy ApprovalPolicy,
+) TransactionService {
+	return &transactionService{
+		repo:      repo,
+		audit:     audit,
+		approvals: approvals,
+		policy:    policy,
+	}
 }
 
 // Checkout validates the checkout request and delegates to the repository
-func (s *transactionService) Checkout(req models.CheckoutRequest) (*models.Transaction, error) {
+func (s *transactionService) Checkout(actor models.Actor, req models.CheckoutRequest, approvalToken string) (*models.Transaction, error) {
+	transaction, err := s.checkout(actor, req, approvalToken)
+	if err != nil {
+		recordCheckoutFailure(err)
+		return nil, err
+	}
+
+	metrics.CheckoutsTotal.Inc()
+	metrics.RevenueTotal.Add(float64(transaction.TotalAmount))
+	s.audit.Record(actor, models.AuditActionCreate, models.AuditEntityTransaction, transaction.ID, nil, transaction)
+	return transaction, nil
+}
+
+func (s *transactionService) checkout(actor models.Actor, req models.CheckoutRequest, approvalToken string) (*models.Transaction, error) {
 	if len(req.Items) == 0 {
-		return nil, errors.New("checkout items cannot be empty")
+		return nil, helpers.NewValidationError("checkout items cannot be empty")
 	}
 
 	for _, item := range req.Items {
 		if item.ProductID <= 0 {
-			return nil, errors.New("invalid product ID")
+			return nil, helpers.NewValidationError("invalid product ID")
 		}
 		if item.Quantity <= 0 {
-			return nil, errors.New("quantity must be greater than 0")
+			return nil, helpers.NewValidationError("quantity must be greater than 0")
 		}
 	}
 
-	return s.repo.CreateTransaction(req)
+	if req.Discount < 0 {
+		return nil, helpers.NewValidationError("discount cannot be negative")
+	}
+
+	// Large discounts need a manager approval
+	var approval *Approval
+	if req.Discount > 0 && s.policy.DiscountThresholdPercent > 0 {
+		gross, err := s.repo.CalculateGrossAmount(req.Items)
+		if err != nil {
+			return nil, err
+		}
+		if req.Discount*100 > gross*s.policy.DiscountThresholdPercent {
+			approval, err = s.approvals.Authorize(actor, models.ApprovalActionDiscount, approvalToken, models.ApprovalBinding{
+				Discount:    &req.Discount,
+				GrossAmount: &gross,
+			})
+			if err != nil {
+				return nil, err
+			}
+			req.ApprovedBy = &approval.ApproverID
+		}
+	}
+
+	transaction, err := s.repo.CreateTransaction(req)
+	if err != nil {
+		s.approvals.Release(approval)
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// recordCheckoutFailure increments the checkout failure metrics for err
+func recordCheckoutFailure(err error) {
+	switch {
+	case helpers.IsInsufficientStock(err):
+		metrics.InsufficientStockTotal.Inc()
+		metrics.CheckoutFailuresTotal.WithLabelValues(metrics.ReasonInsufficientStock).Inc()
+	case helpers.IsProductInactive(err):
+		metrics.CheckoutFailuresTotal.WithLabelValues(metrics.ReasonProductInactive).Inc()
+	case helpers.IsNotFound(err):
+		metrics.CheckoutFailuresTotal.WithLabelValues(metrics.ReasonNotFound).Inc()
+	case helpers.IsValidation(err):
+		metrics.CheckoutFailuresTotal.WithLabelValues(metrics.ReasonValidation).Inc()
+	case helpers.IsApprovalRequired(err):
+		metrics.CheckoutFailuresTotal.WithLabelValues(metrics.ReasonApprovalRequired).Inc()
+	default:
+		metrics.CheckoutFailuresTotal.WithLabelValues(metrics.ReasonInternal).Inc()
+	}
 }
 
 // VoidTransaction voids a transaction and restores stock
-func (s *transactionService) VoidTransaction(id int) error {
+func (s *transactionService) VoidTransaction(actor models.Actor, id int, approvalToken string) error {
 	if id <= 0 {
 		return errors.New("invalid transaction ID")
 	}
-	return s.repo.VoidTransaction(id)
+
+	before, err := s.repo.GetTransactionByID(id)
+	if err != nil {
+		return err
+	}
+
+	var approval *Approval
+	var approvedBy *int
+	if s.policy.RequireVoidApproval {
+		approval, err = s.approvals.Authorize(actor, models.ApprovalActionVoid, approvalToken, models.ApprovalBinding{TransactionID: &id})
+		if err != nil {
+			return err
+		}
+		approvedBy = &approval.ApproverID
+	}
+
+	if err := s.repo.VoidTransaction(id, approvedBy); err != nil {
+		s.approvals.Release(approval)
+		return err
+	}
+
+	metrics.VoidsTotal.Inc()
+	after := *before
+	after.Status = "void"
+	after.VoidApprovedBy = approvedBy
+	s.audit.Record(actor, models.AuditActionVoid, models.AuditEntityTransaction, id, before, &after)
+	return nil
 }
 
 // GetDailySalesReport returns the sales summary for today
@@ -67,17 +169,27 @@ func (s *transactionService) GetSalesReportByDateRange(startDate, endDate string
 	return s.repo.GetSalesReportByDateRange(startDate, endDate)
 }
 
-// GetReportSummary returns an aggregated report with category breakdown
-func (s *transactionService) GetReportSummary(startDate, endDate string) (*models.ReportSummary, error) {
+// GetReportSummary returns an aggregated report with category breakdown,
+// optionally rolled up to a level of the category tree
+func (s *transactionService) GetReportSummary(startDate, endDate string, categoryLevel int) (*models.ReportSummary, error) {
 	if startDate == "" || endDate == "" {
 		return nil, errors.New("start_date and end_date are required")
 	}
-	return s.repo.GetReportSummary(startDate, endDate)
+	if categoryLevel < 0 {
+		return nil, errors.New("category_level must not be negative")
+	}
+	return s.repo.GetReportSummary(startDate, endDate, categoryLevel)
 }
 
 // GetAllTransactions returns a paginated list of transactions with optional date range
-func (s *transactionService) GetAllTransactions(page, limit int, startDate, endDate string) (*models.PaginatedTransactions, error) {
-	return s.repo.GetAllTransactions(page, limit, startDate, endDate)
+func (s *transactionService) GetAllTransactions(params models.TransactionListParams) (*models.PaginatedTransactions, error) {
+	return s.repo.GetAllTransactions(params)
+}
+
+// ExportTransactions calls fn for every transaction in the date range,
+// without pagination
+func (s *transactionService) ExportTransactions(params models.TransactionListParams, fn func(models.TransactionListItem) error) error {
+	return s.repo.EachTransaction(params, fn)
 }
 
 // GetTransactionByID returns a single transaction with its details
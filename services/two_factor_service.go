@@ -0,0 +1,223 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"log/slog"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"retail-core-api/repositories"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recoveryCodeCount is the number of recovery codes issued on enrollment
+const recoveryCodeCount = 10
+
+// TwoFactorService defines the interface for TOTP two-factor authentication
+type TwoFactorService interface {
+	Setup(actor models.Actor, password string) (*models.TwoFactorSetup, error)
+	Enable(actor models.Actor, code string) (*models.RecoveryCodes, error)
+	Disable(actor models.Actor, password, code string) error
+	Verify(userID int, code string) (bool, error)
+	Required(role string) bool
+}
+
+// twoFactorService implements TwoFactorService interface
+type twoFactorService struct {
+	userRepo     repositories.UserRepository
+	repo         repositories.TwoFactorRepository
+	audit        AuditService
+	issuer       string
+	requireOwner bool
+}
+
+// NewTwoFactorService creates a new two-factor service instance. issuer is
+// the name shown in authenticator apps; requireOwner makes enrollment
+// mandatory for owners.
+func NewTwoFactorService(
+	userRepo repositories.UserRepository,
+	repo repositories.TwoFactorRepository,
+	audit AuditService,
+	issuer string,
+	requireOwner bool,
+) TwoFactorService {
+	return &twoFactorService{
+		userRepo:     userRepo,
+		repo:         repo,
+		audit:        audit,
+		issuer:       issuer,
+		requireOwner: requireOwner,
+	}
+}
+
+// Required reports whether users with the given role must enroll
+func (s *twoFactorService) Required(role string) bool {
+	return s.requireOwner && role == models.RoleOwner
+}
+
+// currentUser loads the active user behind the actor
+func (s *twoFactorService) currentUser(actor models.Actor) (*models.User, error) {
+	if actor.UserID == nil {
+		return nil, helpers.NewValidationError("a user account is required for two-factor authentication")
+	}
+	user, err := s.userRepo.GetByID(*actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, helpers.NewNotFoundError("user not found")
+	}
+	return user, nil
+}
+
+// Setup generates a new secret for the caller after confirming their
+// password. It stays pending until confirmed with Enable.
+func (s *twoFactorService) Setup(actor models.Actor, password string) (*models.TwoFactorSetup, error) {
+	user, err := s.currentUser(actor)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, helpers.NewConflictError("two-factor authentication is already enabled")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, helpers.NewValidationError("password is incorrect")
+	}
+
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate secret")
+	}
+	if err := s.repo.SetPendingSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: helpers.TOTPProvisioningURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Enable confirms the pending secret with a code from the authenticator app
+// and returns freshly generated recovery codes
+func (s *twoFactorService) Enable(actor models.Actor, code string) (*models.RecoveryCodes, error) {
+	user, err := s.currentUser(actor)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, enabled, err := s.repo.GetSecret(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, helpers.NewConflictError("two-factor authentication is already enabled")
+	}
+	if secret == "" {
+		return nil, helpers.NewValidationError("start enrollment with /auth/2fa/setup first")
+	}
+
+	step := helpers.ValidateTOTP(secret, code, time.Now())
+	if step == 0 {
+		return nil, helpers.NewValidationError("invalid code")
+	}
+	if _, err := s.repo.MarkStepUsed(user.ID, step); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, errors.New("failed to generate recovery codes")
+		}
+		codes[i] = code
+		hashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+	if err := s.repo.Enable(user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(actor, models.AuditActionEnable2FA, models.AuditEntityUser, user.ID, nil, nil)
+	return &models.RecoveryCodes{Codes: codes}, nil
+}
+
+// Disable turns off two-factor authentication after confirming the password
+// and a current code. Roles that require it cannot turn it off.
+func (s *twoFactorService) Disable(actor models.Actor, password, code string) error {
+	user, err := s.currentUser(actor)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return helpers.NewValidationError("two-factor authentication is not enabled")
+	}
+	if s.Required(user.Role) {
+		return helpers.NewValidationError("two-factor authentication is required for your role")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return helpers.NewValidationError("password is incorrect")
+	}
+
+	ok, err := s.Verify(user.ID, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return helpers.NewValidationError("invalid code")
+	}
+
+	if err := s.repo.Disable(user.ID); err != nil {
+		return err
+	}
+
+	s.audit.Record(actor, models.AuditActionDisable2FA, models.AuditEntityUser, user.ID, nil, nil)
+	return nil
+}
+
+// Verify checks a TOTP code, or failing that a recovery code, for a user
+// with two-factor authentication enabled. Each TOTP time step and each
+// recovery code is accepted only once.
+func (s *twoFactorService) Verify(userID int, code string) (bool, error) {
+	secret, enabled, err := s.repo.GetSecret(userID)
+	if err != nil {
+		return false, err
+	}
+	if !enabled || secret == "" {
+		return false, nil
+	}
+
+	if step := helpers.ValidateTOTP(secret, code, time.Now()); step > 0 {
+		return s.repo.MarkStepUsed(userID, step)
+	}
+
+	used, err := s.repo.ConsumeRecoveryCode(userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	if used {
+		slog.Info("Recovery code used", "user_id", userID)
+	}
+	return used, nil
+}
+
+// newRecoveryCode returns a random 80-bit code formatted as XXXX-XXXX-XXXX-XXXX
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := base32.StdEncoding.EncodeToString(b)
+	return raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16], nil
+}
+
+// normalizeRecoveryCode strips separators and case so codes can be typed loosely
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
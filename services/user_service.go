@@ -1,9 +1,13 @@
 package services
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"retail-core-api/helpers"
 	"retail-core-api/models"
 	"retail-core-api/repositories"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -12,18 +16,34 @@ import (
 type UserService interface {
 	GetAll() ([]models.User, error)
 	GetByID(id int) (*models.User, error)
-	Update(id int, input models.UserInput) (*models.User, error)
-	Delete(id int) error
+	Update(actor models.Actor, id int, input models.UserPatch) (*models.User, error)
+	Activate(actor models.Actor, id int) (*models.User, error)
+	Deactivate(actor models.Actor, id int) (*models.User, error)
+	Unlock(actor models.Actor, id int) (*models.User, error)
+	ResetPassword(actor models.Actor, id int, newPassword string) error
+	GetProfile(actor models.Actor) (*models.User, error)
+	UpdateProfile(actor models.Actor, input models.ProfileInput) (*models.User, error)
+	GetSessions(actor models.Actor, currentSessionID int) ([]models.Session, error)
 }
 
 // userService implements UserService interface
 type userService struct {
-	userRepo repositories.UserRepository
+	userRepo  repositories.UserRepository
+	roleRepo  repositories.RoleRepository
+	sessions  repositories.SessionRepository
+	audit     AuditService
+	passwords helpers.PasswordPolicy
 }
 
 // NewUserService creates a new user service instance
-func NewUserService(userRepo repositories.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(
+	userRepo repositories.UserRepository,
+	roleRepo repositories.RoleRepository,
+	sessions repositories.SessionRepository,
+	audit AuditService,
+	passwords helpers.PasswordPolicy,
+) UserService {
+	return &userService{userRepo: userRepo, roleRepo: roleRepo, sessions: sessions, audit: audit, passwords: passwords}
 }
 
 // GetAll returns all users
@@ -38,55 +58,305 @@ func (s *userService) GetByID(id int) (*models.User, error) {
 		return nil, err
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, helpers.NewNotFoundError("user not found")
 	}
 	// Clear password
 	user.Password = ""
 	return user, nil
 }
 
-// Update updates a user
-func (s *userService) Update(id int, input models.UserInput) (*models.User, error) {
+// Update applies a partial update to a user. Passwords are changed through
+// ResetPassword, and the last active owner cannot be demoted. The actor must
+// hold every permission of both the user's current role and a new one.
+func (s *userService) Update(actor models.Actor, id int, input models.UserPatch) (*models.User, error) {
+	if input.Password != nil {
+		return nil, helpers.NewValidationError("passwords cannot be changed here, use POST /api/users/{id}/reset-password")
+	}
+
 	existing, err := s.userRepo.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
 	if existing == nil {
-		return nil, errors.New("user not found")
+		return nil, helpers.NewNotFoundError("user not found")
+	}
+	existing.Password = ""
+	if err := checkManageUser(s.roleRepo, actor, existing); err != nil {
+		return nil, err
 	}
 
-	// If password is provided, hash it
-	if input.Password != "" {
-		hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	user := *existing
+	if input.Name != nil {
+		user.Name = strings.TrimSpace(*input.Name)
+		if user.Name == "" {
+			return nil, helpers.NewValidationError("name cannot be empty")
+		}
+	}
+	if input.Email != nil && *input.Email != existing.Email {
+		clash, err := s.userRepo.GetByEmail(*input.Email)
 		if err != nil {
-			return nil, errors.New("failed to hash password")
+			return nil, err
+		}
+		if clash != nil {
+			return nil, helpers.NewConflictError("email already registered")
+		}
+		user.Email = *input.Email
+	}
+	if input.Role != nil && *input.Role != existing.Role {
+		if err := checkAssignRole(s.roleRepo, actor, *input.Role); err != nil {
+			return nil, err
+		}
+		user.Role = *input.Role
+	}
+
+	// The repository refuses to demote the last active owner
+	updated, err := s.userRepo.Update(id, user)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, helpers.NewNotFoundError("user not found")
+	}
+
+	s.audit.Record(actor, models.AuditActionUpdate, models.AuditEntityUser, id, existing, updated)
+	return updated, nil
+}
+
+// Activate reactivates a deactivated user
+func (s *userService) Activate(actor models.Actor, id int) (*models.User, error) {
+	return s.setActive(actor, id, true)
+}
+
+// Deactivate soft-deletes a user so they can no longer log in and ends
+// their sessions. The last active owner cannot be deactivated.
+func (s *userService) Deactivate(actor models.Actor, id int) (*models.User, error) {
+	return s.setActive(actor, id, false)
+}
+
+// setActive changes the active flag of a user and records it
+func (s *userService) setActive(actor models.Actor, id int, active bool) (*models.User, error) {
+	existing, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, helpers.NewNotFoundError("user not found")
+	}
+	existing.Password = ""
+	if err := checkManageUser(s.roleRepo, actor, existing); err != nil {
+		return nil, err
+	}
+	if existing.IsActive == active {
+		return existing, nil
+	}
+
+	// The repository refuses to deactivate the last active owner
+	if err := s.userRepo.SetActive(id, active); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, helpers.NewNotFoundError("user not found")
+		}
+		return nil, err
+	}
+	if !active {
+		if err := s.sessions.RevokeByUser(id); err != nil {
+			return nil, err
 		}
-		input.Password = string(hash)
 	}
 
-	// Validate role if provided
-	if input.Role != "" && input.Role != "owner" && input.Role != "cashier" {
-		return nil, errors.New("role must be 'owner' or 'cashier'")
+	after := *existing
+	after.IsActive = active
+	action := models.AuditActionDeactivate
+	if active {
+		action = models.AuditActionActivate
 	}
+	s.audit.Record(actor, action, models.AuditEntityUser, id, existing, &after)
+	return &after, nil
+}
 
-	user := models.User{
-		Name:     input.Name,
-		Email:    input.Email,
-		Password: input.Password,
-		Role:     input.Role,
+// Unlock clears the failed login counter and lockout of a user
+func (s *userService) Unlock(actor models.Actor, id int) (*models.User, error) {
+	existing, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, helpers.NewNotFoundError("user not found")
+	}
+	if err := checkManageUser(s.roleRepo, actor, existing); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.ResetFailedLogins(id); err != nil {
+		return nil, err
 	}
 
-	return s.userRepo.Update(id, user)
+	existing.Password = ""
+	after := *existing
+	after.LockedUntil = nil
+	s.audit.Record(actor, models.AuditActionUnlock, models.AuditEntityUser, id, existing, &after)
+	return &after, nil
 }
 
-// Delete soft-deletes a user
-func (s *userService) Delete(id int) error {
+// ResetPassword sets a temporary password that the user must change at their
+// next login, and ends their sessions
+func (s *userService) ResetPassword(actor models.Actor, id int, newPassword string) error {
 	existing, err := s.userRepo.GetByID(id)
 	if err != nil {
 		return err
 	}
 	if existing == nil {
-		return errors.New("user not found")
+		return helpers.NewNotFoundError("user not found")
+	}
+	if err := checkManageUser(s.roleRepo, actor, existing); err != nil {
+		return err
+	}
+	if err := s.passwords.Validate(newPassword); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+	if err := s.userRepo.SetPassword(id, string(hash), true); err != nil {
+		return err
+	}
+	if err := s.sessions.RevokeByUser(id); err != nil {
+		return err
+	}
+
+	existing.Password = ""
+	after := *existing
+	after.MustChangePassword = true
+	s.audit.Record(actor, models.AuditActionResetPassword, models.AuditEntityUser, id, existing, &after)
+	return nil
+}
+
+// GetProfile returns the account of the logged-in user
+func (s *userService) GetProfile(actor models.Actor) (*models.User, error) {
+	if actor.UserID == nil {
+		return nil, helpers.NewValidationError("a user account is required")
+	}
+	user, err := s.userRepo.GetByID(*actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, helpers.NewNotFoundError("user not found")
+	}
+	user.Password = ""
+	return user, nil
+}
+
+// UpdateProfile lets users change their own name and password. A new
+// password requires the current one; the role can only be changed by a
+// user manager through Update.
+func (s *userService) UpdateProfile(actor models.Actor, input models.ProfileInput) (*models.User, error) {
+	if input.Role != nil {
+		return nil, helpers.NewValidationError("you cannot change your own role")
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" && input.NewPassword == "" {
+		return nil, helpers.NewValidationError("nothing to update, provide name or new_password")
+	}
+
+	if actor.UserID == nil {
+		return nil, helpers.NewValidationError("a user account is required")
+	}
+	existing, err := s.userRepo.GetByID(*actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || !existing.IsActive {
+		return nil, helpers.NewNotFoundError("user not found")
+	}
+
+	var hash []byte
+	if input.NewPassword != "" {
+		if bcrypt.CompareHashAndPassword([]byte(existing.Password), []byte(input.CurrentPassword)) != nil {
+			return nil, helpers.NewValidationError("current password is incorrect")
+		}
+		if input.CurrentPassword == input.NewPassword {
+			return nil, helpers.NewValidationError("new password must differ from the current password")
+		}
+		if err := s.passwords.Validate(input.NewPassword); err != nil {
+			return nil, err
+		}
+		if hash, err = bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost); err != nil {
+			return nil, errors.New("failed to hash password")
+		}
+	}
+
+	existing.Password = ""
+	updated := *existing
+	if input.Name != "" && input.Name != existing.Name {
+		if err := s.userRepo.SetName(existing.ID, input.Name); err != nil {
+			return nil, err
+		}
+		updated.Name = input.Name
+		s.audit.Record(actor, models.AuditActionUpdate, models.AuditEntityUser, existing.ID, existing, &updated)
+	}
+	if hash != nil {
+		if err := s.userRepo.SetPassword(existing.ID, string(hash), false); err != nil {
+			return nil, err
+		}
+		s.audit.Record(actor, models.AuditActionChangePassword, models.AuditEntityUser, existing.ID, nil, nil)
+	}
+	return &updated, nil
+}
+
+// GetSessions returns the unexpired sessions of the logged-in user, marking
+// the one making the request
+func (s *userService) GetSessions(actor models.Actor, currentSessionID int) ([]models.Session, error) {
+	if actor.UserID == nil {
+		return nil, helpers.NewValidationError("a user account is required")
+	}
+	sessions, err := s.sessions.GetActiveByUser(*actor.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// checkAssignRole checks that the named role exists and that actor holds
+// every permission it grants, so user managers cannot hand out more access
+// than they have themselves
+func checkAssignRole(roleRepo repositories.RoleRepository, actor models.Actor, role string) error {
+	r, err := roleRepo.GetByName(role)
+	if err != nil {
+		return errors.New("failed to validate role")
+	}
+	if r == nil {
+		return helpers.NewValidationError(fmt.Sprintf("role '%s' does not exist", role))
+	}
+	for _, p := range r.Permissions {
+		if !actor.Can(p) {
+			return helpers.NewForbiddenError(fmt.Sprintf("cannot assign role '%s': it grants '%s', which you do not hold", role, p))
+		}
+	}
+	return nil
+}
+
+// checkManageUser checks that actor may change the account of user: owner
+// accounts only by owners, and others only by actors holding every
+// permission of the user's role
+func checkManageUser(roleRepo repositories.RoleRepository, actor models.Actor, user *models.User) error {
+	if user.Role == models.RoleOwner && actor.Role != models.RoleOwner {
+		return helpers.NewForbiddenError("only owners can change owner accounts")
+	}
+	r, err := roleRepo.GetByName(user.Role)
+	if err != nil {
+		return errors.New("failed to validate role")
+	}
+	if r == nil {
+		return nil
+	}
+	for _, p := range r.Permissions {
+		if !actor.Can(p) {
+			return helpers.NewForbiddenError(fmt.Sprintf("cannot change a '%s' account: the role grants '%s', which you do not hold", user.Role, p))
+		}
 	}
-	return s.userRepo.Delete(id)
+	return nil
 }
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"database/sql"
+	"retail-core-api/helpers"
+	"retail-core-api/models"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeUserRepo is an in-memory UserRepository. writeErr makes Update and
+// SetActive fail as the PostgreSQL repository does when its last-owner
+// guard refuses a change; the guard itself needs a database to test.
+type fakeUserRepo struct {
+	users    map[int]*models.User
+	writeErr error
+}
+
+func newFakeUserRepo(users ...models.User) *fakeUserRepo {
+	r := &fakeUserRepo{users: make(map[int]*models.User)}
+	for i := range users {
+		u := users[i]
+		r.users[u.ID] = &u
+	}
+	return r
+}
+
+func (r *fakeUserRepo) GetByID(id int) (*models.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	c := *u
+	return &c, nil
+}
+
+func (r *fakeUserRepo) GetByEmail(email string) (*models.User, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			c := *u
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepo) GetAll() ([]models.User, error) {
+	users := make([]models.User, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, *u)
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepo) Create(user models.User) (*models.User, error) {
+	user.ID = len(r.users) + 1
+	r.users[user.ID] = &user
+	return &user, nil
+}
+
+func (r *fakeUserRepo) Update(id int, user models.User) (*models.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	if r.writeErr != nil {
+		return nil, r.writeErr
+	}
+	u.Name, u.Email, u.Role = user.Name, user.Email, user.Role
+	c := *u
+	c.Password = ""
+	return &c, nil
+}
+
+func (r *fakeUserRepo) SetActive(id int, active bool) error {
+	u, ok := r.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if r.writeErr != nil {
+		return r.writeErr
+	}
+	u.IsActive = active
+	return nil
+}
+
+func (r *fakeUserRepo) GetPINHash(id int) (string, error)         { return "", nil }
+func (r *fakeUserRepo) SetPINHash(id int, pinHash string) error   { return nil }
+func (r *fakeUserRepo) IncrementFailedLogins(id int) (int, error) { return 0, nil }
+func (r *fakeUserRepo) LockUntil(id int, until time.Time) error   { return nil }
+func (r *fakeUserRepo) ResetFailedLogins(id int) error            { return nil }
+func (r *fakeUserRepo) SetName(id int, name string) error         { r.users[id].Name = name; return nil }
+func (r *fakeUserRepo) SetPassword(id int, hash string, mustChange bool) error {
+	u, ok := r.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	u.Password, u.MustChangePassword = hash, mustChange
+	return nil
+}
+
+// fakeRoleRepo knows the built-in roles
+type fakeRoleRepo struct{}
+
+func (fakeRoleRepo) GetAll() ([]models.Role, error)       { return models.DefaultRoles, nil }
+func (fakeRoleRepo) GetByID(id int) (*models.Role, error) { return nil, nil }
+func (fakeRoleRepo) GetByName(name string) (*models.Role, error) {
+	for _, role := range models.DefaultRoles {
+		if role.Name == name {
+			return &role, nil
+		}
+	}
+	return nil, nil
+}
+func (fakeRoleRepo) Create(role models.Role) (*models.Role, error)         { return &role, nil }
+func (fakeRoleRepo) Update(id int, role models.Role) (*models.Role, error) { return &role, nil }
+func (fakeRoleRepo) Delete(id int) error                                   { return nil }
+func (fakeRoleRepo) CountUsers(name string) (int, error)                   { return 0, nil }
+
+// fakeSessionRepo records which users had their sessions revoked
+type fakeSessionRepo struct {
+	revoked []int
+}
+
+func (r *fakeSessionRepo) Create(session models.Session) (*models.Session, error) {
+	return &session, nil
+}
+func (r *fakeSessionRepo) GetActiveByUser(userID int) ([]models.Session, error) { return nil, nil }
+func (r *fakeSessionRepo) IsActive(id, userID int) (bool, error)                { return true, nil }
+func (r *fakeSessionRepo) RevokeByUser(userID int) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+// fakeAudit collects recorded audit actions
+type fakeAudit struct {
+	actions []string
+}
+
+func (a *fakeAudit) Record(actor models.Actor, action, entityType string, entityID int, before, after interface{}) {
+	a.actions = append(a.actions, action)
+}
+
+func (a *fakeAudit) GetAll(params models.AuditLogListParams) (*models.PaginatedAuditLogs, error) {
+	return nil, nil
+}
+
+func newTestUserService(users ...models.User) (UserService, *fakeUserRepo, *fakeAudit) {
+	svc, repo, _, audit := newTestUserServiceWithSessions(users...)
+	return svc, repo, audit
+}
+
+func newTestUserServiceWithSessions(users ...models.User) (UserService, *fakeUserRepo, *fakeSessionRepo, *fakeAudit) {
+	repo := newFakeUserRepo(users...)
+	sessions := &fakeSessionRepo{}
+	audit := &fakeAudit{}
+	svc := NewUserService(repo, fakeRoleRepo{}, sessions, audit, helpers.PasswordPolicy{MinLength: 8})
+	return svc, repo, sessions, audit
+}
+
+var (
+	testOwner   = models.User{ID: 1, Name: "Owner", Email: "owner@retail.com", Role: models.RoleOwner, IsActive: true}
+	testCashier = models.User{ID: 3, Name: "Cashier", Email: "cashier@retail.com", Role: models.RoleCashier, IsActive: true}
+)
+
+// ownerActor acts as testOwner, managerActor as a manager trusted with
+// user.manage on top of the manager role
+var (
+	ownerActor   = models.Actor{UserID: ptr(testOwner.ID), Role: models.RoleOwner, Permissions: models.AllPermissions}
+	managerActor = models.Actor{UserID: ptr(4), Role: models.RoleManager, Permissions: append(rolePermissions(models.RoleManager), models.PermUserManage)}
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func rolePermissions(name string) []string {
+	role, _ := fakeRoleRepo{}.GetByName(name)
+	return append([]string(nil), role.Permissions...)
+}
+
+func TestUserUpdatePartial(t *testing.T) {
+	svc, repo, audit := newTestUserService(testOwner, testCashier)
+
+	updated, err := svc.Update(ownerActor, testCashier.ID, models.UserPatch{Name: ptr("  Head Cashier ")})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Name != "Head Cashier" || updated.Email != testCashier.Email || updated.Role != testCashier.Role {
+		t.Errorf("Update changed more than the name: %+v", updated)
+	}
+
+	updated, err = svc.Update(ownerActor, testCashier.ID, models.UserPatch{Role: ptr(models.RoleManager)})
+	if err != nil {
+		t.Fatalf("Update role: %v", err)
+	}
+	if updated.Name != "Head Cashier" || updated.Role != models.RoleManager {
+		t.Errorf("Update role = %+v", updated)
+	}
+	if repo.users[testCashier.ID].Role != models.RoleManager {
+		t.Errorf("role not stored")
+	}
+	if len(audit.actions) != 2 {
+		t.Errorf("audited %v, want two updates", audit.actions)
+	}
+}
+
+func TestUserUpdateRejects(t *testing.T) {
+	svc, _, audit := newTestUserService(testOwner, testCashier)
+
+	tests := []struct {
+		name  string
+		patch models.UserPatch
+		check func(error) bool
+	}{
+		{"password", models.UserPatch{Password: ptr("secret123")}, helpers.IsValidation},
+		{"empty name", models.UserPatch{Name: ptr("  ")}, helpers.IsValidation},
+		{"unknown role", models.UserPatch{Role: ptr("janitor")}, helpers.IsValidation},
+		{"taken email", models.UserPatch{Email: ptr(testOwner.Email)}, helpers.IsConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Update(ownerActor, testCashier.ID, tt.patch)
+			if !tt.check(err) {
+				t.Errorf("Update error = %v", err)
+			}
+		})
+	}
+
+	if _, err := svc.Update(ownerActor, 99, models.UserPatch{Name: ptr("Nobody")}); !helpers.IsNotFound(err) {
+		t.Errorf("Update of unknown user error = %v, want not found", err)
+	}
+	if len(audit.actions) != 0 {
+		t.Errorf("rejected updates were audited: %v", audit.actions)
+	}
+}
+
+func TestUserActivateDeactivate(t *testing.T) {
+	svc, repo, audit := newTestUserService(testOwner, testCashier)
+
+	user, err := svc.Deactivate(ownerActor, testCashier.ID)
+	if err != nil {
+		t.Fatalf("Deactivate: %v", err)
+	}
+	if user.IsActive || repo.users[testCashier.ID].IsActive {
+		t.Errorf("user still active")
+	}
+
+	// Deactivating again is a no-op
+	if _, err := svc.Deactivate(ownerActor, testCashier.ID); err != nil {
+		t.Fatalf("second Deactivate: %v", err)
+	}
+
+	user, err = svc.Activate(ownerActor, testCashier.ID)
+	if err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	if !user.IsActive || !repo.users[testCashier.ID].IsActive {
+		t.Errorf("user not reactivated")
+	}
+
+	want := []string{models.AuditActionDeactivate, models.AuditActionActivate}
+	if len(audit.actions) != len(want) || audit.actions[0] != want[0] || audit.actions[1] != want[1] {
+		t.Errorf("audited %v, want %v", audit.actions, want)
+	}
+
+	if _, err := svc.Activate(ownerActor, 99); !helpers.IsNotFound(err) {
+		t.Errorf("Activate of unknown user error = %v, want not found", err)
+	}
+}
+
+func TestUserRepositoryConflict(t *testing.T) {
+	svc, repo, audit := newTestUserService(testOwner, testCashier)
+	repo.writeErr = helpers.NewConflictError("cannot deactivate or demote the last active owner")
+
+	if _, err := svc.Deactivate(ownerActor, testOwner.ID); !helpers.IsConflict(err) {
+		t.Errorf("Deactivate error = %v, want conflict", err)
+	}
+	if _, err := svc.Update(ownerActor, testOwner.ID, models.UserPatch{Role: ptr(models.RoleManager)}); !helpers.IsConflict(err) {
+		t.Errorf("Update error = %v, want conflict", err)
+	}
+	if len(audit.actions) != 0 {
+		t.Errorf("refused changes were audited: %v", audit.actions)
+	}
+}
+
+func TestUserResetPassword(t *testing.T) {
+	svc, repo, audit := newTestUserService(testOwner, testCashier)
+
+	if err := svc.ResetPassword(ownerActor, testCashier.ID, "short"); !helpers.IsValidation(err) {
+		t.Errorf("weak password error = %v, want validation", err)
+	}
+
+	if err := svc.ResetPassword(ownerActor, testCashier.ID, "temporary123"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	u := repo.users[testCashier.ID]
+	if !u.MustChangePassword {
+		t.Errorf("reset password does not have to be changed")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte("temporary123")) != nil {
+		t.Errorf("stored hash does not match the new password")
+	}
+	if len(audit.actions) != 1 || audit.actions[0] != models.AuditActionResetPassword {
+		t.Errorf("audited %v, want reset_password", audit.actions)
+	}
+
+	if err := svc.ResetPassword(ownerActor, 99, "temporary123"); !helpers.IsNotFound(err) {
+		t.Errorf("ResetPassword of unknown user error = %v, want not found", err)
+	}
+}
+
+func TestUserManagerCannotEscalate(t *testing.T) {
+	svc, repo, audit := newTestUserService(testOwner, testCashier)
+
+	if _, err := svc.Update(managerActor, testCashier.ID, models.UserPatch{Role: ptr(models.RoleOwner)}); !helpers.IsForbidden(err) {
+		t.Errorf("promote to owner error = %v, want forbidden", err)
+	}
+	if err := svc.ResetPassword(managerActor, testOwner.ID, "temporary123"); !helpers.IsForbidden(err) {
+		t.Errorf("reset owner password error = %v, want forbidden", err)
+	}
+	if _, err := svc.Update(managerActor, testOwner.ID, models.UserPatch{Email: ptr("me@retail.com")}); !helpers.IsForbidden(err) {
+		t.Errorf("change owner email error = %v, want forbidden", err)
+	}
+	if _, err := svc.Deactivate(managerActor, testOwner.ID); !helpers.IsForbidden(err) {
+		t.Errorf("deactivate owner error = %v, want forbidden", err)
+	}
+	if u := repo.users[testOwner.ID]; u.Email != testOwner.Email || u.Password != "" || !u.IsActive {
+		t.Errorf("owner account changed: %+v", u)
+	}
+	if u := repo.users[testCashier.ID]; u.Role != models.RoleCashier {
+		t.Errorf("cashier promoted to %s", u.Role)
+	}
+	if len(audit.actions) != 0 {
+		t.Errorf("refused changes were audited: %v", audit.actions)
+	}
+
+	// Roles within the manager's own permissions are fine
+	if _, err := svc.Update(managerActor, testCashier.ID, models.UserPatch{Role: ptr(models.RoleManager)}); err != nil {
+		t.Errorf("promote cashier to manager: %v", err)
+	}
+	if err := svc.ResetPassword(managerActor, testCashier.ID, "temporary123"); err != nil {
+		t.Errorf("reset manager password: %v", err)
+	}
+}
+
+func TestUserDeactivateAndResetEndSessions(t *testing.T) {
+	svc, _, sessions, _ := newTestUserServiceWithSessions(testOwner, testCashier)
+
+	if _, err := svc.Activate(ownerActor, testCashier.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions.revoked) != 0 {
+		t.Errorf("activation revoked sessions of %v", sessions.revoked)
+	}
+	if _, err := svc.Deactivate(ownerActor, testCashier.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ResetPassword(ownerActor, testCashier.ID, "temporary123"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions.revoked) != 2 || sessions.revoked[0] != testCashier.ID || sessions.revoked[1] != testCashier.ID {
+		t.Errorf("revoked sessions of %v, want the cashier's twice", sessions.revoked)
+	}
+}
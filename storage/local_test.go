@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoragePutDelete(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(dir, "/uploads/")
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "products/7/a.jpg", strings.NewReader("image"), 5, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	path := filepath.Join(dir, "products", "7", "a.jpg")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("stored file: %v", err)
+	}
+	if string(data) != "image" {
+		t.Errorf("stored %q, want %q", data, "image")
+	}
+
+	// Overwrites replace the file and leave no temporary files behind
+	if err := s.Put(ctx, "products/7/a.jpg", strings.NewReader("newer"), 5, "image/jpeg"); err != nil {
+		t.Fatalf("second Put: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want 1", len(entries))
+	}
+
+	if got, want := s.URL("products/7/a.jpg"), "/uploads/products/7/a.jpg"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	if err := s.Delete(ctx, "products/7/a.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file still exists after Delete: %v", err)
+	}
+	if err := s.Delete(ctx, "products/7/a.jpg"); err != nil {
+		t.Errorf("Delete of a missing file: %v", err)
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalStorage(filepath.Join(dir, "uploads"), "/uploads")
+	ctx := context.Background()
+
+	for _, key := range []string{"../secret.txt", "products/../../secret.txt", "/etc/passwd", ""} {
+		if err := s.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", key)
+		}
+		if err := s.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded, want an error", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "secret.txt")); !os.IsNotExist(err) {
+		t.Errorf("a file was written outside the storage directory")
+	}
+}
+
+func TestLocalStorageCanceledContext(t *testing.T) {
+	s := NewLocalStorage(t.TempDir(), "/uploads")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Put(ctx, "a.jpg", strings.NewReader("x"), 1, "image/jpeg"); err == nil {
+		t.Errorf("Put with a canceled context succeeded")
+	}
+}
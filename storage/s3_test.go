@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal S3 API stand-in serving path-style PutObject and
+// DeleteObject requests for a single bucket
+type fakeS3 struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data         string
+	contentType  string
+	cacheControl string
+}
+
+func newFakeS3(t *testing.T, bucket string) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{bucket: bucket, objects: make(map[string]fakeObject)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		s3Error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		s3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, err := readS3Payload(r)
+		if err != nil {
+			s3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = fakeObject{
+			data:         data,
+			contentType:  r.Header.Get("Content-Type"),
+			cacheControl: r.Header.Get("Cache-Control"),
+		}
+		w.Header().Set("ETag", `"fake"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (f *fakeS3) object(key string) (fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+// readS3Payload returns the object data of a PutObject request, decoding
+// the aws-chunked encoding the client uses over plain HTTP
+func readS3Payload(r *http.Request) (string, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		data, err := io.ReadAll(r.Body)
+		return string(data), err
+	}
+
+	var data strings.Builder
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return "", err
+		}
+		if size == 0 {
+			return data.String(), nil
+		}
+		if _, err := io.CopyN(&data, br, size); err != nil {
+			return "", err
+		}
+		if _, err := br.Discard(2); err != nil { // chunk trailer \r\n
+			return "", err
+		}
+	}
+}
+
+func s3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>`+code+`</Code><Message>`+code+`</Message></Error>`)
+}
+
+func newTestS3Storage(t *testing.T, srv *httptest.Server, bucket, publicURL string) *S3Storage {
+	s, err := NewS3Storage(S3Config{
+		Endpoint:  strings.TrimPrefix(srv.URL, "http://"),
+		Region:    "us-east-1",
+		Bucket:    bucket,
+		AccessKey: "access",
+		SecretKey: "secret",
+		PublicURL: publicURL,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage: %v", err)
+	}
+	return s
+}
+
+func TestS3StoragePutDelete(t *testing.T) {
+	fake, srv := newFakeS3(t, "media")
+	s := newTestS3Storage(t, srv, "media", "")
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "products/7/a.jpg", strings.NewReader("image"), 5, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	obj, ok := fake.object("products/7/a.jpg")
+	if !ok {
+		t.Fatalf("object was not stored")
+	}
+	if obj.data != "image" || obj.contentType != "image/jpeg" {
+		t.Errorf("stored %q as %q, want %q as image/jpeg", obj.data, obj.contentType, "image")
+	}
+	if !strings.Contains(obj.cacheControl, "immutable") {
+		t.Errorf("Cache-Control = %q, want immutable caching", obj.cacheControl)
+	}
+
+	if err := s.Delete(ctx, "products/7/a.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fake.object("products/7/a.jpg"); ok {
+		t.Errorf("object still exists after Delete")
+	}
+}
+
+func TestS3StorageErrors(t *testing.T) {
+	_, srv := newFakeS3(t, "media")
+	s := newTestS3Storage(t, srv, "other", "")
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "a.jpg", strings.NewReader("image"), 5, "image/jpeg"); err == nil || !strings.Contains(err.Error(), "a.jpg") {
+		t.Errorf("Put to a missing bucket error = %v, want one naming the key", err)
+	}
+	if err := s.Delete(ctx, "a.jpg"); err == nil {
+		t.Errorf("Delete from a missing bucket succeeded")
+	}
+}
+
+func TestS3StorageURL(t *testing.T) {
+	_, srv := newFakeS3(t, "media")
+
+	s := newTestS3Storage(t, srv, "media", "")
+	if got, want := s.URL("products/7/a.jpg"), srv.URL+"/media/products/7/a.jpg"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	s = newTestS3Storage(t, srv, "media", "https://cdn.example.com/")
+	if got, want := s.URL("products/7/a.jpg"), "https://cdn.example.com/products/7/a.jpg"; got != want {
+		t.Errorf("URL with public URL = %q, want %q", got, want)
+	}
+}
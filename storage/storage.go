@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// Storage keeps uploaded files under slash-separated keys such as
+// "products/12/3f9c2a7e.jpg" and tells clients where to fetch them
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}